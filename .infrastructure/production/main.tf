@@ -214,6 +214,16 @@ resource "aws_alb_target_group" "main" {
   protocol    = "HTTP"
   target_type = "ip"
   vpc_id      = aws_vpc.main.id
+
+  # readiness reports 503 while dependencies are down or the task is draining, so traffic moves to other tasks
+  health_check {
+    path                = "/health/ready"
+    matcher             = "200"
+    interval            = 15
+    timeout             = 5
+    healthy_threshold   = 2
+    unhealthy_threshold = 3
+  }
 }
 
 resource "aws_alb_listener" "http" {
@@ -329,6 +339,16 @@ resource "aws_dynamodb_table" "application_dynamodb_table" {
     name = "SK"
     type = "S"
   }
+
+  attribute {
+    name = "GSI2PK"
+    type = "S"
+  }
+
+  attribute {
+    name = "GSI2SK"
+    type = "S"
+  }
   
   global_secondary_index {
     name               = "APPLICATION_GSI_1"
@@ -338,6 +358,15 @@ resource "aws_dynamodb_table" "application_dynamodb_table" {
     projection_type    = "ALL"
   }
 
+  global_secondary_index {
+    name               = "APPLICATION_GSI_2"
+    hash_key           = "GSI2PK"
+    range_key          = "GSI2SK"
+    write_capacity     = 10
+    read_capacity      = 10
+    projection_type    = "ALL"
+  }
+
   tags = {
     Name        = "APPLICATION"
     Environment = "production"
@@ -366,11 +395,13 @@ resource "aws_iam_policy" "dynamodb_task_policy" {
           "dynamodb:Query",
           "dynamodb:UpdateItem",
           "dynamodb:UpdateTable",
-          "dynamodb:BatchWriteItem"
+          "dynamodb:BatchWriteItem",
+          "dynamodb:BatchGetItem"
         ],
         Resource = [
             aws_dynamodb_table.authentication_dynamodb_table.arn,
             aws_dynamodb_table.application_dynamodb_table.arn,
+            "${aws_dynamodb_table.application_dynamodb_table.arn}/index/*",
         ]
       }
     ]
@@ -442,6 +473,16 @@ resource "aws_ecs_task_definition" "task" {
       ]
 
       essential = true
+
+      # liveness only fails when the process can't serve requests, so ECS doesn't restart tasks when a dependency is down
+      healthCheck = {
+        command     = ["CMD-SHELL", "wget -q -O /dev/null http://localhost:8080/health/live || exit 1"]
+        interval    = 30
+        timeout     = 5
+        retries     = 3
+        startPeriod = 10
+      }
+
       portMappings = [
         {
           protocol      = "tcp"
@@ -2,18 +2,31 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"os"
+	"os/signal"
 	"speakeasy/internal/app"
+	"speakeasy/internal/pkg/album"
 	"speakeasy/internal/pkg/authentication"
+	"speakeasy/internal/pkg/config"
+	"speakeasy/internal/pkg/feed"
 	"speakeasy/internal/pkg/profile"
+	"speakeasy/internal/pkg/social"
 	"speakeasy/internal/pkg/trip"
+	"speakeasy/internal/pkg/upload"
+	"speakeasy/pkg"
+	"speakeasy/pkg/clock"
+	"speakeasy/pkg/database"
+	"speakeasy/pkg/filestorage"
+	"speakeasy/pkg/health"
+	"speakeasy/pkg/ids"
+	"speakeasy/pkg/logging"
+	"syscall"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 
 	"github.com/gin-contrib/cors"
 
@@ -23,27 +36,111 @@ import (
 var ginLambda *ginadapter.GinLambda
 
 func main() {
-	godotenv.Load()
+	// Fail fast on missing or invalid settings, e.g. missing JWT secrets
+	cfg, err := config.Load()
+	if err != nil {
+		logging.New(os.Stdout, slog.LevelError).Error("load config failed", "error", err.Error())
+		os.Exit(1)
+	}
+
+	logger := logging.New(os.Stdout, logging.ParseLevel(cfg.LogLevel))
+	slog.SetDefault(logger)
+	cfg.ApplyTimeouts()
+
+	if err := pkg.RegisterValidators(); err != nil {
+		logger.Error("register validators failed", "error", err.Error())
+		os.Exit(1)
+	}
 
-	authenticationService := authentication.NewAuthenticationService()
-	tripService := trip.NewTripService()
-	profileService := profile.NewProfileService()
+	// Composition root, the clients are shared by every service
+	clk := clock.System()
+	uuids := ids.UUID()
+	dynamo := database.NewClient(cfg.Database())
+	storage := filestorage.NewFileStorageService(cfg.FileStorage(), cfg.Storage.Bucket)
+	tokens := authentication.NewTokenIssuer(cfg.JWT, clk, uuids)
+	authenticationDB := database.NewDatabaseService[authentication.Authentication](dynamo, cfg.Tables.Authentication)
+	tripDB := database.NewDatabaseService[trip.Trip](dynamo, cfg.Tables.Application)
 
-	router := gin.Default()
+	// Readiness fails when a dependency is unavailable, liveness only checks the process
+	readiness := health.NewRegistry(cfg.Health.CheckTimeout, cfg.Health.CacheTTL, clk, logger)
+	readiness.Register("dynamodb_authentication", health.CheckerFunc(authenticationDB.Ping))
+	readiness.Register("dynamodb_application", health.CheckerFunc(tripDB.Ping))
+	readiness.Register("storage", health.CheckerFunc(storage.Ping))
+	readiness.Register("jwt", health.CheckerFunc(tokens.Ping))
+
+	authenticationService := authentication.NewAuthenticationService(
+		authenticationDB,
+		tokens,
+		clk,
+		uuids,
+		logger,
+	)
+	tripService := trip.NewTripService(
+		tripDB,
+		database.NewDatabaseService[trip.InviteLink](dynamo, cfg.Tables.Application),
+		storage,
+		cfg.JWT.InviteSecret,
+		clk,
+		uuids,
+		logger,
+	)
+	profileService := profile.NewProfileService(
+		database.NewDatabaseService[profile.Profile](dynamo, cfg.Tables.Application),
+		storage,
+		clk,
+		uuids,
+		logger,
+	)
+	socialService := social.NewSocialService(
+		database.NewDatabaseService[social.Relationship](dynamo, cfg.Tables.Application),
+		clk,
+		logger,
+	)
+	feedService := feed.NewFeedService(
+		database.NewDatabaseService[feed.Item](dynamo, cfg.Tables.Application),
+		socialService,
+		clk,
+	)
+	uploadService := upload.NewUploadService(
+		database.NewDatabaseService[upload.Upload](dynamo, cfg.Tables.Application),
+		storage,
+		clk,
+		uuids,
+		logger,
+	)
+	albumService := album.NewAlbumService(
+		database.NewDatabaseService[album.Photo](dynamo, cfg.Tables.Application),
+		storage,
+		tripService,
+		clk,
+		uuids,
+		logger,
+	)
+
+	// Requests are logged as JSON by the app middleware instead of the Gin logger
+	router := gin.New()
+	router.Use(gin.Recovery())
 	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3000", "https://amuel.org", "https://dev.amuel.org"},
+		AllowOrigins:     cfg.CORS.AllowOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "User-Agent"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "User-Agent", "X-Request-ID"},
+		ExposeHeaders:    []string{"Content-Length", "X-Request-ID"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
 	server := app.NewServer(
 		router,
+		cfg,
+		logger,
+		readiness,
 		authenticationService,
 		tripService,
 		profileService,
+		socialService,
+		feedService,
+		uploadService,
+		albumService,
 	)
 
 	if inLambda() {
@@ -51,16 +148,22 @@ func main() {
 		lambda.Start(Handler)
 		return
 	} else {
-		server.Run()
+		// ECS sends SIGTERM when the task is stopped, Ctrl+C sends SIGINT
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+		defer stop()
+
+		if err := server.Run(ctx); err != nil {
+			os.Exit(1)
+		}
 	}
 }
 
 func inLambda() bool {
 	if runtime, _ := os.LookupEnv("AWS_LAMBDA_RUNTIME_API"); runtime != "" {
-		log.Println("Found Lambda environment.")
+		slog.Info("found Lambda environment")
 		return true
 	} else {
-		log.Println("Lambda environment not found.")
+		slog.Info("Lambda environment not found")
 		return false
 	}
 }
@@ -1,6 +1,6 @@
 module speakeasy
 
-go 1.19
+go 1.21
 
 require (
 	github.com/aws/aws-cdk-go/awscdk/v2 v2.29.0
@@ -13,19 +13,21 @@ require (
 	github.com/joho/godotenv v1.4.0
 	github.com/stretchr/testify v1.8.1
 	golang.org/x/crypto v0.4.0
+	golang.org/x/image v0.5.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
-require github.com/aws/aws-lambda-go v1.19.1 // indirect
+require github.com/aws/aws-lambda-go v1.19.1
 
 require (
 	github.com/Masterminds/semver/v3 v3.1.1 // indirect
-	github.com/aws/jsii-runtime-go v1.60.1 // indirect
+	github.com/aws/jsii-runtime-go v1.60.1
 	github.com/awslabs/aws-lambda-go-api-proxy v0.14.0
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/gin-contrib/sse v0.1.0 // indirect
 	github.com/go-playground/locales v0.14.0 // indirect
 	github.com/go-playground/universal-translator v0.18.0 // indirect
-	github.com/go-playground/validator/v10 v10.11.1 // indirect
+	github.com/go-playground/validator/v10 v10.11.1
 	github.com/goccy/go-json v0.10.0 // indirect
 	github.com/google/go-cmp v0.5.8 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
@@ -39,8 +41,7 @@ require (
 	github.com/ugorji/go/codec v1.2.7 // indirect
 	golang.org/x/net v0.4.0 // indirect
 	golang.org/x/sys v0.3.0 // indirect
-	golang.org/x/text v0.5.0 // indirect
+	golang.org/x/text v0.7.0 // indirect
 	google.golang.org/protobuf v1.28.1 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
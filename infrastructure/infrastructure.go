@@ -2,9 +2,6 @@ package main
 
 import (
 	"github.com/aws/aws-cdk-go/awscdk/v2"
-	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudfront"
-	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudfrontorigins"
-
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
 	"github.com/aws/constructs-go/constructs/v10"
@@ -22,17 +19,11 @@ func NewInfrastructureStack(scope constructs.Construct, id string, props *Infras
 	}
 	stack := awscdk.NewStack(scope, &id, &sprops)
 
-	// S3 bucket for image upload
+	// S3 bucket for image upload, objects are private and only served through presigned urls
 	bucket := awss3.NewBucket(stack, jsii.String("profile_pic_s3_bucket"), &awss3.BucketProps{
-		BucketName: jsii.String("profile.image.amuel.org"),
-		BlockPublicAccess: awss3.NewBlockPublicAccess(
-			&awss3.BlockPublicAccessOptions{
-				BlockPublicAcls:       jsii.Bool(false),
-				BlockPublicPolicy:     jsii.Bool(false),
-				IgnorePublicAcls:      jsii.Bool(false),
-				RestrictPublicBuckets: jsii.Bool(false),
-			},
-		),
+		BucketName:        jsii.String("profile.image.amuel.org"),
+		BlockPublicAccess: awss3.BlockPublicAccess_BLOCK_ALL(),
+		EnforceSSL:        jsii.Bool(true),
 		Cors: &[]*awss3.CorsRule{
 			{
 				AllowedHeaders: jsii.Strings("*"),
@@ -45,25 +36,14 @@ func NewInfrastructureStack(scope constructs.Construct, id string, props *Infras
 				ExposedHeaders: &[]*string{},
 			},
 		},
-	})
-
-	// Add read policy for bucket and objects
-	bucket.AddToResourcePolicy(
-		awsiam.NewPolicyStatement(
-			&awsiam.PolicyStatementProps{
-				Resources: &[]*string{
-					bucket.ArnForObjects(jsii.String("*")),
-					bucket.BucketArn(),
-				},
-				Actions: &[]*string{
-					jsii.String("s3:GetObject"),
-				},
-				Principals: &[]awsiam.IPrincipal{
-					awsiam.NewAnyPrincipal(),
-				},
+		// Pending uploads are processed into new files, remove the ones which are never completed
+		LifecycleRules: &[]*awss3.LifecycleRule{
+			{
+				Prefix:     jsii.String("uploads/"),
+				Expiration: awscdk.Duration_Days(jsii.Number(1)),
 			},
-		),
-	)
+		},
+	})
 
 	// Create new policy for storing to bucket
 	awsiam.NewPolicy(stack, jsii.String("profile_pic_put_policy"),
@@ -78,6 +58,10 @@ func NewInfrastructureStack(scope constructs.Construct, id string, props *Infras
 						},
 						Actions: &[]*string{
 							jsii.String("s3:PutObject"),
+							jsii.String("s3:GetObject"),
+							jsii.String("s3:DeleteObject"),
+							// HeadBucket of the readiness check
+							jsii.String("s3:ListBucket"),
 						},
 					},
 				),
@@ -85,14 +69,6 @@ func NewInfrastructureStack(scope constructs.Construct, id string, props *Infras
 		},
 	)
 
-	awscloudfront.NewDistribution(stack, jsii.String("profile_pic_cdn"),
-		&awscloudfront.DistributionProps{
-			DefaultBehavior: &awscloudfront.BehaviorOptions{
-				Origin: awscloudfrontorigins.NewS3Origin(bucket, &awscloudfrontorigins.S3OriginProps{}),
-			},
-		},
-	)
-
 	return stack
 }
 
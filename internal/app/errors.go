@@ -0,0 +1,68 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"speakeasy/pkg"
+
+	"github.com/gin-gonic/gin"
+)
+
+// problemContentType is the media type of RFC 7807 error responses
+const problemContentType = "application/problem+json"
+
+// problemTypePrefix is prepended to the error type to get the problem type URI
+const problemTypePrefix = "urn:speakeasy:problem:"
+
+// Problem object which is the RFC 7807 error response.
+// Code is the stable error type and Errors contains the reason of each invalid request field.
+type Problem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Code     string            `json:"code"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+// ErrorHandler Gin middleware which renders the last error added with c.Error as problem response.
+// The underlying cause is only logged by AccessLog, errors which are not *pkg.Error are rendered as 500.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		err := requestError(c)
+
+		// The handler already responded, the error is only logged
+		if err == nil || c.Writer.Written() {
+			return
+		}
+
+		c.Header("Content-Type", problemContentType)
+		c.JSON(err.Code, Problem{
+			Type:     problemTypePrefix + err.ErrorType(),
+			Title:    http.StatusText(err.Code),
+			Status:   err.Code,
+			Detail:   err.Reason,
+			Instance: c.Request.URL.Path,
+			Code:     err.ErrorType(),
+			Errors:   err.Details,
+		})
+	}
+}
+
+// requestError returns the last error added with c.Error, nil if there is none.
+// Errors which are not *pkg.Error are returned as 500.
+func requestError(c *gin.Context) *pkg.Error {
+	if len(c.Errors) == 0 {
+		return nil
+	}
+
+	var err *pkg.Error
+	if !errors.As(c.Errors.Last().Err, &err) {
+		err = pkg.Internal(c.FullPath(), c.Errors.Last().Err)
+	}
+
+	return err
+}
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"speakeasy/pkg"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// serveError handles a request with ErrorHandler and a handler which adds the error
+func serveError(err error) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.GET("/trips/:tripid", func(c *gin.Context) {
+		c.Error(err)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/trips/trip", nil))
+	return w
+}
+
+func TestErrorHandler(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		err     error
+		problem Problem
+	}{
+		{
+			name: "RENDER ERROR AS PROBLEM WITH TYPE OF STATUS",
+			err:  &pkg.Error{Code: http.StatusNotFound, Reason: "Trip not found"},
+			problem: Problem{
+				Type: "urn:speakeasy:problem:not_found", Title: "Not Found", Status: http.StatusNotFound,
+				Detail: "Trip not found", Instance: "/trips/trip", Code: pkg.TypeNotFound,
+			},
+		},
+		{
+			name: "RENDER DETAILS OF EACH INVALID FIELD",
+			err: &pkg.Error{
+				Code: http.StatusBadRequest, Type: pkg.TypeValidationFailed, Reason: "Validation failed",
+				Details: map[string]string{"name": "Is required"},
+			},
+			problem: Problem{
+				Type: "urn:speakeasy:problem:validation_failed", Title: "Bad Request", Status: http.StatusBadRequest,
+				Detail: "Validation failed", Instance: "/trips/trip", Code: pkg.TypeValidationFailed,
+				Errors: map[string]string{"name": "Is required"},
+			},
+		},
+		{
+			name: "RENDER WRAPPED ERROR",
+			err:  fmt.Errorf("get trip: %w", &pkg.Error{Code: http.StatusConflict, Reason: "Trip was changed, please try again"}),
+			problem: Problem{
+				Type: "urn:speakeasy:problem:conflict", Title: "Conflict", Status: http.StatusConflict,
+				Detail: "Trip was changed, please try again", Instance: "/trips/trip", Code: pkg.TypeConflict,
+			},
+		},
+		{
+			name: "RENDER OTHER ERROR AS 500 WITHOUT CAUSE",
+			err:  errors.New("connection refused"),
+			problem: Problem{
+				Type: "urn:speakeasy:problem:internal_error", Title: "Internal Server Error", Status: http.StatusInternalServerError,
+				Detail: "Internal Server Error", Instance: "/trips/trip", Code: pkg.TypeInternal,
+			},
+		},
+	} {
+		t.Run("SUCCESS: "+test.name, func(t *testing.T) {
+			w := serveError(test.err)
+
+			var problem Problem
+			assert.Empty(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(t, test.problem.Status, w.Code)
+			assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, test.problem, problem)
+			assert.NotContains(t, w.Body.String(), "connection refused", "Cause should not be sent")
+		})
+	}
+
+	t.Run("SUCCESS: KEEP RESPONSE WHEN HANDLER ALREADY RESPONDED", func(t *testing.T) {
+		router := gin.New()
+		router.Use(ErrorHandler())
+		router.GET("/health", func(c *gin.Context) {
+			c.String(http.StatusServiceUnavailable, "unhealthy")
+			c.Error(errors.New("database unavailable"))
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "unhealthy", w.Body.String())
+	})
+
+	t.Run("SUCCESS: KEEP RESPONSE WITHOUT ERROR", func(t *testing.T) {
+		router := gin.New()
+		router.Use(ErrorHandler())
+		router.GET("/trips", func(c *gin.Context) {
+			c.JSON(http.StatusOK, []string{})
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/trips", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "[]", w.Body.String())
+	})
+}
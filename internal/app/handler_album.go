@@ -0,0 +1,113 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"speakeasy/internal/pkg/album"
+	"speakeasy/internal/pkg/feed"
+	"speakeasy/internal/pkg/social"
+	"speakeasy/pkg"
+	"speakeasy/pkg/database"
+	"speakeasy/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTripPhotos Gin handler function to list photos of the trip album, only participants can see them
+func (s *Server) GetTripPhotos() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var page social.PageRequest
+		if err := c.ShouldBindQuery(&page); err != nil {
+			c.Error(pkg.BindingError(err))
+			return
+		}
+
+		userID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		result, err := s.albumService.GetPhotos(c.Request.Context(), userID, c.Param("tripid"), &page)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, database.MapPage(result, (*album.Photo).Response))
+	}
+}
+
+// AddTripPhoto Gin handler function to upload photo to the trip album with multipart form,
+// POST /v1/uploads should be used for photos larger than the request body limit
+func (s *Server) AddTripPhoto() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		// Leave room for the multipart headers and caption, the photo size is checked by the service
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, album.MaxPhotoSize+1<<20)
+
+		file, _, formErr := c.Request.FormFile("photo")
+		if formErr != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(formErr, &maxBytesErr) {
+				c.Error(&pkg.Error{Code: http.StatusRequestEntityTooLarge, Reason: "Photo is too large", Err: formErr})
+				return
+			}
+
+			c.Error(&pkg.Error{Code: http.StatusBadRequest, Reason: "Photo is required", Err: formErr})
+			return
+		}
+		defer file.Close()
+
+		photo, perr := s.albumService.AddPhoto(c.Request.Context(), userID, c.Param("tripid"), file, c.Request.FormValue("caption"))
+		if perr != nil {
+			c.Error(perr)
+			return
+		}
+
+		s.publishPhotoEvent(c.Request.Context(), userID, photo)
+
+		c.JSON(http.StatusCreated, photo.Response())
+	}
+}
+
+// DeleteTripPhoto Gin handler function to delete photo from the trip album, only the uploader and the owner can delete it
+func (s *Server) DeleteTripPhoto() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		if err := s.albumService.DeletePhoto(c.Request.Context(), userID, c.Param("tripid"), c.Param("photoid")); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response := map[string]any{
+			"status":  http.StatusOK,
+			"message": "Deleted",
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// publishPhotoEvent publishes photo posted event of the photo's trip, errors do not fail the request
+func (s *Server) publishPhotoEvent(ctx context.Context, userID string, photo *album.Photo) {
+	t, err := s.tripService.GetTrip(ctx, photo.TripID)
+	if err != nil {
+		logging.FromContext(ctx).Error("publish photo event failed", "trip_id", photo.TripID, "photo_id", photo.ID, "error", err.Error())
+		return
+	}
+
+	s.publishTripEvent(ctx, feed.EventPhotoPosted, userID, t, photo.ID)
+}
@@ -1,7 +1,6 @@
 package app
 
 import (
-	"log"
 	"net/http"
 	"speakeasy/internal/pkg/authentication"
 	"speakeasy/internal/pkg/profile"
@@ -13,21 +12,16 @@ import (
 // Login Gin handler function to login and get access token
 func (s *Server) Login() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Header("Content-Type", "application/json")
-
 		// read and validate request body
 		var request authentication.LoginRequest
-		if err := c.Bind(&request); err != nil {
-			LogAndSendErrorResponse(c, &pkg.Error{
-				Code:   http.StatusBadRequest,
-				Reason: "Bad Request",
-			})
+		if err := c.ShouldBind(&request); err != nil {
+			c.Error(pkg.BindingError(err))
 			return
 		}
 
-		login, err := s.authenticationService.Login(request)
+		login, err := s.authenticationService.Login(c.Request.Context(), request)
 		if err != nil {
-			LogAndSendErrorResponse(c, err)
+			c.Error(err)
 			return
 		}
 
@@ -38,30 +32,27 @@ func (s *Server) Login() gin.HandlerFunc {
 // Signup Gin handler function to signup user
 func (s *Server) Signup() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Header("Content-Type", "application/json")
-
 		var request authentication.SignupRequest
-		if err := c.Bind(&request); err != nil {
-			LogAndSendErrorResponse(c, &pkg.Error{
-				Code:   http.StatusBadRequest,
-				Reason: "Bad Request",
-			})
+		if err := c.ShouldBind(&request); err != nil {
+			c.Error(pkg.BindingError(err))
 			return
 		}
 
-		signup, err := s.authenticationService.Signup(request)
-		if err != nil || !signup.Status {
-			LogAndSendErrorResponse(c, err)
+		signup, err := s.authenticationService.Signup(c.Request.Context(), request)
+		if err != nil {
+			c.Error(err)
 			return
 		}
 
-		err = s.profileService.PutProfile(&profile.Profile{
-			UserID: signup.UserID,
-			Name:   request.Name,
+		err = s.profileService.PutProfile(c.Request.Context(), &profile.Profile{
+			UserID:    signup.UserID,
+			Name:      request.Name,
+			Phone:     request.Phone,
+			BirthDate: request.BirthDate,
 		})
 
 		if err != nil {
-			LogAndSendErrorResponse(c, err)
+			c.Error(err)
 			return
 		}
 
@@ -74,39 +65,21 @@ func (s *Server) Signup() gin.HandlerFunc {
 	}
 }
 
-// Signup Gin handler function to signup user
+// Refresh Gin handler function to get new access token with refresh token
 func (s *Server) Refresh() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Header("Content-Type", "application/json")
-
 		var request authentication.RefreshRequest
-		if err := c.Bind(&request); err != nil {
-			LogAndSendErrorResponse(c, &pkg.Error{
-				Code:   http.StatusBadRequest,
-				Reason: "Bad Request",
-			})
+		if err := c.ShouldBind(&request); err != nil {
+			c.Error(pkg.BindingError(err))
 			return
 		}
 
-		token, err := s.authenticationService.Refresh(&request)
+		token, err := s.authenticationService.Refresh(c.Request.Context(), &request)
 		if err != nil {
-			LogAndSendErrorResponse(c, &pkg.Error{
-				Code:   http.StatusUnauthorized,
-				Reason: "Unauthorized",
-			})
+			c.Error(&pkg.Error{Code: http.StatusUnauthorized, Type: pkg.TypeTokenExpired, Reason: "Refresh token is invalid or expired", Err: err})
 			return
 		}
 
 		c.JSON(http.StatusOK, token)
 	}
 }
-
-func LogAndSendErrorResponse(c *gin.Context, err *pkg.Error) {
-	log.Printf("(%s) error: Reason: %s, Code: %d", c.Request.URL, err.Reason, err.Code)
-	response := map[string]any{
-		"status":  err.Code,
-		"message": err.Reason,
-	}
-
-	c.JSON(err.Code, response)
-}
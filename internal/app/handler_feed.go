@@ -0,0 +1,82 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"speakeasy/internal/pkg/feed"
+	"speakeasy/internal/pkg/social"
+	"speakeasy/internal/pkg/trip"
+	"speakeasy/pkg"
+	"speakeasy/pkg/database"
+	"speakeasy/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetFeed Gin handler function to get the activity feed of the authenticated user
+func (s *Server) GetFeed() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var page social.PageRequest
+		if err := c.ShouldBindQuery(&page); err != nil {
+			c.Error(pkg.BindingError(err))
+			return
+		}
+
+		userID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		result, err := s.feedService.GetFeed(c.Request.Context(), userID, &page)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, database.MapPage(result, (*feed.Item).Response))
+	}
+}
+
+// publishTripEvent publishes trip activity to the feed of the actor's followers and friends.
+// Activity on private trips is not published, and errors do not fail the request.
+func (s *Server) publishTripEvent(ctx context.Context, eventType feed.EventType, actorID string, t *trip.Trip, objectID string) {
+	var audience feed.Audience
+	switch t.Visibility {
+	case trip.VisibilityPublic:
+		audience = feed.AudiencePublic
+	case trip.VisibilityFriends:
+		audience = feed.AudienceFriends
+	default:
+		return
+	}
+
+	err := s.feedService.Publish(ctx, &feed.Event{
+		Type:     eventType,
+		ActorID:  actorID,
+		TripID:   t.ID,
+		TripName: t.Name,
+		ObjectID: objectID,
+		Audience: audience,
+	})
+
+	if err != nil {
+		logging.FromContext(ctx).Error("publish trip event failed", "trip_id", t.ID, "event_type", eventType, "error", err.Error())
+	}
+}
+
+// backfillTimeout is the longest time a feed backfill may add to the request which started it
+const backfillTimeout = 3 * time.Second
+
+// backfillFeed copies recent activity of the followed user into the follower's feed within the request,
+// work started after the request returned would be frozen with the Lambda, errors do not fail the request
+func (s *Server) backfillFeed(ctx context.Context, followerID string, followeeID string) {
+	ctx, cancel := context.WithTimeout(ctx, backfillTimeout)
+	defer cancel()
+
+	if err := s.feedService.Backfill(ctx, followerID, followeeID); err != nil {
+		logging.FromContext(ctx).Error("backfill feed failed", "followee_id", followeeID, "error", err.Error())
+	}
+}
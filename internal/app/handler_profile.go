@@ -1,47 +1,56 @@
 package app
 
 import (
-	"log"
+	"errors"
+	"io"
+	"mime"
 	"net/http"
-	"speakeasy/internal/pkg/authentication"
 	"speakeasy/internal/pkg/profile"
+	"speakeasy/pkg"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-// UploadProfilePicture Gin handler function to create trip
+// maxProfilePatchSize is the maximum size of PatchProfile request body
+const maxProfilePatchSize = 64 << 10
+
+// UploadProfilePicture Gin handler function to upload profile picture of the authenticated user
 func (s *Server) UploadProfilePicture() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		file, _, _ := c.Request.FormFile("profile_pic")
-
-		// Get UserID from JWT
-		claims, errauth := authentication.GetTokenClaims(c.Request)
-		if errauth != nil {
-			log.Printf("error(handler.UploadProfilePicture): %s", errauth)
-			response := map[string]any{
-				"status":  401,
-				"message": "Token expired",
-			}
-
-			c.JSON(401, response)
+		userID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
 			return
 		}
-		userID := ((*claims)["user_id"]).(string)
 
-		if err := s.profileService.UploadProfilePicture(userID, file); err != nil {
-			log.Printf("error(handler.UploadProfilePicture): %s", err)
-			response := map[string]any{
-				"status":  http.StatusBadRequest,
-				"message": "Bad Request",
+		// Leave room for the multipart headers, the picture size is checked by the service
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, profile.MaxProfilePictureSize+1<<20)
+
+		file, _, formErr := c.Request.FormFile("profile_pic")
+		if formErr != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(formErr, &maxBytesErr) {
+				c.Error(&pkg.Error{Code: http.StatusRequestEntityTooLarge, Reason: "Profile picture is too large", Err: formErr})
+				return
 			}
 
-			c.JSON(http.StatusBadRequest, response)
+			c.Error(&pkg.Error{Code: http.StatusBadRequest, Reason: "Profile picture is required", Err: formErr})
+			return
+		}
+		defer file.Close()
+
+		profile, perr := s.profileService.UploadProfilePicture(c.Request.Context(), userID, file)
+		if perr != nil {
+			c.Error(perr)
 			return
 		}
 
 		// File saved successfully. Return proper result
 		c.JSON(http.StatusOK, gin.H{
-			"message": "Your profile picture has been successfully updated.",
+			"message":          "Your profile picture has been successfully updated.",
+			"profile_pic_url":  profile.ProfilePicUrl,
+			"profile_pic_urls": profile.ProfilePicUrls,
 		})
 	}
 }
@@ -49,41 +58,21 @@ func (s *Server) UploadProfilePicture() gin.HandlerFunc {
 // CreateProfile handler function
 func (s *Server) CreateProfile() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var request profile.Profile
-
-		// Get UserID from JWT
-		claims, errauth := authentication.GetTokenClaims(c.Request)
-		if errauth != nil {
-			log.Printf("error(handler.CreateProfile): %s", errauth)
-			response := map[string]any{
-				"status":  401,
-				"message": "Token expired",
-			}
+		var request profile.ProfileRequest
 
-			c.JSON(401, response)
+		userID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
 			return
 		}
-		request.UserID = ((*claims)["user_id"]).(string)
 
-		if err := c.Bind(&request); err != nil {
-			log.Printf("(handler.CreateProfile) error: %s", err)
-			response := map[string]any{
-				"status":  http.StatusBadRequest,
-				"message": "Bad Request",
-			}
-
-			c.JSON(http.StatusBadRequest, response)
+		if err := c.ShouldBind(&request); err != nil {
+			c.Error(pkg.BindingError(err))
 			return
 		}
 
-		if err := s.profileService.PutProfile(&request); err != nil {
-			log.Printf("(handler.CreateProfile) error: %v", err)
-			response := map[string]any{
-				"status":  err.Code,
-				"message": err.Reason,
-			}
-
-			c.JSON(err.Code, response)
+		if err := s.profileService.PutProfile(c.Request.Context(), request.Profile(userID)); err != nil {
+			c.Error(err)
 			return
 		}
 
@@ -93,35 +82,161 @@ func (s *Server) CreateProfile() gin.HandlerFunc {
 	}
 }
 
-// GetProfile handler function
-func (s *Server) GetMyProfile() gin.HandlerFunc {
+// PatchProfile Gin handler function to change only the profile fields in the JSON Merge Patch body,
+// fields set to null are cleared
+func (s *Server) PatchProfile() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get UserID from JWT
-		claims, errauth := authentication.GetTokenClaims(c.Request)
-		if errauth != nil {
-			log.Printf("(handler.GetProfile) error: %s", errauth)
-			response := map[string]any{
-				"status":  401,
-				"message": "Token expired",
+		userID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		if mediaType != "application/merge-patch+json" && mediaType != "application/json" {
+			c.Error(&pkg.Error{Code: http.StatusUnsupportedMediaType, Reason: "Content type must be application/merge-patch+json"})
+			return
+		}
+
+		patch, readErr := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxProfilePatchSize))
+		if readErr != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(readErr, &maxBytesErr) {
+				c.Error(&pkg.Error{Code: http.StatusRequestEntityTooLarge, Reason: "Patch is too large", Err: readErr})
+				return
 			}
 
-			c.JSON(401, response)
+			c.Error(pkg.BindingError(readErr))
+			return
+		}
+
+		updated, perr := s.profileService.PatchProfile(c.Request.Context(), userID, patch)
+		if perr != nil {
+			c.Error(perr)
 			return
 		}
-		userID := ((*claims)["user_id"]).(string)
 
-		profile, err := s.profileService.GetProfile(userID)
+		c.JSON(http.StatusOK, updated.Response())
+	}
+}
+
+// GetProfile handler function
+func (s *Server) GetMyProfile() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := authenticatedUserID(c)
 		if err != nil {
-			log.Printf("(hander.GetProfile) error: %v", err)
-			response := map[string]any{
-				"status":  err.Code,
-				"message": err.Reason,
+			c.Error(err)
+			return
+		}
+
+		profile, err := s.profileService.GetProfile(c.Request.Context(), userID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, profile.Response())
+	}
+}
+
+// GetProfile Gin handler function to get public profile of user by user id or @handle,
+// the user's privacy settings decide which fields are visible to the viewer
+func (s *Server) GetProfile() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("userid")
+
+		// Gin doesn't allow /profile/@:handle next to /profile/:userid, handles are resolved here
+		if strings.HasPrefix(userID, "@") {
+			var err *pkg.Error
+			if userID, err = s.profileService.ResolveHandle(c.Request.Context(), userID); err != nil {
+				c.Error(err)
+				return
 			}
+		}
+
+		relation, blocked, err := s.relationTo(c.Request.Context(), optionalUserID(c), userID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
 
-			c.JSON(err.Code, response)
+		if blocked {
+			c.Error(&pkg.Error{Code: http.StatusNotFound, Reason: "Profile not found"})
+			return
+		}
+
+		profile, err := s.profileService.GetPublicProfile(c.Request.Context(), userID, relation)
+		if err != nil {
+			c.Error(err)
 			return
 		}
 
 		c.JSON(http.StatusOK, profile)
 	}
 }
+
+// SetHandle Gin handler function to change the handle of the authenticated user's profile
+func (s *Server) SetHandle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// read and validate request body
+		var request profile.SetHandleRequest
+		if err := c.ShouldBind(&request); err != nil {
+			c.Error(pkg.BindingError(err))
+			return
+		}
+
+		userID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		if err := s.profileService.SetHandle(c.Request.Context(), userID, request.Handle); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response := map[string]any{
+			"status":  http.StatusOK,
+			"message": "Handle updated",
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// SearchProfiles Gin handler function to search profiles by name prefix,
+// users blocked by or blocking the viewer are not included
+func (s *Server) SearchProfiles() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filter profile.SearchFilter
+		if err := c.ShouldBindQuery(&filter); err != nil {
+			c.Error(pkg.BindingError(err))
+			return
+		}
+
+		result, err := s.profileService.SearchProfiles(c.Request.Context(), &filter)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		if viewerID := optionalUserID(c); viewerID != "" {
+			blocked, err := s.socialService.GetBlockedSet(c.Request.Context(), viewerID)
+			if err != nil {
+				c.Error(err)
+				return
+			}
+
+			profiles := []profile.PublicProfile{}
+			for _, p := range result.Items {
+				if !blocked[p.UserID] {
+					profiles = append(profiles, p)
+				}
+			}
+			result.Items = profiles
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,182 @@
+package app
+
+import (
+	"context"
+	"net/http"
+
+	"speakeasy/internal/pkg/social"
+	"speakeasy/pkg"
+	"speakeasy/pkg/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Follow Gin handler function to follow user, recent activity of the user is added to the follower's feed
+func (s *Server) Follow() gin.HandlerFunc {
+	return s.relationshipHandler(s.withExistingTarget(func(ctx context.Context, userID string, targetID string) *pkg.Error {
+		if err := s.socialService.Follow(ctx, userID, targetID); err != nil {
+			return err
+		}
+
+		s.backfillFeed(ctx, userID, targetID)
+		return nil
+	}), "Followed")
+}
+
+// Unfollow Gin handler function to unfollow user
+func (s *Server) Unfollow() gin.HandlerFunc {
+	return s.relationshipHandler(s.socialService.Unfollow, "Unfollowed")
+}
+
+// SendFriendRequest Gin handler function to send friend request to user
+func (s *Server) SendFriendRequest() gin.HandlerFunc {
+	return s.relationshipHandler(s.withExistingTarget(s.socialService.SendFriendRequest), "Friend request sent")
+}
+
+// AcceptFriendRequest Gin handler function to accept friend request from user,
+// recent activity of each user is added to the other user's feed
+func (s *Server) AcceptFriendRequest() gin.HandlerFunc {
+	return s.relationshipHandler(func(ctx context.Context, userID string, requesterID string) *pkg.Error {
+		if err := s.socialService.AcceptFriendRequest(ctx, userID, requesterID); err != nil {
+			return err
+		}
+
+		s.backfillFeed(ctx, userID, requesterID)
+		s.backfillFeed(ctx, requesterID, userID)
+		return nil
+	}, "Friend request accepted")
+}
+
+// DeclineFriendRequest Gin handler function to decline friend request from user
+func (s *Server) DeclineFriendRequest() gin.HandlerFunc {
+	return s.relationshipHandler(s.socialService.DeclineFriendRequest, "Friend request declined")
+}
+
+// RemoveFriend Gin handler function to remove friend
+func (s *Server) RemoveFriend() gin.HandlerFunc {
+	return s.relationshipHandler(s.socialService.RemoveFriend, "Friend removed")
+}
+
+// GetFollowers Gin handler function to list followers of user
+func (s *Server) GetFollowers() gin.HandlerFunc {
+	return s.relationshipListHandler(s.socialService.GetFollowers)
+}
+
+// GetFollowing Gin handler function to list users followed by user
+func (s *Server) GetFollowing() gin.HandlerFunc {
+	return s.relationshipListHandler(s.socialService.GetFollowing)
+}
+
+// GetFriends Gin handler function to list friends of user
+func (s *Server) GetFriends() gin.HandlerFunc {
+	return s.relationshipListHandler(s.socialService.GetFriends)
+}
+
+// GetFriendRequests Gin handler function to list friend requests received by the authenticated user
+func (s *Server) GetFriendRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Params = append(c.Params, gin.Param{Key: "userid", Value: "me"})
+		s.relationshipListHandler(s.socialService.GetFriendRequests)(c)
+	}
+}
+
+// Block Gin handler function to block user
+func (s *Server) Block() gin.HandlerFunc {
+	return s.relationshipHandler(s.socialService.Block, "Blocked")
+}
+
+// Unblock Gin handler function to unblock user
+func (s *Server) Unblock() gin.HandlerFunc {
+	return s.relationshipHandler(s.socialService.Unblock, "Unblocked")
+}
+
+// GetBlockedUsers Gin handler function to list users blocked by the authenticated user
+func (s *Server) GetBlockedUsers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Params = append(c.Params, gin.Param{Key: "userid", Value: "me"})
+		s.relationshipListHandler(s.socialService.GetBlocked)(c)
+	}
+}
+
+// relationshipHandler returns Gin handler function which applies action from authenticated user to :userid
+func (s *Server) relationshipHandler(action func(ctx context.Context, userID string, targetID string) *pkg.Error, message string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		if err := action(c.Request.Context(), userID, c.Param("userid")); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response := map[string]any{
+			"status":  http.StatusOK,
+			"message": message,
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// withExistingTarget returns relationship action which is rejected with 400 if the target is the user,
+// or with 404 if the target doesn't exist
+func (s *Server) withExistingTarget(action func(ctx context.Context, userID string, targetID string) *pkg.Error) func(ctx context.Context, userID string, targetID string) *pkg.Error {
+	return func(ctx context.Context, userID string, targetID string) *pkg.Error {
+		if userID == targetID {
+			return &pkg.Error{Code: http.StatusBadRequest, Reason: "Target cannot be yourself"}
+		}
+
+		exists, err := s.profileService.ProfileExists(ctx, targetID)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return &pkg.Error{Code: http.StatusNotFound, Reason: "User not found"}
+		}
+
+		return action(ctx, userID, targetID)
+	}
+}
+
+// relationshipListHandler returns Gin handler function which lists a page of relationships of :userid,
+// "me" can be used as :userid for the authenticated user.
+func (s *Server) relationshipListHandler(list func(ctx context.Context, userID string, page *social.PageRequest) (*database.Page[social.Relationship], *pkg.Error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var page social.PageRequest
+		if err := c.ShouldBindQuery(&page); err != nil {
+			c.Error(pkg.BindingError(err))
+			return
+		}
+
+		viewerID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		userID := c.Param("userid")
+		if userID == "me" {
+			userID = viewerID
+		}
+
+		// Lists of other users are hidden like their profile, e.g. from users they blocked
+		if userID != viewerID {
+			if err := s.checkProfileVisible(c.Request.Context(), viewerID, userID); err != nil {
+				c.Error(err)
+				return
+			}
+		}
+
+		result, err := list(c.Request.Context(), userID, &page)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, database.MapPage(result, (*social.Relationship).Response))
+	}
+}
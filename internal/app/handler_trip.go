@@ -3,8 +3,9 @@ package app
 import (
 	"net/http"
 
-	"speakeasy/internal/pkg/authentication"
+	"speakeasy/internal/pkg/feed"
 	"speakeasy/internal/pkg/trip"
+	"speakeasy/pkg"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,46 +13,30 @@ import (
 // CreateTrip Gin handler function to create trip
 func (s *Server) CreateTrip() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Header("Content-Type", "application/json")
-
 		// read and validate request body
-		var request trip.Trip
-		if err := c.Bind(&request); err != nil {
-			response := map[string]any{
-				"status":  http.StatusBadRequest,
-				"message": "Bad Request",
-			}
-
-			c.JSON(http.StatusBadRequest, response)
+		var request trip.TripRequest
+		if err := c.ShouldBind(&request); err != nil {
+			c.Error(pkg.BindingError(err))
 			return
 		}
 
-		// Get UserID from JWT
-		claims, errauth := authentication.GetTokenClaims(c.Request)
-		if errauth != nil {
-			response := map[string]any{
-				"status":  401,
-				"message": "Token expired",
-			}
-
-			c.JSON(401, response)
+		userID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
 			return
 		}
 
-		userID := ((*claims)["user_id"]).(string)
-		request.CreatedBy = userID
+		created := request.Trip()
+		created.CreatedBy = userID
 
-		err := s.tripService.CreateTrip(&request)
+		err = s.tripService.CreateTrip(c.Request.Context(), created)
 		if err != nil {
-			response := map[string]any{
-				"status":  err.Code,
-				"message": err.Reason,
-			}
-
-			c.JSON(err.Code, response)
+			c.Error(err)
 			return
 		}
 
+		s.publishTripEvent(c.Request.Context(), feed.EventTripCreated, userID, created, "")
+
 		response := map[string]any{
 			"status":  http.StatusCreated,
 			"message": "Created",
@@ -61,44 +46,286 @@ func (s *Server) CreateTrip() gin.HandlerFunc {
 	}
 }
 
-// GetTrip Gin handler function to get trip by trip id
+// GetTrip Gin handler function to get trip by trip id, the trip must be visible to the user
 func (s *Server) GetTrip() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Header("Content-Type", "application/json")
 		tripID := c.Param("tripid")
 
-		trip, err := s.tripService.GetTrip(tripID)
+		trip, err := s.tripService.GetTrip(c.Request.Context(), tripID)
 		if err != nil {
-			response := map[string]any{
-				"status":  err.Code,
-				"message": err.Reason,
-			}
+			c.Error(err)
+			return
+		}
 
-			c.JSON(err.Code, response)
+		visible, err := s.canViewTrip(c.Request.Context(), optionalUserID(c), trip)
+		if err != nil {
+			c.Error(err)
 			return
 		}
 
-		c.JSON(http.StatusOK, trip)
+		if !visible {
+			c.Error(&pkg.Error{Code: http.StatusForbidden, Reason: "Forbidden"})
+			return
+		}
+
+		c.JSON(http.StatusOK, trip.Response())
 	}
 }
 
-// GetUserTrips Gin handler function to get trip by trip id
+// GetTripParticipants Gin handler function to get participants of a trip visible to the user,
+// participants blocked by or blocking the user are not included
 func (s *Server) GetTripParticipants() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Header("Content-Type", "application/json")
 		tripID := c.Param("tripid")
+		viewerID := optionalUserID(c)
+
+		t, err := s.tripService.GetTrip(c.Request.Context(), tripID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		visible, err := s.canViewTrip(c.Request.Context(), viewerID, t)
+		if err != nil {
+			c.Error(err)
+			return
+		}
 
-		trips, err := s.tripService.GetTripParticipants(tripID)
+		if !visible {
+			c.Error(&pkg.Error{Code: http.StatusForbidden, Reason: "Forbidden"})
+			return
+		}
+
+		trips, err := s.tripService.GetTripParticipants(c.Request.Context(), tripID)
 		if err != nil {
-			response := map[string]any{
-				"status":  err.Code,
-				"message": err.Reason,
+			c.Error(err)
+			return
+		}
+
+		blocked := map[string]bool{}
+		if viewerID != "" {
+			blocked, err = s.socialService.GetBlockedSet(c.Request.Context(), viewerID)
+			if err != nil {
+				c.Error(err)
+				return
 			}
+		}
+
+		participants := []trip.TripResponse{}
+		for _, participant := range *trips {
+			if !blocked[participant.ParticipantID] {
+				participants = append(participants, participant.Response())
+			}
+		}
+
+		c.JSON(http.StatusOK, participants)
+	}
+}
+
+// UpdateTrip Gin handler function to update trip details
+func (s *Server) UpdateTrip() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// read and validate request body
+		var request trip.TripRequest
+		if err := c.ShouldBind(&request); err != nil {
+			c.Error(pkg.BindingError(err))
+			return
+		}
+
+		userID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		updated := request.Trip()
+		updated.ID = c.Param("tripid")
+
+		if err := s.tripService.UpdateTrip(c.Request.Context(), userID, updated); err != nil {
+			c.Error(err)
+			return
+		}
+
+		response := map[string]any{
+			"status":  http.StatusOK,
+			"message": "Updated",
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// UpdateParticipantRole Gin handler function to change the role of a trip participant
+func (s *Server) UpdateParticipantRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// read and validate request body
+		var request trip.UpdateRoleRequest
+		if err := c.ShouldBind(&request); err != nil {
+			c.Error(pkg.BindingError(err))
+			return
+		}
+
+		userID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
 
-			c.JSON(err.Code, response)
+		err = s.tripService.UpdateParticipantRole(c.Request.Context(), userID, c.Param("tripid"), c.Param("userid"), request.Role)
+		if err != nil {
+			c.Error(err)
 			return
 		}
 
-		c.JSON(http.StatusOK, trips)
+		response := map[string]any{
+			"status":  http.StatusOK,
+			"message": "Updated",
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// TransferTripOwnership Gin handler function to transfer trip ownership to another participant
+func (s *Server) TransferTripOwnership() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// read and validate request body
+		var request trip.TransferOwnershipRequest
+		if err := c.ShouldBind(&request); err != nil {
+			c.Error(pkg.BindingError(err))
+			return
+		}
+
+		userID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		err = s.tripService.TransferOwnership(c.Request.Context(), userID, c.Param("tripid"), request.UserID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response := map[string]any{
+			"status":  http.StatusOK,
+			"message": "Updated",
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// RemoveTripParticipant Gin handler function to remove participant from trip
+func (s *Server) RemoveTripParticipant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		err = s.tripService.RemoveParticipant(c.Request.Context(), userID, c.Param("tripid"), c.Param("userid"))
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response := map[string]any{
+			"status":  http.StatusOK,
+			"message": "Removed",
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// CreateTripInviteLink Gin handler function to create a shareable invite link token
+func (s *Server) CreateTripInviteLink() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// request body is optional, defaults are used when empty
+		var request trip.CreateInviteLinkRequest
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBind(&request); err != nil {
+				c.Error(pkg.BindingError(err))
+				return
+			}
+		}
+
+		userID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		link, err := s.tripService.CreateInviteLink(c.Request.Context(), userID, c.Param("tripid"), &request)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, link)
+	}
+}
+
+// RevokeTripInviteLink Gin handler function to revoke an invite link
+func (s *Server) RevokeTripInviteLink() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		err = s.tripService.RevokeInviteLink(c.Request.Context(), userID, c.Param("tripid"), c.Param("linkid"))
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		response := map[string]any{
+			"status":  http.StatusOK,
+			"message": "Revoked",
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// JoinTrip Gin handler function to join trip using an invite link token
+func (s *Server) JoinTrip() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		// Invitations from or to trips of blocked users are rejected
+		canJoin := func(t *trip.Trip, link *trip.InviteLink) *pkg.Error {
+			for _, otherID := range []string{t.CreatedBy, link.CreatedBy} {
+				blocked, err := s.socialService.IsBlocked(c.Request.Context(), userID, otherID)
+				if err != nil {
+					return err
+				}
+
+				if blocked {
+					return &pkg.Error{Code: http.StatusForbidden, Reason: "Forbidden"}
+				}
+			}
+
+			return nil
+		}
+
+		trip, err := s.tripService.JoinTrip(c.Request.Context(), userID, c.Param("token"), canJoin)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		s.publishTripEvent(c.Request.Context(), feed.EventTripJoined, userID, trip, "")
+
+		c.JSON(http.StatusOK, trip.Response())
 	}
 }
@@ -3,62 +3,147 @@ package app
 import (
 	"net/http"
 
-	"speakeasy/internal/pkg/authentication"
+	"speakeasy/internal/pkg/profile"
+	"speakeasy/internal/pkg/trip"
+	"speakeasy/pkg"
 
 	"github.com/gin-gonic/gin"
 )
 
-// GetUserTrips Gin handler function to get trip by trip id
+// GetUserTrips Gin handler function to get trips of user, the user's privacy settings decide if
+// the trip history is visible and only trips visible to the viewer are returned.
+// Trips the user joined are checked against the viewer's relation to their creator, like GetTrip.
 func (s *Server) GetUserTrips() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Header("Content-Type", "application/json")
 		userID := c.Param("userid")
+		viewerID := optionalUserID(c)
 
-		trips, err := s.tripService.GetTripsByUser(userID)
+		relation, blocked, err := s.relationTo(c.Request.Context(), viewerID, userID)
 		if err != nil {
-			response := map[string]any{
-				"status":  err.Code,
-				"message": err.Reason,
-			}
+			c.Error(err)
+			return
+		}
 
-			c.JSON(err.Code, response)
+		if blocked {
+			c.Error(&pkg.Error{Code: http.StatusForbidden, Reason: "Forbidden"})
 			return
 		}
 
-		c.JSON(http.StatusOK, trips)
+		userProfile, err := s.profileService.GetProfile(c.Request.Context(), userID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		if !userProfile.Privacy.TripHistory.Allows(relation) {
+			c.Error(&pkg.Error{Code: http.StatusForbidden, Reason: "Trip history is private"})
+			return
+		}
+
+		trips, err := s.tripService.GetTripsByUser(c.Request.Context(), userID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		visible := []trip.TripResponse{}
+		for _, t := range *trips {
+			ok, err := s.canViewTrip(c.Request.Context(), viewerID, &t)
+			if err != nil {
+				c.Error(err)
+				return
+			}
+
+			if ok {
+				visible = append(visible, t.Response())
+			}
+		}
+
+		c.JSON(http.StatusOK, visible)
+	}
+}
+
+// tripVisibleTo returns true if the trip visibility allows viewers with the relation to the trip creator
+func tripVisibleTo(t *trip.Trip, relation profile.Relation) bool {
+	switch t.Visibility {
+	case trip.VisibilityPublic:
+		return true
+	case trip.VisibilityFriends:
+		return relation >= profile.RelationFriend
+	default:
+		return relation == profile.RelationSelf
 	}
 }
 
-// GetUserTrips Gin handler function to get trip by trip id
+// GetMyTrips Gin handler function to search trips of the authenticated user
 func (s *Server) GetMyTrips() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Header("Content-Type", "application/json")
-
-		// Get UserID from JWT
-		claims, errauth := authentication.GetTokenClaims(c.Request)
-		if errauth != nil {
-			response := map[string]any{
-				"status":  401,
-				"message": "Token expired",
-			}
+		var filter trip.TripFilter
+		if err := c.ShouldBindQuery(&filter); err != nil {
+			c.Error(pkg.BindingError(err))
+			return
+		}
 
-			c.JSON(401, response)
+		userID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
 			return
 		}
 
-		userID := ((*claims)["user_id"]).(string)
+		trips, err := s.tripService.SearchTripsByUser(c.Request.Context(), userID, &filter)
+		if err != nil {
+			c.Error(err)
+			return
+		}
 
-		trips, err := s.tripService.GetTripsByUser(userID)
+		c.JSON(http.StatusOK, trips)
+	}
+}
+
+// publicTrip object which is a public trip with the creator profile summary embedded
+type publicTrip struct {
+	trip.TripResponse
+	Creator *profile.Summary `json:"creator,omitempty"`
+}
+
+// GetPublicTrips Gin handler function to list upcoming public trips
+func (s *Server) GetPublicTrips() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filter trip.PublicTripFilter
+		if err := c.ShouldBindQuery(&filter); err != nil {
+			c.Error(pkg.BindingError(err))
+			return
+		}
+
+		page, err := s.tripService.GetPublicTrips(c.Request.Context(), &filter)
 		if err != nil {
-			response := map[string]any{
-				"status":  err.Code,
-				"message": err.Reason,
-			}
+			c.Error(err)
+			return
+		}
+
+		creatorIDs := []string{}
+		for _, item := range page.Items {
+			creatorIDs = append(creatorIDs, item.CreatedBy)
+		}
 
-			c.JSON(err.Code, response)
+		creators, err := s.profileService.GetProfileSummaries(c.Request.Context(), creatorIDs)
+		if err != nil {
+			c.Error(err)
 			return
 		}
 
-		c.JSON(http.StatusOK, trips)
+		items := []publicTrip{}
+		for _, item := range page.Items {
+			result := publicTrip{TripResponse: item.Response()}
+			if creator, ok := creators[item.CreatedBy]; ok {
+				result.Creator = &creator
+			}
+			items = append(items, result)
+		}
+
+		c.JSON(http.StatusOK, map[string]any{
+			"items":  items,
+			"cursor": page.Cursor,
+		})
 	}
 }
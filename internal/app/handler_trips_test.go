@@ -0,0 +1,130 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"speakeasy/internal/pkg/profile"
+	"speakeasy/internal/pkg/social"
+	"speakeasy/internal/pkg/trip"
+	"speakeasy/pkg"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// Mock TripService which returns the trips of every user and knows the participants of each trip
+type _TripServiceMock struct {
+	trip.Service
+	trips        []trip.Trip
+	participants map[string][]string
+}
+
+func (svc *_TripServiceMock) GetTripsByUser(ctx context.Context, userID string) (*[]trip.Trip, *pkg.Error) {
+	return &svc.trips, nil
+}
+
+func (svc *_TripServiceMock) CheckPermission(ctx context.Context, tripID string, userID string, permission trip.Permission) (*trip.Trip, *pkg.Error) {
+	for _, participant := range svc.participants[tripID] {
+		if participant == userID {
+			return &trip.Trip{ID: tripID, ParticipantID: userID, Role: trip.RoleViewer}, nil
+		}
+	}
+	return nil, &pkg.Error{Code: http.StatusForbidden, Reason: "Forbidden"}
+}
+
+// Mock SocialService where nobody is blocked and friends contains both users of each friendship
+type _SocialServiceMock struct {
+	social.Service
+	friends map[string]string
+}
+
+func (svc *_SocialServiceMock) IsBlocked(ctx context.Context, userID string, otherID string) (bool, *pkg.Error) {
+	return false, nil
+}
+
+func (svc *_SocialServiceMock) AreFriends(ctx context.Context, userID string, otherID string) (bool, *pkg.Error) {
+	return svc.friends[userID] == otherID || svc.friends[otherID] == userID, nil
+}
+
+// Mock ProfileService where every profile has the default privacy settings
+type _ProfileServiceMock struct {
+	profile.Service
+}
+
+func (svc *_ProfileServiceMock) GetProfile(ctx context.Context, userID string) (*profile.Profile, *pkg.Error) {
+	return &profile.Profile{UserID: userID}, nil
+}
+
+// serveAs handles the request with the handler as the authenticated viewer
+func serveAs(viewerID string, path string, route string, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Use(ErrorHandler(), func(c *gin.Context) {
+		c.Set(userIDKey, viewerID)
+	})
+	router.GET(route, handler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestGetUserTrips(t *testing.T) {
+	trips := []trip.Trip{
+		{ID: "own", CreatedBy: "user", Visibility: trip.VisibilityFriends},
+		{ID: "joined", CreatedBy: "creator", Visibility: trip.VisibilityFriends},
+		{ID: "public", CreatedBy: "creator", Visibility: trip.VisibilityPublic},
+	}
+
+	tripIDs := func(w *httptest.ResponseRecorder) []string {
+		var result []trip.TripResponse
+		json.Unmarshal(w.Body.Bytes(), &result)
+
+		ids := []string{}
+		for _, t := range result {
+			ids = append(ids, t.ID)
+		}
+		return ids
+	}
+
+	t.Run("SUCCESS: HIDE FRIENDS ONLY TRIP WHOSE CREATOR IS NOT A FRIEND OF THE VIEWER", func(t *testing.T) {
+		s := &Server{
+			tripService:    &_TripServiceMock{trips: trips},
+			socialService:  &_SocialServiceMock{friends: map[string]string{"viewer": "user"}},
+			profileService: &_ProfileServiceMock{},
+		}
+
+		w := serveAs("viewer", "/users/user/trips", "/users/:userid/trips", s.GetUserTrips())
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"own", "public"}, tripIDs(w))
+	})
+
+	t.Run("SUCCESS: SHOW FRIENDS ONLY TRIP TO FRIEND OF ITS CREATOR", func(t *testing.T) {
+		s := &Server{
+			tripService:    &_TripServiceMock{trips: trips},
+			socialService:  &_SocialServiceMock{friends: map[string]string{"viewer": "creator"}},
+			profileService: &_ProfileServiceMock{},
+		}
+
+		w := serveAs("viewer", "/users/user/trips", "/users/:userid/trips", s.GetUserTrips())
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"joined", "public"}, tripIDs(w))
+	})
+
+	t.Run("SUCCESS: SHOW FRIENDS ONLY TRIP TO PARTICIPANT", func(t *testing.T) {
+		s := &Server{
+			tripService:    &_TripServiceMock{trips: trips, participants: map[string][]string{"joined": {"viewer"}}},
+			socialService:  &_SocialServiceMock{},
+			profileService: &_ProfileServiceMock{},
+		}
+
+		w := serveAs("viewer", "/users/user/trips", "/users/:userid/trips", s.GetUserTrips())
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"joined", "public"}, tripIDs(w))
+	})
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"context"
+	"net/http"
+
+	"speakeasy/internal/pkg/trip"
+	"speakeasy/internal/pkg/upload"
+	"speakeasy/pkg"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateUpload Gin handler function to create a pending upload with a presigned url,
+// the file is uploaded directly to storage so it's not limited by the request body limit
+func (s *Server) CreateUpload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// read and validate request body
+		var request upload.CreateUploadRequest
+		if err := c.ShouldBind(&request); err != nil {
+			c.Error(pkg.BindingError(err))
+			return
+		}
+
+		userID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		// Check permission before the file is uploaded, it's checked again when attaching the file
+		permissions := map[upload.Target]trip.Permission{
+			upload.TargetTripCover: trip.PermissionEdit,
+			upload.TargetTripPhoto: trip.PermissionView,
+		}
+
+		if permission, ok := permissions[request.Target]; ok {
+			if _, err := s.tripService.CheckPermission(c.Request.Context(), request.TargetID, userID, permission); err != nil {
+				c.Error(err)
+				return
+			}
+		}
+
+		result, err := s.uploadService.CreateUpload(c.Request.Context(), userID, &request)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, result)
+	}
+}
+
+// CompleteUpload Gin handler function to verify the uploaded file and attach it to the upload target
+func (s *Server) CompleteUpload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := authenticatedUserID(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		attach := func(u *upload.Upload) *pkg.Error {
+			return s.attachUpload(c.Request.Context(), u)
+		}
+
+		result, err := s.uploadService.CompleteUpload(c.Request.Context(), userID, c.Param("uploadid"), attach)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, result.Response())
+	}
+}
+
+// attachUpload attaches the verified upload to its target
+func (s *Server) attachUpload(ctx context.Context, u *upload.Upload) *pkg.Error {
+	switch u.Target {
+	case upload.TargetProfilePicture:
+		file, err := s.uploadService.OpenFile(ctx, u)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		updated, err := s.profileService.UploadProfilePicture(ctx, u.UserID, file)
+		if err != nil {
+			return err
+		}
+
+		u.Url = updated.ProfilePicUrl
+		return nil
+	case upload.TargetTripCover:
+		file, err := s.uploadService.OpenFile(ctx, u)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		updated, err := s.tripService.SetCoverPhoto(ctx, u.UserID, u.TargetID, file)
+		if err != nil {
+			return err
+		}
+
+		u.Url = updated.CoverPhotoUrl
+		return nil
+	case upload.TargetTripPhoto:
+		file, err := s.uploadService.OpenFile(ctx, u)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		photo, err := s.albumService.AddPhoto(ctx, u.UserID, u.TargetID, file, u.Caption)
+		if err != nil {
+			return err
+		}
+
+		u.Url = photo.Url
+		s.publishPhotoEvent(ctx, u.UserID, photo)
+		return nil
+	default:
+		return &pkg.Error{Code: http.StatusBadRequest, Reason: "Target is invalid"}
+	}
+}
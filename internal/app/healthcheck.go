@@ -2,20 +2,42 @@ package app
 
 import (
 	"net/http"
+	"speakeasy/pkg/health"
 
 	"github.com/gin-gonic/gin"
 )
 
-// HealthCheck Gin handler function to check api health
-func (s *Server) HealthCheck() gin.HandlerFunc {
+// statusDraining is the readiness status while the server is shutting down
+const statusDraining = "draining"
+
+// Liveness Gin handler function which returns 200 while the process can serve requests,
+// it doesn't check dependencies so the task is not restarted when e.g. DynamoDB is unavailable
+func (s *Server) Liveness() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Header("Content-Type", "application/json")
+		c.JSON(http.StatusOK, map[string]any{
+			"status": health.StatusUp,
+		})
+	}
+}
+
+// Readiness Gin handler function which returns the status of each dependency, e.g. DynamoDB tables and S3 bucket.
+// Returns 503 if any dependency is down or while the server is draining, so load balancers stop routing to it.
+func (s *Server) Readiness() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.draining.Load() {
+			c.JSON(http.StatusServiceUnavailable, map[string]any{
+				"status": statusDraining,
+			})
+			return
+		}
+
+		report := s.readiness.Check(c.Request.Context())
 
-		response := map[string]any{
-			"status":  http.StatusOK,
-			"message": "health check successful",
+		status := http.StatusOK
+		if !report.Up() {
+			status = http.StatusServiceUnavailable
 		}
 
-		c.JSON(http.StatusOK, response)
+		c.JSON(status, report)
 	}
 }
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"log/slog"
+	"net/http"
+	"speakeasy/pkg/logging"
+	"time"
+	"unicode"
+
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// requestIDHeader is the header which contains the request ID of requests and responses
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the maximum length of request IDs accepted from clients
+const maxRequestIDLength = 128
+
+// userIDKey is the Gin context key of the authenticated user id
+const userIDKey = "user_id"
+
+// RequestID Gin middleware which adds a logger with the request ID to the request context.
+// The request ID is the X-Request-ID header, the API Gateway request ID of the Lambda event,
+// or a new uuid, and it is returned in the X-Request-ID response header.
+func RequestID(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := requestIDOf(c.Request)
+		c.Header(requestIDHeader, requestID)
+
+		ctx := logging.WithLogger(c.Request.Context(), logger.With("request_id", requestID))
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// AccessLog Gin middleware which logs every request with its route, status and latency,
+// server errors are logged at error level and client errors at warn level, with the error added with c.Error
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []any{
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+			"path", c.Request.URL.Path,
+			"status", status,
+			"latency_ms", time.Since(start).Milliseconds(),
+			"size", c.Writer.Size(),
+		}
+
+		// The error handled by ErrorHandler is logged with the request instead of on its own
+		if err := requestError(c); err != nil {
+			attrs = append(attrs, "code", err.ErrorType(), "error", err.Error())
+		}
+
+		requestLogger(c).Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// requestLogger returns the logger of the request, with the user id once the user is authenticated
+func requestLogger(c *gin.Context) *slog.Logger {
+	return logging.FromContext(c.Request.Context())
+}
+
+// setRequestUser adds the authenticated user id to the request logger
+func setRequestUser(c *gin.Context, userID string) {
+	if _, exists := c.Get(userIDKey); exists {
+		return
+	}
+
+	c.Set(userIDKey, userID)
+
+	ctx := logging.WithLogger(c.Request.Context(), requestLogger(c).With("user_id", userID))
+	c.Request = c.Request.WithContext(ctx)
+}
+
+// requestIDOf returns the request ID from the client, or from API Gateway in Lambda, or a new uuid
+func requestIDOf(r *http.Request) string {
+	if requestID := r.Header.Get(requestIDHeader); validRequestID(requestID) {
+		return requestID
+	}
+
+	if gateway, ok := core.GetAPIGatewayContextFromContext(r.Context()); ok && gateway.RequestID != "" {
+		return gateway.RequestID
+	}
+
+	return uuid.New().String()
+}
+
+// validRequestID returns true if the request ID is not empty, not too long and only contains printable ASCII
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range requestID {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) || r == ' ' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"speakeasy/internal/pkg/authentication"
+	"speakeasy/internal/pkg/profile"
+	"speakeasy/internal/pkg/trip"
+	"speakeasy/pkg"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tokenErrorKey is the Gin context key of the reason the access token of the request is invalid
+const tokenErrorKey = "token_error"
+
+// Authenticate Gin middleware which verifies the access token of the request once,
+// handlers get the user with authenticatedUserID or optionalUserID
+func (s *Server) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tokenString := authentication.ExtractToken(c.Request); tokenString != "" {
+			claims, err := s.authenticationService.VerifyAccessToken(tokenString)
+			userID, _ := claims["user_id"].(string)
+
+			switch {
+			case err != nil:
+				c.Set(tokenErrorKey, err)
+			case userID == "":
+				c.Set(tokenErrorKey, errors.New("token has no user id"))
+			default:
+				setRequestUser(c, userID)
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// authenticatedUserID returns id of the user from the access token, 401 if the token is missing or expired
+func authenticatedUserID(c *gin.Context) (string, *pkg.Error) {
+	if userID := c.GetString(userIDKey); userID != "" {
+		return userID, nil
+	}
+
+	err := errors.New("token is missing")
+	if tokenErr, ok := c.Get(tokenErrorKey); ok {
+		err = tokenErr.(error)
+	}
+
+	return "", &pkg.Error{Code: http.StatusUnauthorized, Type: pkg.TypeTokenExpired, Reason: "Token expired", Err: err}
+}
+
+// optionalUserID returns UserID from JWT, or empty string if the request is not authenticated
+func optionalUserID(c *gin.Context) string {
+	return c.GetString(userIDKey)
+}
+
+// relationTo returns the relation of the viewer to the user, blocked is true if either user blocked the other
+func (s *Server) relationTo(ctx context.Context, viewerID string, userID string) (profile.Relation, bool, *pkg.Error) {
+	if viewerID == "" {
+		return profile.RelationStranger, false, nil
+	}
+
+	if viewerID == userID {
+		return profile.RelationSelf, false, nil
+	}
+
+	blocked, err := s.socialService.IsBlocked(ctx, viewerID, userID)
+	if err != nil {
+		return profile.RelationStranger, false, err
+	}
+
+	if blocked {
+		return profile.RelationStranger, true, nil
+	}
+
+	friends, err := s.socialService.AreFriends(ctx, viewerID, userID)
+	if err != nil {
+		return profile.RelationStranger, false, err
+	}
+
+	if friends {
+		return profile.RelationFriend, false, nil
+	}
+
+	return profile.RelationStranger, false, nil
+}
+
+// checkProfileVisible returns 404 if the user doesn't exist or the profile is hidden from the viewer
+// because one of them blocked the other, the same way GetProfile hides it
+func (s *Server) checkProfileVisible(ctx context.Context, viewerID string, userID string) *pkg.Error {
+	_, blocked, err := s.relationTo(ctx, viewerID, userID)
+	if err != nil {
+		return err
+	}
+
+	if blocked {
+		return &pkg.Error{Code: http.StatusNotFound, Reason: "Profile not found"}
+	}
+
+	exists, err := s.profileService.ProfileExists(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return &pkg.Error{Code: http.StatusNotFound, Reason: "Profile not found"}
+	}
+
+	return nil
+}
+
+// canViewTrip returns true if the trip is visible to the viewer.
+// Participants can always view the trip, otherwise the trip visibility is
+// checked against the viewer's relation to the trip creator.
+func (s *Server) canViewTrip(ctx context.Context, viewerID string, t *trip.Trip) (bool, *pkg.Error) {
+	if viewerID != "" {
+		_, err := s.tripService.CheckPermission(ctx, t.ID, viewerID, trip.PermissionView)
+		if err == nil {
+			return true, nil
+		}
+
+		if err.Code != 403 {
+			return false, err
+		}
+	}
+
+	relation, blocked, err := s.relationTo(ctx, viewerID, t.CreatedBy)
+	if err != nil || blocked {
+		return false, err
+	}
+
+	return tripVisibleTo(t, relation), nil
+}
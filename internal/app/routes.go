@@ -1,16 +1,31 @@
 package app
 
-import "github.com/gin-gonic/gin"
+import (
+	"speakeasy/pkg/filestorage"
+
+	"github.com/gin-gonic/gin"
+)
 
 // Routes Gin function which contains api routes
 func (s *Server) Routes() *gin.Engine {
 	router := s.router
 
+	// request logger with request ID, access log, problem responses of the errors added by handlers,
+	// and the user of the access token
+	router.Use(RequestID(s.logger), AccessLog(), ErrorHandler(), s.Authenticate())
+
+	// files of the local file storage backend, S3 serves them in production
+	if storage := s.config.FileStorage(); storage.UseLocalStorage() {
+		router.Any(filestorage.LOCAL_FILES_PATH+"*filepath", gin.WrapH(filestorage.LocalFileHandler(storage)))
+	}
+
 	// version 1 apis
 	v1 := router.Group("/v1")
 	{
-		// health check endpoint
-		router.GET("/health", s.HealthCheck())
+		// health check endpoints, /health is kept for load balancers which still use it
+		router.GET("/health", s.Readiness())
+		router.GET("/health/live", s.Liveness())
+		router.GET("/health/ready", s.Readiness())
 
 		auth := v1.Group("/auth")
 		{
@@ -23,20 +38,65 @@ func (s *Server) Routes() *gin.Engine {
 		{
 			trip.POST("", s.CreateTrip())
 			trip.GET("/:tripid", s.GetTrip())
+			trip.PUT("/:tripid", s.UpdateTrip())
 			trip.GET("/:tripid/participants", s.GetTripParticipants())
+			trip.PUT("/:tripid/participants/:userid", s.UpdateParticipantRole())
+			trip.DELETE("/:tripid/participants/:userid", s.RemoveTripParticipant())
+			trip.POST("/:tripid/owner", s.TransferTripOwnership())
+			trip.POST("/:tripid/links", s.CreateTripInviteLink())
+			trip.DELETE("/:tripid/links/:linkid", s.RevokeTripInviteLink())
+			trip.POST("/join/:token", s.JoinTrip())
+			trip.GET("/:tripid/photos", s.GetTripPhotos())
+			trip.POST("/:tripid/photos", s.AddTripPhoto())
+			trip.DELETE("/:tripid/photos/:photoid", s.DeleteTripPhoto())
 		}
 
 		user := v1.Group("/trips")
 		{
 			user.GET("/user/:userid", s.GetUserTrips())
 			user.GET("/user/me", s.GetMyTrips())
+			user.GET("/public", s.GetPublicTrips())
 		}
 
+		users := v1.Group("/users")
+		{
+			users.POST("/:userid/follow", s.Follow())
+			users.DELETE("/:userid/follow", s.Unfollow())
+			users.GET("/:userid/followers", s.GetFollowers())
+			users.GET("/:userid/following", s.GetFollowing())
+			users.GET("/:userid/friends", s.GetFriends())
+			users.POST("/:userid/block", s.Block())
+			users.DELETE("/:userid/block", s.Unblock())
+		}
+
+		friends := v1.Group("/friends")
+		{
+			friends.GET("/requests", s.GetFriendRequests())
+			friends.POST("/requests/:userid", s.SendFriendRequest())
+			friends.POST("/requests/:userid/accept", s.AcceptFriendRequest())
+			friends.POST("/requests/:userid/decline", s.DeclineFriendRequest())
+			friends.DELETE("/:userid", s.RemoveFriend())
+		}
+
+		v1.GET("/blocks", s.GetBlockedUsers())
+		v1.GET("/feed", s.GetFeed())
+
 		profile := v1.Group("/profile")
 		{
 			profile.GET("", s.GetMyProfile())
 			profile.POST("", s.CreateProfile())
+			profile.PATCH("", s.PatchProfile())
 			profile.POST("/picture", s.UploadProfilePicture())
+			profile.PUT("/handle", s.SetHandle())
+			profile.GET("/:userid", s.GetProfile())
+		}
+
+		v1.GET("/profiles/search", s.SearchProfiles())
+
+		uploads := v1.Group("/uploads")
+		{
+			uploads.POST("", s.CreateUpload())
+			uploads.POST("/:uploadid/complete", s.CompleteUpload())
 		}
 	}
 
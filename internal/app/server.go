@@ -1,10 +1,21 @@
 package app
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"speakeasy/internal/pkg/album"
 	"speakeasy/internal/pkg/authentication"
+	"speakeasy/internal/pkg/config"
+	"speakeasy/internal/pkg/feed"
 	"speakeasy/internal/pkg/profile"
+	"speakeasy/internal/pkg/social"
 	"speakeasy/internal/pkg/trip"
+	"speakeasy/internal/pkg/upload"
+	"speakeasy/pkg/health"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,34 +23,102 @@ import (
 // Server object which contains router and services
 type Server struct {
 	router                *gin.Engine
+	config                *config.Config
+	logger                *slog.Logger
+	readiness             *health.Registry
 	authenticationService authentication.Service
 	tripService           trip.Service
 	profileService        profile.Service
+	socialService         social.Service
+	feedService           feed.Service
+	uploadService         upload.Service
+	albumService          album.Service
+
+	// draining is set when the server is shutting down, readiness reports unhealthy from then on
+	draining atomic.Bool
 }
 
 // NewServer returns Server object
 func NewServer(
 	router *gin.Engine,
+	cfg *config.Config,
+	logger *slog.Logger,
+	readiness *health.Registry,
 	authenticationService authentication.Service,
 	tripService trip.Service,
 	profileService profile.Service,
+	socialService social.Service,
+	feedService feed.Service,
+	uploadService upload.Service,
+	albumService album.Service,
 ) *Server {
 	return &Server{
 		router:                router,
+		config:                cfg,
+		logger:                logger,
+		readiness:             readiness,
 		authenticationService: authenticationService,
 		tripService:           tripService,
 		profileService:        profileService,
+		socialService:         socialService,
+		feedService:           feedService,
+		uploadService:         uploadService,
+		albumService:          albumService,
 	}
 }
 
-// Run function to run server
-func (s *Server) Run() error {
-	err := s.Routes().Run()
+// Run function to run HTTP server until ctx is done, e.g. on SIGTERM, then the server is shut down gracefully.
+// Readiness reports unhealthy for the shutdown delay while requests are still served,
+// then the listener is closed and in-flight requests are drained until the shutdown timeout.
+func (s *Server) Run(ctx context.Context) error {
+	settings := s.config.Server
+	server := &http.Server{
+		Addr:              settings.Addr,
+		Handler:           s.Routes(),
+		ReadHeaderTimeout: settings.ReadHeaderTimeout,
+		ReadTimeout:       settings.ReadTimeout,
+		WriteTimeout:      settings.WriteTimeout,
+		IdleTimeout:       settings.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(s.logger.Handler(), slog.LevelWarn),
+	}
+
+	listenErr := make(chan error, 1)
+	go func() {
+		s.logger.Info("server started", "addr", settings.Addr)
+		listenErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-listenErr:
+		s.logger.Error("server stopped", "error", err)
+		return err
+	case <-ctx.Done():
+	}
+
+	s.draining.Store(true)
+	s.logger.Info("server draining", "delay", settings.ShutdownDelay.String(), "timeout", settings.ShutdownTimeout.String())
+
+	select {
+	case err := <-listenErr:
+		s.logger.Error("server stopped", "error", err)
+		return err
+	case <-time.After(settings.ShutdownDelay):
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), settings.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		// Drain deadline passed, the remaining connections are closed
+		s.logger.Error("server shutdown failed", "error", err)
+		server.Close()
+		return err
+	}
 
-	if err != nil {
-		log.Printf("Server - there was an error calling Run on router: %v", err)
+	if err := <-listenErr; !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
 
+	s.logger.Info("server stopped")
 	return nil
 }
@@ -0,0 +1,62 @@
+package album
+
+var PHOTO_PK string = "TRIP#%s"
+var PHOTO_SK string = "PHOTO#%s"
+var PHOTO_SK_PREFIX string = "PHOTO#"
+
+// Storage keys of photo files: trip id and photo id
+var PHOTO_KEY string = "trips/%s/photos/%s.jpg"
+var THUMBNAIL_KEY string = "trips/%s/photos/%s_thumb.jpg"
+
+// Photo object to store in database.
+// PK should be in the format of PHOTO_PK and SK in the format of PHOTO_SK.
+// Photo ids start with the upload time, so photos of a trip are sorted by upload time.
+// Width and Height are the dimensions of the uploaded image, TakenAt is read from EXIF data.
+type Photo struct {
+	PK           string `json:"-" dynamodbav:"PK"`
+	SK           string `json:"-" dynamodbav:"SK"`
+	ID           string `json:"id"`
+	TripID       string `json:"trip_id"`
+	UploaderID   string `json:"uploader_id"`
+	Caption      string `json:"caption,omitempty"`
+	TakenAt      string `json:"taken_at,omitempty"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	CreatedAt    string `json:"created_at"`
+	Key          string `json:"-" dynamodbav:"key"`
+	ThumbnailKey string `json:"-" dynamodbav:"thumbnail_key"`
+
+	// Short-lived urls are signed when photos are read
+	Url          string `json:"url" dynamodbav:"-"`
+	ThumbnailUrl string `json:"thumbnail_url" dynamodbav:"-"`
+}
+
+// PhotoResponse object which contains the photo fields sent to clients
+type PhotoResponse struct {
+	ID           string `json:"id"`
+	TripID       string `json:"trip_id"`
+	UploaderID   string `json:"uploader_id"`
+	Caption      string `json:"caption,omitempty"`
+	TakenAt      string `json:"taken_at,omitempty"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	CreatedAt    string `json:"created_at"`
+	Url          string `json:"url"`
+	ThumbnailUrl string `json:"thumbnail_url"`
+}
+
+// Response returns photo as sent to clients
+func (photo *Photo) Response() PhotoResponse {
+	return PhotoResponse{
+		ID:           photo.ID,
+		TripID:       photo.TripID,
+		UploaderID:   photo.UploaderID,
+		Caption:      photo.Caption,
+		TakenAt:      photo.TakenAt,
+		Width:        photo.Width,
+		Height:       photo.Height,
+		CreatedAt:    photo.CreatedAt,
+		Url:          photo.Url,
+		ThumbnailUrl: photo.ThumbnailUrl,
+	}
+}
@@ -0,0 +1,262 @@
+package album
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"speakeasy/internal/pkg/social"
+	"speakeasy/internal/pkg/trip"
+	"speakeasy/pkg"
+	"speakeasy/pkg/clock"
+	"speakeasy/pkg/database"
+	"speakeasy/pkg/filestorage"
+	"speakeasy/pkg/ids"
+	"speakeasy/pkg/imaging"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxPhotoSize is the maximum size of an uploaded photo in bytes
+	MaxPhotoSize = 20 << 20
+
+	maxPhotoDimension = 2048
+	thumbnailSize     = 320
+	maxCaptionLength  = 500
+
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+
+	photoIDLayout = "20060102T150405.000000Z"
+	takenAtLayout = "2006-01-02T15:04:05"
+)
+
+type _Service struct {
+	db          database.Service[Photo]
+	storage     filestorage.Service
+	tripService trip.Service
+	clock       clock.Clock
+	ids         ids.Generator
+	logger      *slog.Logger
+}
+
+// NewAlbumService returns Service object, trip service is used to check access to the trip
+func NewAlbumService(
+	db database.Service[Photo],
+	storage filestorage.Service,
+	tripService trip.Service,
+	clock clock.Clock,
+	ids ids.Generator,
+	logger *slog.Logger,
+) Service {
+	return &_Service{db, storage, tripService, clock, ids, logger}
+}
+
+// Service interface which contains trip photo album operations, only trip participants can use them
+type Service interface {
+	AddPhoto(ctx context.Context, userID string, tripID string, file io.Reader, caption string) (*Photo, *pkg.Error)
+	GetPhotos(ctx context.Context, userID string, tripID string, page *social.PageRequest) (*database.Page[Photo], *pkg.Error)
+	DeletePhoto(ctx context.Context, userID string, tripID string, photoID string) *pkg.Error
+}
+
+// AddPhoto function to add photo to the trip album. The photo is stored as JPEG scaled down to
+// maxPhotoDimension with a thumbnail, re-encoding the image strips EXIF data such as location.
+func (service *_Service) AddPhoto(ctx context.Context, userID string, tripID string, file io.Reader, caption string) (*Photo, *pkg.Error) {
+	caption = strings.TrimSpace(caption)
+	if len(caption) > maxCaptionLength {
+		return nil, &pkg.Error{Code: 400, Reason: fmt.Sprintf("Caption can't be longer than %d characters", maxCaptionLength)}
+	}
+
+	if _, err := service.tripService.CheckPermission(ctx, tripID, userID, trip.PermissionView); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, MaxPhotoSize+1))
+	if err != nil {
+		return nil, &pkg.Error{Code: 400, Reason: "Photo can't be read", Err: err}
+	}
+
+	if len(data) > MaxPhotoSize {
+		return nil, &pkg.Error{Code: 413, Reason: "Photo is too large"}
+	}
+
+	img, metadata, err := imaging.Decode(data)
+	if errors.Is(err, imaging.ErrUnsupportedFormat) {
+		return nil, &pkg.Error{Code: 415, Reason: "Photo must be a JPEG, PNG or WebP image"}
+	}
+
+	if errors.Is(err, imaging.ErrTooManyPixels) {
+		return nil, &pkg.Error{Code: 413, Reason: "Photo dimensions are too large"}
+	}
+
+	if err != nil {
+		return nil, &pkg.Error{Code: 400, Reason: "Photo can't be decoded", Err: err}
+	}
+
+	now := service.clock.Now().UTC()
+	id := fmt.Sprintf("%s-%s", now.Format(photoIDLayout), service.ids.NewID()[:8])
+
+	photo := &Photo{
+		PK:           fmt.Sprintf(PHOTO_PK, tripID),
+		SK:           fmt.Sprintf(PHOTO_SK, id),
+		ID:           id,
+		TripID:       tripID,
+		UploaderID:   userID,
+		Caption:      caption,
+		Width:        metadata.Width,
+		Height:       metadata.Height,
+		CreatedAt:    now.Format(time.RFC3339),
+		Key:          fmt.Sprintf(PHOTO_KEY, tripID, id),
+		ThumbnailKey: fmt.Sprintf(THUMBNAIL_KEY, tripID, id),
+	}
+
+	// EXIF dates have no time zone, they are the local time where the photo was taken
+	if metadata.Exif.TakenAt != nil {
+		photo.TakenAt = metadata.Exif.TakenAt.Format(takenAtLayout)
+	}
+
+	files := map[string]*bytes.Reader{}
+	for key, resized := range map[string]int{photo.Key: maxPhotoDimension, photo.ThumbnailKey: thumbnailSize} {
+		encoded, err := imaging.EncodeJPEG(imaging.Fit(img, resized))
+		if err != nil {
+			return nil, pkg.Internal("AddPhoto", err)
+		}
+		files[key] = bytes.NewReader(encoded)
+	}
+
+	// Files of a photo which isn't added to the album are deleted, like DeletePhoto does
+	for key, body := range files {
+		if err := service.storage.UploadFile(ctx, key, body, imaging.ContentTypeJPEG); err != nil {
+			service.deleteFiles(ctx, photo)
+			return nil, pkg.Unavailable("AddPhoto", err)
+		}
+	}
+
+	if err := service.db.Write(ctx, photo); err != nil {
+		service.deleteFiles(ctx, photo)
+		return nil, pkg.Unavailable("AddPhoto", err)
+	}
+
+	if err := service.setUrls(photo); err != nil {
+		return nil, pkg.Unavailable("AddPhoto", err)
+	}
+
+	return photo, nil
+}
+
+// GetPhotos function to list photos of the trip album, newest first
+func (service *_Service) GetPhotos(ctx context.Context, userID string, tripID string, page *social.PageRequest) (*database.Page[Photo], *pkg.Error) {
+	if page.Limit < 0 || page.Limit > maxPageLimit {
+		return nil, &pkg.Error{Code: 400, Reason: "Limit is invalid"}
+	}
+
+	if _, err := service.tripService.CheckPermission(ctx, tripID, userID, trip.PermissionView); err != nil {
+		return nil, err
+	}
+
+	options := &database.QueryOptions{
+		Limit:      page.Limit,
+		Cursor:     page.Cursor,
+		Descending: true,
+	}
+
+	if options.Limit == 0 {
+		options.Limit = defaultPageLimit
+	}
+
+	filter := map[string]string{
+		":PK": fmt.Sprintf(PHOTO_PK, tripID),
+		":SK": PHOTO_SK_PREFIX,
+	}
+
+	result, err := service.db.QueryPage(ctx, filter, "PK = :PK And begins_with(SK, :SK)", options)
+	if errors.Is(err, database.ErrInvalidCursor) {
+		return nil, &pkg.Error{Code: 400, Reason: "Cursor is invalid"}
+	}
+
+	if err != nil {
+		return nil, pkg.Unavailable("GetPhotos", err)
+	}
+
+	for i := range result.Items {
+		if err := service.setUrls(&result.Items[i]); err != nil {
+			return nil, pkg.Unavailable("GetPhotos", err)
+		}
+	}
+
+	return result, nil
+}
+
+// DeletePhoto function to delete photo and its files, only the uploader and the trip owner can delete it
+func (service *_Service) DeletePhoto(ctx context.Context, userID string, tripID string, photoID string) *pkg.Error {
+	participant, err := service.tripService.CheckPermission(ctx, tripID, userID, trip.PermissionView)
+	if err != nil {
+		return err
+	}
+
+	photo, err := service.getPhoto(ctx, tripID, photoID)
+	if err != nil {
+		return err
+	}
+
+	if photo.UploaderID != userID && !participant.Role.Can(trip.PermissionManage) {
+		return &pkg.Error{Code: 403, Reason: "Forbidden"}
+	}
+
+	if err := service.db.Delete(ctx, map[string]string{"PK": photo.PK, "SK": photo.SK}); err != nil {
+		return pkg.Unavailable("DeletePhoto", err)
+	}
+
+	service.deleteFiles(ctx, photo)
+
+	return nil
+}
+
+// deleteFiles deletes the photo and thumbnail files of a photo which is not in the album,
+// files which can't be deleted are only logged
+func (service *_Service) deleteFiles(ctx context.Context, photo *Photo) {
+	for _, key := range []string{photo.Key, photo.ThumbnailKey} {
+		if err := service.storage.DeleteFile(ctx, key); err != nil {
+			service.logger.Warn("delete photo file failed", "trip_id", photo.TripID, "photo_id", photo.ID, "error", err.Error())
+		}
+	}
+}
+
+// getPhoto returns photo of the trip by id
+func (service *_Service) getPhoto(ctx context.Context, tripID string, photoID string) (*Photo, *pkg.Error) {
+	input := map[string]string{
+		"PK": fmt.Sprintf(PHOTO_PK, tripID),
+		"SK": fmt.Sprintf(PHOTO_SK, photoID),
+	}
+
+	photo, err := service.db.Get(ctx, input)
+	if err != nil {
+		return nil, pkg.Unavailable("getPhoto", err)
+	}
+
+	if photo == nil {
+		return nil, &pkg.Error{Code: 404, Reason: "Photo not found"}
+	}
+
+	return photo, nil
+}
+
+// setUrls sets short-lived urls of the photo files
+func (service *_Service) setUrls(photo *Photo) error {
+	url, err := service.storage.GetDownloadUrl(photo.Key)
+	if err != nil {
+		return err
+	}
+
+	thumbnailUrl, err := service.storage.GetDownloadUrl(photo.ThumbnailKey)
+	if err != nil {
+		return err
+	}
+
+	photo.Url = url
+	photo.ThumbnailUrl = thumbnailUrl
+	return nil
+}
@@ -0,0 +1,309 @@
+package album
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"speakeasy/internal/pkg/social"
+	"speakeasy/internal/pkg/trip"
+	"speakeasy/pkg"
+	"speakeasy/pkg/clock"
+	"speakeasy/pkg/database"
+	"speakeasy/pkg/filestorage"
+	"speakeasy/pkg/ids"
+	"speakeasy/pkg/logging"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testLogger drops the log records of the services under test
+var testLogger = logging.Discard()
+
+// testClock and testIDs are the real clock and uuid generator, tests which check times or ids set fixed ones
+var (
+	testClock = clock.System()
+	testIDs   = ids.UUID()
+)
+
+// Mock DatabaseService which keeps photos in memory
+type _DatabaseServiceMockInMemory struct {
+	database.Service[Photo]
+	items   map[string]Photo
+	options *database.QueryOptions
+}
+
+func newInMemoryDatabase() *_DatabaseServiceMockInMemory {
+	return &_DatabaseServiceMockInMemory{items: map[string]Photo{}}
+}
+
+func (db *_DatabaseServiceMockInMemory) Get(ctx context.Context, keyObj interface{}) (*Photo, error) {
+	key := keyObj.(map[string]string)
+	item, ok := db.items[key["PK"]+"|"+key["SK"]]
+	if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+func (db *_DatabaseServiceMockInMemory) Write(ctx context.Context, obj ...*Photo) error {
+	for _, item := range obj {
+		db.items[item.PK+"|"+item.SK] = *item
+	}
+	return nil
+}
+
+func (db *_DatabaseServiceMockInMemory) Delete(ctx context.Context, keyObj interface{}) error {
+	key := keyObj.(map[string]string)
+	delete(db.items, key["PK"]+"|"+key["SK"])
+	return nil
+}
+
+func (db *_DatabaseServiceMockInMemory) QueryPage(ctx context.Context, filterObj interface{}, condition string, options *database.QueryOptions) (*database.Page[Photo], error) {
+	filter := filterObj.(map[string]string)
+	db.options = options
+
+	page := &database.Page[Photo]{Items: []Photo{}}
+	for _, item := range db.items {
+		if item.PK == filter[":PK"] && strings.HasPrefix(item.SK, filter[":SK"]) {
+			page.Items = append(page.Items, item)
+		}
+	}
+	return page, nil
+}
+
+// Mock DatabaseService where every operation returns an error
+type _DatabaseServiceMockError struct {
+	database.Service[Photo]
+}
+
+func (db *_DatabaseServiceMockError) Write(ctx context.Context, obj ...*Photo) error {
+	return errors.New("ERROR")
+}
+
+func (db *_DatabaseServiceMockError) QueryPage(ctx context.Context, filterObj interface{}, condition string, options *database.QueryOptions) (*database.Page[Photo], error) {
+	return nil, errors.New("ERROR")
+}
+
+// Mock StorageService which keeps uploaded files in memory
+type _StorageServiceMock struct {
+	filestorage.Service
+	files map[string][]byte
+}
+
+func newStorage() *_StorageServiceMock {
+	return &_StorageServiceMock{files: map[string][]byte{}}
+}
+
+func (storage *_StorageServiceMock) UploadFile(ctx context.Context, filename string, body io.ReadSeeker, contentType string) error {
+	data, _ := io.ReadAll(body)
+	storage.files[filename] = data
+	return nil
+}
+
+func (storage *_StorageServiceMock) DeleteFile(ctx context.Context, filename string) error {
+	delete(storage.files, filename)
+	return nil
+}
+
+func (storage *_StorageServiceMock) GetDownloadUrl(filename string) (string, error) {
+	return "https://storage/" + filename + "?signature=test", nil
+}
+
+// Mock TripService where users have the roles of the map
+type _TripServiceMock struct {
+	trip.Service
+	roles map[string]trip.Role
+}
+
+func newTripService() *_TripServiceMock {
+	return &_TripServiceMock{roles: map[string]trip.Role{
+		"owner":  trip.RoleOwner,
+		"editor": trip.RoleEditor,
+		"viewer": trip.RoleViewer,
+	}}
+}
+
+func (tripService *_TripServiceMock) CheckPermission(ctx context.Context, tripID string, userID string, permission trip.Permission) (*trip.Trip, *pkg.Error) {
+	role, ok := tripService.roles[userID]
+	if !ok || !role.Can(permission) {
+		return nil, &pkg.Error{Code: 403, Reason: "Forbidden"}
+	}
+	return &trip.Trip{ID: tripID, ParticipantID: userID, Role: role}, nil
+}
+
+func encodePNG(width int, height int) io.Reader {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)))
+	return &buf
+}
+
+// encodePNGHeader returns a 1x1 PNG whose header declares width x height like a decompression bomb
+func encodePNGHeader(width uint32, height uint32) io.Reader {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1)))
+	data := buf.Bytes()
+
+	// IHDR chunk follows the 8 byte signature, its checksum covers the type and data
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return bytes.NewReader(data)
+}
+
+func TestAddPhoto(t *testing.T) {
+	t.Run("SUCCESS: STORE PHOTO AND THUMBNAIL", func(t *testing.T) {
+		db := newInMemoryDatabase()
+		storage := newStorage()
+		svc := &_Service{db: db, storage: storage, tripService: newTripService(), clock: testClock, ids: testIDs, logger: testLogger}
+
+		photo, err := svc.AddPhoto(context.Background(), "viewer", "trip", encodePNG(3000, 1500), " Beach ")
+
+		assert.Empty(t, err, "Error should be empty")
+		assert.Equal(t, "Beach", photo.Caption)
+		assert.Equal(t, 3000, photo.Width)
+		assert.Equal(t, 1500, photo.Height)
+		assert.Equal(t, "viewer", photo.UploaderID)
+		assert.Equal(t, "https://storage/trips/trip/photos/"+photo.ID+".jpg?signature=test", photo.Url)
+		assert.Len(t, db.items, 1)
+
+		full, _, _ := image.DecodeConfig(bytes.NewReader(storage.files[photo.Key]))
+		assert.Equal(t, maxPhotoDimension, full.Width, "Photo should be scaled down")
+		thumbnail, _, _ := image.DecodeConfig(bytes.NewReader(storage.files[photo.ThumbnailKey]))
+		assert.Equal(t, thumbnailSize, thumbnail.Width)
+		assert.Equal(t, thumbnailSize/2, thumbnail.Height, "Thumbnail should keep aspect ratio")
+	})
+
+	t.Run("ERROR: RETURN 403 WHEN USER IS NOT A PARTICIPANT", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), storage: newStorage(), tripService: newTripService(), clock: testClock, ids: testIDs, logger: testLogger}
+
+		photo, err := svc.AddPhoto(context.Background(), "stranger", "trip", encodePNG(10, 10), "")
+
+		assert.Equal(t, 403, err.Code, "Error should be 403")
+		assert.Empty(t, photo, "Result should be empty")
+	})
+
+	t.Run("ERROR: RETURN 415 WHEN FILE IS NOT AN IMAGE", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), storage: newStorage(), tripService: newTripService(), clock: testClock, ids: testIDs, logger: testLogger}
+
+		photo, err := svc.AddPhoto(context.Background(), "viewer", "trip", strings.NewReader("not an image"), "")
+
+		assert.Equal(t, 415, err.Code, "Error should be 415")
+		assert.Empty(t, photo, "Result should be empty")
+	})
+
+	t.Run("ERROR: RETURN 413 WHEN IMAGE HAS TOO MANY PIXELS", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), storage: newStorage(), tripService: newTripService(), clock: testClock, ids: testIDs, logger: testLogger}
+
+		photo, err := svc.AddPhoto(context.Background(), "viewer", "trip", encodePNGHeader(50000, 50000), "")
+
+		assert.Equal(t, 413, err.Code, "Error should be 413")
+		assert.Empty(t, photo, "Result should be empty")
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN CAPTION IS TOO LONG", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), storage: newStorage(), tripService: newTripService(), clock: testClock, ids: testIDs, logger: testLogger}
+
+		photo, err := svc.AddPhoto(context.Background(), "viewer", "trip", encodePNG(10, 10), strings.Repeat("a", maxCaptionLength+1))
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+		assert.Empty(t, photo, "Result should be empty")
+	})
+
+	t.Run("ERROR: RETURN 503 AND DELETE FILES WHEN DB WRITE RETURNS ERROR", func(t *testing.T) {
+		storage := newStorage()
+		svc := &_Service{db: &_DatabaseServiceMockError{}, storage: storage, tripService: newTripService(), clock: testClock, ids: testIDs, logger: testLogger}
+
+		photo, err := svc.AddPhoto(context.Background(), "viewer", "trip", encodePNG(10, 10), "")
+
+		assert.Equal(t, 503, err.Code, "Error should be 503")
+		assert.Empty(t, photo, "Result should be empty")
+		assert.Empty(t, storage.files, "Uploaded files should be deleted")
+	})
+}
+
+func TestGetPhotos(t *testing.T) {
+	t.Run("SUCCESS: LIST PHOTOS NEWEST FIRST WITH URLS", func(t *testing.T) {
+		db := newInMemoryDatabase()
+		svc := &_Service{db: db, storage: newStorage(), tripService: newTripService(), clock: testClock, ids: testIDs, logger: testLogger}
+		svc.AddPhoto(context.Background(), "viewer", "trip", encodePNG(10, 10), "")
+		svc.AddPhoto(context.Background(), "viewer", "other", encodePNG(10, 10), "")
+
+		result, err := svc.GetPhotos(context.Background(), "owner", "trip", &social.PageRequest{})
+
+		assert.Empty(t, err, "Error should be empty")
+		assert.Len(t, result.Items, 1)
+		assert.NotEmpty(t, result.Items[0].ThumbnailUrl)
+		assert.True(t, db.options.Descending, "Newest photos should be first")
+		assert.Equal(t, int64(defaultPageLimit), db.options.Limit)
+	})
+
+	t.Run("ERROR: RETURN 403 WHEN USER IS NOT A PARTICIPANT", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), storage: newStorage(), tripService: newTripService(), clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.GetPhotos(context.Background(), "stranger", "trip", &social.PageRequest{})
+
+		assert.Equal(t, 403, err.Code, "Error should be 403")
+		assert.Empty(t, result, "Result should be empty")
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN LIMIT IS INVALID", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), storage: newStorage(), tripService: newTripService(), clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.GetPhotos(context.Background(), "owner", "trip", &social.PageRequest{Limit: 1000})
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+		assert.Empty(t, result, "Result should be empty")
+	})
+
+	t.Run("ERROR: RETURN 503 WHEN DB QUERY RETURNS ERROR", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockError{}, storage: newStorage(), tripService: newTripService(), clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.GetPhotos(context.Background(), "owner", "trip", &social.PageRequest{})
+
+		assert.Equal(t, 503, err.Code, "Error should be 503")
+		assert.Empty(t, result, "Result should be empty")
+	})
+}
+
+func TestDeletePhoto(t *testing.T) {
+	for _, userID := range []string{"viewer", "owner"} {
+		t.Run("SUCCESS: "+strings.ToUpper(userID)+" DELETES PHOTO AND FILES", func(t *testing.T) {
+			db := newInMemoryDatabase()
+			storage := newStorage()
+			svc := &_Service{db: db, storage: storage, tripService: newTripService(), clock: testClock, ids: testIDs, logger: testLogger}
+			photo, _ := svc.AddPhoto(context.Background(), "viewer", "trip", encodePNG(10, 10), "")
+
+			err := svc.DeletePhoto(context.Background(), userID, "trip", photo.ID)
+
+			assert.Empty(t, err, "Error should be empty")
+			assert.Empty(t, db.items, "Photo should be deleted")
+			assert.Empty(t, storage.files, "Files should be deleted")
+		})
+	}
+
+	t.Run("ERROR: RETURN 403 WHEN EDITOR DELETES OTHER USER'S PHOTO", func(t *testing.T) {
+		db := newInMemoryDatabase()
+		svc := &_Service{db: db, storage: newStorage(), tripService: newTripService(), clock: testClock, ids: testIDs, logger: testLogger}
+		photo, _ := svc.AddPhoto(context.Background(), "viewer", "trip", encodePNG(10, 10), "")
+
+		err := svc.DeletePhoto(context.Background(), "editor", "trip", photo.ID)
+
+		assert.Equal(t, 403, err.Code, "Error should be 403")
+		assert.Len(t, db.items, 1, "Photo should not be deleted")
+	})
+
+	t.Run("ERROR: RETURN 404 WHEN PHOTO DOES NOT EXIST", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), storage: newStorage(), tripService: newTripService(), clock: testClock, ids: testIDs, logger: testLogger}
+
+		err := svc.DeletePhoto(context.Background(), "owner", "trip", "photo")
+
+		assert.Equal(t, 404, err.Code, "Error should be 404")
+	})
+}
@@ -2,8 +2,8 @@ package authentication
 
 // LoginRequest object which is the request for Login function
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" binding:"required,email,max=254"`
+	Password string `json:"password" binding:"required,max=72"`
 }
 
 // Token object used to store access and refresh token
@@ -19,11 +19,11 @@ type LoginResponse struct {
 
 // SignupRequest object which is the request for Signup function
 type SignupRequest struct {
-	Email     string `json:"email"`
-	Password  string `json:"password"`
-	Name      string `json:"name"`
-	Phone     string `json:"phone"`
-	BirthDate string `json:"birth_date"`
+	Email     string `json:"email" binding:"required,email,max=254"`
+	Password  string `json:"password" binding:"required,password"`
+	Name      string `json:"name" binding:"required,notblank,max=100"`
+	Phone     string `json:"phone" binding:"omitempty,phone"`
+	BirthDate string `json:"birth_date" binding:"omitempty,date"`
 }
 
 // SignupReponse object which is the response for Signup function
@@ -46,5 +46,5 @@ type Authentication struct {
 }
 
 type RefreshRequest struct {
-	RefreshToken string `json:"refresh_token"`
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
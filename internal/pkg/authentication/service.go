@@ -1,88 +1,93 @@
 package authentication
 
 import (
-	"fmt"
-	"log"
-	"net/http"
-	"os"
+	"context"
+	"errors"
+	"log/slog"
 	"speakeasy/pkg"
+	"speakeasy/pkg/clock"
 	"speakeasy/pkg/database"
-
-	"strings"
-	"time"
+	"speakeasy/pkg/ids"
 
 	"github.com/golang-jwt/jwt"
-	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type _Service struct {
-	db database.Service[Authentication]
+	db     database.Service[Authentication]
+	tokens TokenIssuer
+	clock  clock.Clock
+	ids    ids.Generator
+	logger *slog.Logger
 }
 
-// NewAuthenticationService returns _AuthenticationService object
-func NewAuthenticationService() Service {
-	db := database.NewDatabaseService[Authentication]("AUTHENTICATION")
-
-	return &_Service{db}
+// NewAuthenticationService returns _AuthenticationService object which stores accounts in db and signs in users with tokens
+func NewAuthenticationService(db database.Service[Authentication], tokens TokenIssuer, clock clock.Clock, ids ids.Generator, logger *slog.Logger) Service {
+	return &_Service{db, tokens, clock, ids, logger}
 }
 
 // Service interface which contains authentication operations
 type Service interface {
-	Login(request LoginRequest) (*LoginResponse, *pkg.Error)
-	Signup(request SignupRequest) (*SignupReponse, *pkg.Error)
-	Refresh(request *RefreshRequest) (*Token, error)
+	Login(ctx context.Context, request LoginRequest) (*LoginResponse, *pkg.Error)
+	Signup(ctx context.Context, request SignupRequest) (*SignupReponse, *pkg.Error)
+	Refresh(ctx context.Context, request *RefreshRequest) (*Token, error)
+	VerifyAccessToken(tokenString string) (jwt.MapClaims, error)
 }
 
 // Login function to get access token
-func (service *_Service) Login(request LoginRequest) (*LoginResponse, *pkg.Error) {
+func (service *_Service) Login(ctx context.Context, request LoginRequest) (*LoginResponse, *pkg.Error) {
 	input := map[string]string{
 		"PK": request.Email,
 	}
 
-	result, err := service.db.Get(input)
+	result, err := service.db.Get(ctx, input)
 
 	if err != nil {
-		log.Println("LoginError: ", err)
-		return nil, &pkg.Error{Code: 503, Reason: "Internal Server Error"}
+		return nil, pkg.Unavailable("Login", err)
 	}
 
 	if result == nil {
-		log.Println("LoginError: Item does not exist")
+		service.logger.Info("login failed", "reason", "account not found")
 		return nil, &pkg.Error{Code: 401, Reason: "Invalid email or password, please try again"}
 	}
 
 	invalid := bcrypt.CompareHashAndPassword([]byte(result.Password), []byte(request.Password))
 	if invalid != nil {
-		log.Println("LoginError: ", invalid)
+		service.logger.Info("login failed", "reason", "wrong password")
 		return nil, &pkg.Error{Code: 401, Reason: "Invalid email or password, please try again"}
 	}
 
 	// create jwt token logic
-	token, createTokenError := CreateToken(result.ID)
+	token, createTokenError := service.tokens.CreateToken(result.ID)
 	if createTokenError != nil {
-		log.Println("LoginError: error occurred when generating access token", createTokenError)
-		return nil, &pkg.Error{Code: 500, Reason: "Internal Server pkg.Error"}
+		return nil, pkg.Internal("Login: create token", createTokenError)
 	}
 
 	return &LoginResponse{*token}, nil
 }
 
 // Signup function to create an account
-func (service *_Service) Signup(request SignupRequest) (*SignupReponse, *pkg.Error) {
+func (service *_Service) Signup(ctx context.Context, request SignupRequest) (*SignupReponse, *pkg.Error) {
+	// Validate optional fields before creating the account, they are also stored on the profile
+	validator := &pkg.Validator{}
+	validator.Phone("phone", request.Phone)
+	validator.BirthDate("birth_date", request.BirthDate, service.clock.Now().UTC())
+	if err := validator.Error(); err != nil {
+		return nil, err
+	}
+
 	// get dynamodb item with user credentials
 	input := map[string]string{
 		"PK": request.Email,
 	}
 
-	result, err := service.db.Get(input)
+	result, err := service.db.Get(ctx, input)
 	if err != nil {
-		log.Println("SignupError:", err)
-		return nil, &pkg.Error{Code: 503, Reason: "Internal Server Error"}
+		return nil, pkg.Unavailable("Signup", err)
 	}
 
 	if result != nil {
-		log.Println("SignupError: Account already exists")
+		service.logger.Info("signup failed", "reason", "account exists")
 		return nil, &pkg.Error{Code: 400, Reason: "Account already exists"}
 	}
 
@@ -90,151 +95,53 @@ func (service *_Service) Signup(request SignupRequest) (*SignupReponse, *pkg.Err
 	password := []byte(request.Password)
 	hashed, hashErr := bcrypt.GenerateFromPassword(password, bcrypt.DefaultCost)
 	if hashErr != nil {
-		log.Println("SignupError: Unable to generate hash from password", hashErr)
-		return nil, &pkg.Error{Code: 500, Reason: "Internal Server pkg.Error"}
+		return nil, pkg.Internal("Signup: hash password", hashErr)
 	}
 
 	// Create account if no issues exists
 	account := Authentication{
-		PK:       request.Email,
-		ID:       uuid.New().String(),
-		Email:    request.Email,
-		Password: string(hashed),
-		Name:     request.Name,
-		Phone:    request.Phone,
+		PK:        request.Email,
+		ID:        service.ids.NewID(),
+		Email:     request.Email,
+		Password:  string(hashed),
+		Name:      request.Name,
+		Phone:     request.Phone,
+		BirthDate: request.BirthDate,
 	}
 
 	// Save item to database
-	err = service.db.Write(&account)
+	err = service.db.Write(ctx, &account)
 	if err != nil {
-		log.Printf("SignupError: %s", err)
-		return nil, &pkg.Error{Code: 503, Reason: "Internal Server Error"}
+		return nil, pkg.Unavailable("Signup", err)
 	}
 
 	return &SignupReponse{Status: true, UserID: account.ID}, nil
 }
 
 // Refresh function to refresh access token
-func (service *_Service) Refresh(request *RefreshRequest) (*Token, error) {
-	refreshToken, err := VerifyTokenString(request.RefreshToken)
+func (service *_Service) Refresh(ctx context.Context, request *RefreshRequest) (*Token, error) {
+	claims, err := service.tokens.VerifyRefreshToken(request.RefreshToken)
 	if err != nil {
-		fmt.Println("ERR! Refresh token cannot be verified.")
-		return nil, err
-	}
-
-	//Since token is valid, get the uuid:
-	claims, ok := refreshToken.Claims.(jwt.MapClaims) //the token claims should conform to MapClaims
-	if !ok || !refreshToken.Valid {
-		fmt.Println("ERR! Refresh token cannot be verified.")
+		service.logger.Info("refresh token cannot be verified", "error", err.Error())
 		return nil, err
 	}
 
 	userID, ok := claims["user_id"].(string)
 	if !ok {
-		fmt.Println("ERR! Refresh token cannot be verified.")
-		return nil, err
-	}
-
-	token, err := CreateToken(userID)
-	if err != nil {
-		fmt.Println("ERR! Refresh token cannot be verified.")
-		return nil, err
-	}
-
-	return token, nil
-}
-
-// CreateToken function to create jwt
-func CreateToken(userID string) (*Token, error) {
-	token := Token{}
-
-	// Set generic claims for both access and refresh tokens
-	claims := jwt.MapClaims{}
-	claims["authorized"] = true
-	claims["user_id"] = userID
-
-	// Set claims and retrieve access token
-	claims["id"] = uuid.New().String()
-	claims["exp"] = time.Now().Add(time.Minute * 15).Unix()
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := accessToken.SignedString([]byte(os.Getenv("JWT_ACCESS_SECRET")))
-	if err != nil {
-		return nil, err
-	}
-	token.AccessToken = signed
-
-	// Set claims and retrieve refresh token
-	claims["id"] = uuid.New().String()
-	claims["exp"] = time.Now().Add(time.Hour * 24 * 7).Unix()
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err = refreshToken.SignedString([]byte(os.Getenv("JWT_REFRESH_SECRET")))
-	if err != nil {
-		return nil, err
-	}
-	token.RefreshToken = signed
-
-	return &token, nil
-}
-
-// ExtractToken function to extract jwt from http request
-func ExtractToken(r *http.Request) string {
-	bearToken := r.Header.Get("Authorization")
-	//normally Authorization the_token_xxx
-	strArr := strings.Split(bearToken, " ")
-	if len(strArr) == 2 {
-		return strArr[1]
+		service.logger.Info("refresh token cannot be verified", "error", "missing user id")
+		return nil, errors.New("refresh token has no user id")
 	}
-	return ""
-}
-
-// VerifyTokenString function to verify jwt from http request
-func VerifyTokenString(tokenString string) (*jwt.Token, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(os.Getenv("JWT_ACCESS_SECRET")), nil
-	})
 
+	token, err := service.tokens.CreateToken(userID)
 	if err != nil {
+		service.logger.Error("create token failed", "error", err.Error())
 		return nil, err
 	}
 
 	return token, nil
 }
 
-// VerifyToken function to verify jwt from http request
-func VerifyToken(r *http.Request) (*jwt.Token, error) {
-	tokenString := ExtractToken(r)
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(os.Getenv("JWT_ACCESS_SECRET")), nil
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	return token, nil
-}
-
-func GetTokenClaims(r *http.Request) (*jwt.MapClaims, error) {
-	tokenString := ExtractToken(r)
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(os.Getenv("JWT_ACCESS_SECRET")), nil
-	})
-
-	if err != nil {
-		log.Println("GetTokenClaims: ", err)
-		return nil, err
-	}
-
-	claims := token.Claims.(jwt.MapClaims)
-
-	return &claims, nil
+// VerifyAccessToken function to verify access jwt, returns the claims of the token
+func (service *_Service) VerifyAccessToken(tokenString string) (jwt.MapClaims, error) {
+	return service.tokens.VerifyAccessToken(tokenString)
 }
@@ -1,25 +1,43 @@
 package authentication
 
 import (
+	"context"
 	"errors"
+	"speakeasy/internal/pkg/config"
+	"speakeasy/pkg/clock"
 	"speakeasy/pkg/database"
+	"speakeasy/pkg/ids"
+	"speakeasy/pkg/logging"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// testLogger drops the log records of the services under test
+var testLogger = logging.Discard()
+
+// testClock and testIDs are the real clock and uuid generator, tests which check times or ids set fixed ones
+var (
+	testClock = clock.System()
+	testIDs   = ids.UUID()
+)
+
+// testTokens signs and verifies tokens with test secrets
+var testTokens = NewTokenIssuer(config.JWT{AccessSecret: "access", RefreshSecret: "refresh"}, testClock, testIDs)
+
 // Mock DatabaseService where item exists
 type _DatabaseServiceMockItemExists struct {
 	database.Service[Authentication]
 }
 
-func (db *_DatabaseServiceMockItemExists) Get(keyObj interface{}) (*Authentication, error) {
+func (db *_DatabaseServiceMockItemExists) Get(ctx context.Context, keyObj interface{}) (*Authentication, error) {
 	hash, _ := bcrypt.GenerateFromPassword([]byte("correct.password"), bcrypt.DefaultCost)
 	return &Authentication{Password: string(hash)}, nil
 }
 
-func (db *_DatabaseServiceMockItemExists) Write(obj ...*Authentication) error {
+func (db *_DatabaseServiceMockItemExists) Write(ctx context.Context, obj ...*Authentication) error {
 	return nil
 }
 
@@ -28,11 +46,11 @@ type _DatabaseServiceMockGetError struct {
 	database.Service[Authentication]
 }
 
-func (db *_DatabaseServiceMockGetError) Get(keyObj interface{}) (*Authentication, error) {
+func (db *_DatabaseServiceMockGetError) Get(ctx context.Context, keyObj interface{}) (*Authentication, error) {
 	return nil, errors.New("ERROR")
 }
 
-func (db *_DatabaseServiceMockGetError) Write(obj ...*Authentication) error {
+func (db *_DatabaseServiceMockGetError) Write(ctx context.Context, obj ...*Authentication) error {
 	return nil
 }
 
@@ -41,11 +59,11 @@ type _DatabaseServiceMockWriteError struct {
 	database.Service[Authentication]
 }
 
-func (db *_DatabaseServiceMockWriteError) Get(keyObj interface{}) (*Authentication, error) {
+func (db *_DatabaseServiceMockWriteError) Get(ctx context.Context, keyObj interface{}) (*Authentication, error) {
 	return nil, nil
 }
 
-func (db *_DatabaseServiceMockWriteError) Write(obj ...*Authentication) error {
+func (db *_DatabaseServiceMockWriteError) Write(ctx context.Context, obj ...*Authentication) error {
 	return errors.New("ERROR")
 }
 
@@ -54,17 +72,17 @@ type _DatabaseServiceMockItemNotFound struct {
 	database.Service[Authentication]
 }
 
-func (db *_DatabaseServiceMockItemNotFound) Get(keyObj interface{}) (*Authentication, error) {
+func (db *_DatabaseServiceMockItemNotFound) Get(ctx context.Context, keyObj interface{}) (*Authentication, error) {
 	return nil, nil
 }
 
-func (db *_DatabaseServiceMockItemNotFound) Write(obj ...*Authentication) error {
+func (db *_DatabaseServiceMockItemNotFound) Write(ctx context.Context, obj ...*Authentication) error {
 	return nil
 }
 
 func TestNewAuthenticationService(t *testing.T) {
 	t.Run("SUCCESS: RETURN NEW AUTHENTICATION SERVICE", func(t *testing.T) {
-		svc := NewAuthenticationService()
+		svc := NewAuthenticationService(&_DatabaseServiceMockItemNotFound{}, testTokens, testClock, testIDs, testLogger)
 
 		assert.NotEmpty(t, svc, "Service should not empty")
 	})
@@ -72,9 +90,9 @@ func TestNewAuthenticationService(t *testing.T) {
 
 func TestLogin(t *testing.T) {
 	t.Run("SUCCESS: RETURN JWT WHEN USER PASSWORD IS CORRECT", func(t *testing.T) {
-		svc := &_Service{db: &_DatabaseServiceMockItemExists{}}
+		svc := &_Service{db: &_DatabaseServiceMockItemExists{}, tokens: testTokens, clock: testClock, ids: testIDs, logger: testLogger}
 
-		result, err := svc.Login(LoginRequest{
+		result, err := svc.Login(context.Background(), LoginRequest{
 			Email:    "user@email.com",
 			Password: "correct.password",
 		})
@@ -84,9 +102,9 @@ func TestLogin(t *testing.T) {
 	})
 
 	t.Run("ERROR: RETURN 401 WHEN USER PASSWORD IS INCORRECT", func(t *testing.T) {
-		svc := &_Service{db: &_DatabaseServiceMockItemExists{}}
+		svc := &_Service{db: &_DatabaseServiceMockItemExists{}, tokens: testTokens, clock: testClock, ids: testIDs, logger: testLogger}
 
-		result, err := svc.Login(LoginRequest{
+		result, err := svc.Login(context.Background(), LoginRequest{
 			Email:    "user@email.com",
 			Password: "wrong.password",
 		})
@@ -96,9 +114,9 @@ func TestLogin(t *testing.T) {
 	})
 
 	t.Run("ERROR: RETURN 401 WHEN USER DOES NOT EXIST", func(t *testing.T) {
-		svc := &_Service{db: &_DatabaseServiceMockItemNotFound{}}
+		svc := &_Service{db: &_DatabaseServiceMockItemNotFound{}, tokens: testTokens, clock: testClock, ids: testIDs, logger: testLogger}
 
-		result, err := svc.Login(LoginRequest{
+		result, err := svc.Login(context.Background(), LoginRequest{
 			Email:    "user@email.com",
 			Password: "correct.password",
 		})
@@ -108,9 +126,9 @@ func TestLogin(t *testing.T) {
 	})
 
 	t.Run("ERROR: RETURN 503 WHEN DB RETURNS ERROR", func(t *testing.T) {
-		svc := &_Service{db: &_DatabaseServiceMockGetError{}}
+		svc := &_Service{db: &_DatabaseServiceMockGetError{}, tokens: testTokens, clock: testClock, ids: testIDs, logger: testLogger}
 
-		result, err := svc.Login(LoginRequest{
+		result, err := svc.Login(context.Background(), LoginRequest{
 			Email:    "user@email.com",
 			Password: "correct.password",
 		})
@@ -122,25 +140,41 @@ func TestLogin(t *testing.T) {
 
 func TestSignup(t *testing.T) {
 	t.Run("SUCCESS: RETURN CREATED MESSAGE WHEN ITEM NOT FOUND", func(t *testing.T) {
-		svc := &_Service{db: &_DatabaseServiceMockItemNotFound{}}
-		result, err := svc.Signup(SignupRequest{
+		svc := &_Service{db: &_DatabaseServiceMockItemNotFound{}, tokens: testTokens, clock: testClock, ids: testIDs, logger: testLogger}
+		result, err := svc.Signup(context.Background(), SignupRequest{
 			Email:    "user@email.com",
 			Password: "correct.password",
 			Name:     "user.name",
-			Phone:    "user.phone",
+			Phone:    "+14155550100",
 		})
 
 		assert.Equal(t, true, result.Status, "Status should be true")
 		assert.Empty(t, err, "Error should be empty")
 	})
 
+	t.Run("ERROR: RETURN 400 WITH FIELD DETAILS WHEN PHONE AND BIRTH DATE ARE INVALID", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockItemNotFound{}, tokens: testTokens, clock: testClock, ids: testIDs, logger: testLogger}
+		result, err := svc.Signup(context.Background(), SignupRequest{
+			Email:     "user@email.com",
+			Password:  "correct.password",
+			Name:      "user.name",
+			Phone:     "555-0100",
+			BirthDate: time.Now().AddDate(-10, 0, 0).Format("2006-01-02"),
+		})
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+		assert.Contains(t, err.Details, "phone")
+		assert.Contains(t, err.Details, "birth_date")
+		assert.Empty(t, result, "Result should be empty")
+	})
+
 	t.Run("ERROR: RETURN 400 ERROR WHEN ITEM EXISTS", func(t *testing.T) {
-		svc := &_Service{db: &_DatabaseServiceMockItemExists{}}
-		result, err := svc.Signup(SignupRequest{
+		svc := &_Service{db: &_DatabaseServiceMockItemExists{}, tokens: testTokens, clock: testClock, ids: testIDs, logger: testLogger}
+		result, err := svc.Signup(context.Background(), SignupRequest{
 			Email:    "user@email.com",
 			Password: "correct.password",
 			Name:     "user.name",
-			Phone:    "user.phone",
+			Phone:    "+14155550100",
 		})
 
 		assert.Equal(t, 400, err.Code, "Error should be 400")
@@ -148,13 +182,13 @@ func TestSignup(t *testing.T) {
 	})
 
 	t.Run("ERROR: RETURN 503 WHEN DB READ RETURNS ERROR", func(t *testing.T) {
-		svc := &_Service{db: &_DatabaseServiceMockGetError{}}
+		svc := &_Service{db: &_DatabaseServiceMockGetError{}, tokens: testTokens, clock: testClock, ids: testIDs, logger: testLogger}
 
-		result, err := svc.Signup(SignupRequest{
+		result, err := svc.Signup(context.Background(), SignupRequest{
 			Email:    "user@email.com",
 			Password: "correct.password",
 			Name:     "user.name",
-			Phone:    "user.phone",
+			Phone:    "+14155550100",
 		})
 
 		assert.Equal(t, 503, err.Code, "Error should be 503")
@@ -162,16 +196,32 @@ func TestSignup(t *testing.T) {
 	})
 
 	t.Run("ERROR: RETURN 503 WHEN DB WRITE RETURNS ERROR", func(t *testing.T) {
-		svc := &_Service{db: &_DatabaseServiceMockWriteError{}}
+		svc := &_Service{db: &_DatabaseServiceMockWriteError{}, tokens: testTokens, clock: testClock, ids: testIDs, logger: testLogger}
 
-		result, err := svc.Signup(SignupRequest{
+		result, err := svc.Signup(context.Background(), SignupRequest{
 			Email:    "user@email.com",
 			Password: "correct.password",
 			Name:     "user.name",
-			Phone:    "user.phone",
+			Phone:    "+14155550100",
 		})
 
 		assert.Equal(t, 503, err.Code, "Error should be 503")
 		assert.Empty(t, result, "Result should be empty")
 	})
 }
+
+func TestTokenIssuerPing(t *testing.T) {
+	t.Run("SUCCESS: RETURN NIL WHEN KEYS ARE LOADED", func(t *testing.T) {
+		err := testTokens.Ping(context.Background())
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("ERROR: RETURN ERROR WHEN REFRESH KEY IS MISSING", func(t *testing.T) {
+		tokens := NewTokenIssuer(config.JWT{AccessSecret: "access"}, testClock, testIDs)
+
+		err := tokens.Ping(context.Background())
+
+		assert.Error(t, err)
+	})
+}
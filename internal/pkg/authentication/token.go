@@ -0,0 +1,123 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"speakeasy/internal/pkg/config"
+	"speakeasy/pkg/clock"
+	"speakeasy/pkg/ids"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	accessTokenExpiry  = time.Minute * 15
+	refreshTokenExpiry = time.Hour * 24 * 7
+)
+
+// TokenIssuer interface which creates and verifies the access and refresh jwt of users
+type TokenIssuer interface {
+	CreateToken(userID string) (*Token, error)
+	VerifyAccessToken(tokenString string) (jwt.MapClaims, error)
+	VerifyRefreshToken(tokenString string) (jwt.MapClaims, error)
+	Ping(ctx context.Context) error
+}
+
+type _TokenIssuer struct {
+	secrets config.JWT
+	clock   clock.Clock
+	ids     ids.Generator
+}
+
+// NewTokenIssuer returns TokenIssuer which signs access and refresh tokens with their own secret
+func NewTokenIssuer(secrets config.JWT, clock clock.Clock, ids ids.Generator) TokenIssuer {
+	return &_TokenIssuer{secrets, clock, ids}
+}
+
+// CreateToken function to create access and refresh jwt of the user
+func (issuer *_TokenIssuer) CreateToken(userID string) (*Token, error) {
+	token := Token{}
+	now := issuer.clock.Now()
+
+	// Set generic claims for both access and refresh tokens
+	claims := jwt.MapClaims{}
+	claims["authorized"] = true
+	claims["user_id"] = userID
+
+	// Set claims and retrieve access token
+	claims["id"] = issuer.ids.NewID()
+	claims["exp"] = now.Add(accessTokenExpiry).Unix()
+	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := accessToken.SignedString([]byte(issuer.secrets.AccessSecret))
+	if err != nil {
+		return nil, err
+	}
+	token.AccessToken = signed
+
+	// Set claims and retrieve refresh token
+	claims["id"] = issuer.ids.NewID()
+	claims["exp"] = now.Add(refreshTokenExpiry).Unix()
+	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err = refreshToken.SignedString([]byte(issuer.secrets.RefreshSecret))
+	if err != nil {
+		return nil, err
+	}
+	token.RefreshToken = signed
+
+	return &token, nil
+}
+
+// VerifyAccessToken function to verify access jwt, returns the claims of the token
+func (issuer *_TokenIssuer) VerifyAccessToken(tokenString string) (jwt.MapClaims, error) {
+	return verifyToken(tokenString, issuer.secrets.AccessSecret)
+}
+
+// VerifyRefreshToken function to verify refresh jwt, returns the claims of the token
+func (issuer *_TokenIssuer) VerifyRefreshToken(tokenString string) (jwt.MapClaims, error) {
+	return verifyToken(tokenString, issuer.secrets.RefreshSecret)
+}
+
+// Ping function to check the signing keys are loaded, it's used by readiness checks
+func (issuer *_TokenIssuer) Ping(ctx context.Context) error {
+	if issuer.secrets.AccessSecret == "" || issuer.secrets.RefreshSecret == "" {
+		return errors.New("jwt signing keys are not loaded")
+	}
+
+	return nil
+}
+
+// ExtractToken function to extract jwt from http request
+func ExtractToken(r *http.Request) string {
+	bearToken := r.Header.Get("Authorization")
+	//normally Authorization the_token_xxx
+	strArr := strings.Split(bearToken, " ")
+	if len(strArr) == 2 {
+		return strArr[1]
+	}
+	return ""
+}
+
+// verifyToken function to verify jwt signed with the secret, returns the claims of the token
+func verifyToken(tokenString string, secret string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("token is invalid")
+	}
+
+	return claims, nil
+}
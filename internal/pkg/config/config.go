@@ -0,0 +1,342 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"speakeasy/pkg/database"
+	"speakeasy/pkg/filestorage"
+	"speakeasy/pkg/logging"
+	"speakeasy/pkg/timeout"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileEnv is the environment variable which contains the path of the optional YAML config file
+const configFileEnv = "CONFIG_FILE"
+
+// Config object which contains the settings of the api, each field can be set in the YAML file
+// with its yaml name or with the environment variable of its env tag
+type Config struct {
+	LogLevel string   `yaml:"log_level" env:"LOG_LEVEL"`
+	Server   Server   `yaml:"server"`
+	AWS      AWS      `yaml:"aws"`
+	Tables   Tables   `yaml:"tables"`
+	Storage  Storage  `yaml:"storage"`
+	JWT      JWT      `yaml:"jwt"`
+	CORS     CORS     `yaml:"cors"`
+	Timeouts Timeouts `yaml:"timeouts"`
+	Health   Health   `yaml:"health"`
+}
+
+// Server object which contains the listen address and the timeouts of the HTTP server, it's not used in Lambda.
+// On SIGTERM readiness reports unhealthy for the shutdown delay so load balancers stop routing to the server,
+// then in-flight requests are drained until the shutdown timeout.
+type Server struct {
+	Addr              string        `yaml:"addr" env:"SERVER_ADDR"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	ShutdownDelay     time.Duration `yaml:"shutdown_delay" env:"SERVER_SHUTDOWN_DELAY"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+}
+
+// AWS object which contains the region and the endpoints of the AWS clients,
+// the endpoints are only set for local or S3 compatible services
+type AWS struct {
+	Region           string `yaml:"region" env:"AWS_REGION"`
+	DynamoDBEndpoint string `yaml:"dynamodb_endpoint" env:"DYNAMODB_ENDPOINT"`
+	S3Endpoint       string `yaml:"s3_endpoint" env:"S3_ENDPOINT"`
+}
+
+// Tables object which contains the DynamoDB table names
+type Tables struct {
+	Application    string `yaml:"application" env:"TABLE_APPLICATION"`
+	Authentication string `yaml:"authentication" env:"TABLE_AUTHENTICATION"`
+}
+
+// Storage object which contains the file storage settings, the local settings are only used by the local backend
+type Storage struct {
+	Backend     string `yaml:"backend" env:"FILE_STORAGE_BACKEND"`
+	Bucket      string `yaml:"bucket" env:"FILE_STORAGE_BUCKET"`
+	LocalPath   string `yaml:"local_path" env:"FILE_STORAGE_PATH"`
+	LocalURL    string `yaml:"local_url" env:"FILE_STORAGE_LOCAL_URL"`
+	LocalSecret string `yaml:"local_secret" env:"FILE_STORAGE_SECRET"`
+}
+
+// JWT object which contains the secrets of the access, refresh and invite link tokens
+type JWT struct {
+	AccessSecret  string `yaml:"access_secret" env:"JWT_ACCESS_SECRET"`
+	RefreshSecret string `yaml:"refresh_secret" env:"JWT_REFRESH_SECRET"`
+	InviteSecret  string `yaml:"invite_secret" env:"JWT_INVITE_SECRET"`
+}
+
+// CORS object which contains the origins allowed to call the api from browsers
+type CORS struct {
+	AllowOrigins []string `yaml:"allow_origins" env:"CORS_ALLOW_ORIGINS"`
+}
+
+// Timeouts object which contains the timeout of each kind of DynamoDB and S3 call, e.g. 2s
+type Timeouts struct {
+	DatabaseRead  time.Duration `yaml:"database_read" env:"TIMEOUT_DATABASE_READ"`
+	DatabaseQuery time.Duration `yaml:"database_query" env:"TIMEOUT_DATABASE_QUERY"`
+	DatabaseWrite time.Duration `yaml:"database_write" env:"TIMEOUT_DATABASE_WRITE"`
+	StorageRead   time.Duration `yaml:"storage_read" env:"TIMEOUT_STORAGE_READ"`
+	StorageWrite  time.Duration `yaml:"storage_write" env:"TIMEOUT_STORAGE_WRITE"`
+}
+
+// Health object which contains the timeout of each readiness check and how long the readiness report is cached
+type Health struct {
+	CheckTimeout time.Duration `yaml:"check_timeout" env:"HEALTH_CHECK_TIMEOUT"`
+	CacheTTL     time.Duration `yaml:"cache_ttl" env:"HEALTH_CACHE_TTL"`
+}
+
+// Default returns the config which is used for settings which are not set
+func Default() *Config {
+	return &Config{
+		LogLevel: "info",
+		Server: Server{
+			Addr:              ":8080",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			ShutdownDelay:     5 * time.Second,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		Tables: Tables{
+			Application:    "APPLICATION",
+			Authentication: "AUTHENTICATION",
+		},
+		Storage: Storage{
+			Backend:   filestorage.BackendS3,
+			Bucket:    "profile.image.amuel.org",
+			LocalPath: "data/files",
+			LocalURL:  "http://localhost:8080",
+		},
+		CORS: CORS{
+			AllowOrigins: []string{"http://localhost:3000", "https://amuel.org", "https://dev.amuel.org"},
+		},
+		Timeouts: Timeouts{
+			DatabaseRead:  timeout.Defaults[timeout.DatabaseRead],
+			DatabaseQuery: timeout.Defaults[timeout.DatabaseQuery],
+			DatabaseWrite: timeout.Defaults[timeout.DatabaseWrite],
+			StorageRead:   timeout.Defaults[timeout.StorageRead],
+			StorageWrite:  timeout.Defaults[timeout.StorageWrite],
+		},
+		Health: Health{
+			CheckTimeout: 2 * time.Second,
+			CacheTTL:     5 * time.Second,
+		},
+	}
+}
+
+// Load returns the config read from the defaults, the YAML file of CONFIG_FILE if it's set,
+// and the environment including the .env file, later sources override earlier ones.
+// Returns error if a value can't be parsed or the config is invalid, the server should not start in that case.
+func Load() (*Config, error) {
+	// Variables which are already set are not overridden by .env
+	godotenv.Load()
+
+	cfg := Default()
+
+	if path := os.Getenv(configFileEnv); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := loadEnv(reflect.ValueOf(cfg).Elem(), os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Validate returns error which lists every missing or invalid setting
+func (cfg *Config) Validate() error {
+	errs := []error{}
+	required := func(value string, name string) {
+		if strings.TrimSpace(value) == "" {
+			errs = append(errs, fmt.Errorf("%s is required", name))
+		}
+	}
+
+	required(cfg.Server.Addr, "SERVER_ADDR")
+	required(cfg.AWS.Region, "AWS_REGION")
+	required(cfg.Tables.Application, "TABLE_APPLICATION")
+	required(cfg.Tables.Authentication, "TABLE_AUTHENTICATION")
+	required(cfg.Storage.Bucket, "FILE_STORAGE_BUCKET")
+	required(cfg.JWT.AccessSecret, "JWT_ACCESS_SECRET")
+	required(cfg.JWT.RefreshSecret, "JWT_REFRESH_SECRET")
+	required(cfg.JWT.InviteSecret, "JWT_INVITE_SECRET")
+
+	if cfg.JWT.AccessSecret != "" && cfg.JWT.AccessSecret == cfg.JWT.RefreshSecret {
+		errs = append(errs, errors.New("JWT_REFRESH_SECRET must be different from JWT_ACCESS_SECRET"))
+	}
+
+	if cfg.Storage.Backend != filestorage.BackendS3 && cfg.Storage.Backend != filestorage.BackendLocal {
+		errs = append(errs, fmt.Errorf("FILE_STORAGE_BACKEND must be %s or %s", filestorage.BackendS3, filestorage.BackendLocal))
+	}
+
+	if !logging.ValidLevel(cfg.LogLevel) {
+		errs = append(errs, errors.New("LOG_LEVEL must be debug, info, warn or error"))
+	}
+
+	for op, d := range cfg.Timeouts.operations() {
+		if d <= 0 {
+			errs = append(errs, fmt.Errorf("timeout of %s must be positive", op))
+		}
+	}
+
+	for name, d := range cfg.Server.timeouts() {
+		if d <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", name))
+		}
+	}
+
+	if cfg.Server.ShutdownDelay < 0 {
+		errs = append(errs, errors.New("SERVER_SHUTDOWN_DELAY must not be negative"))
+	}
+
+	if cfg.Health.CheckTimeout <= 0 {
+		errs = append(errs, errors.New("HEALTH_CHECK_TIMEOUT must be positive"))
+	}
+
+	if cfg.Health.CacheTTL < 0 {
+		errs = append(errs, errors.New("HEALTH_CACHE_TTL must not be negative"))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	return nil
+}
+
+// Database returns the settings of the DynamoDB clients
+func (cfg *Config) Database() database.Config {
+	return database.Config{
+		Region:   cfg.AWS.Region,
+		Endpoint: cfg.AWS.DynamoDBEndpoint,
+	}
+}
+
+// FileStorage returns the settings of the file storage clients
+func (cfg *Config) FileStorage() filestorage.Config {
+	return filestorage.Config{
+		Backend:     cfg.Storage.Backend,
+		Region:      cfg.AWS.Region,
+		Endpoint:    cfg.AWS.S3Endpoint,
+		LocalPath:   cfg.Storage.LocalPath,
+		LocalURL:    cfg.Storage.LocalURL,
+		LocalSecret: cfg.Storage.LocalSecret,
+	}
+}
+
+// ApplyTimeouts sets the timeouts of the DynamoDB and S3 calls
+func (cfg *Config) ApplyTimeouts() {
+	for op, d := range cfg.Timeouts.operations() {
+		timeout.Set(op, d)
+	}
+}
+
+// operations returns the timeouts keyed by operation
+func (timeouts *Timeouts) operations() map[timeout.Operation]time.Duration {
+	return map[timeout.Operation]time.Duration{
+		timeout.DatabaseRead:  timeouts.DatabaseRead,
+		timeout.DatabaseQuery: timeouts.DatabaseQuery,
+		timeout.DatabaseWrite: timeouts.DatabaseWrite,
+		timeout.StorageRead:   timeouts.StorageRead,
+		timeout.StorageWrite:  timeouts.StorageWrite,
+	}
+}
+
+// timeouts returns the timeouts of the HTTP server keyed by environment variable
+func (server *Server) timeouts() map[string]time.Duration {
+	return map[string]time.Duration{
+		"SERVER_READ_HEADER_TIMEOUT": server.ReadHeaderTimeout,
+		"SERVER_READ_TIMEOUT":        server.ReadTimeout,
+		"SERVER_WRITE_TIMEOUT":       server.WriteTimeout,
+		"SERVER_IDLE_TIMEOUT":        server.IdleTimeout,
+		"SERVER_SHUTDOWN_TIMEOUT":    server.ShutdownTimeout,
+	}
+}
+
+// loadFile reads the YAML file into the config, unknown fields are an error so typos are not ignored
+func (cfg *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("read config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// loadEnv sets the fields with an env tag to the value of the environment variable if it's set,
+// lists are comma separated and durations use the time.ParseDuration format
+func loadEnv(v reflect.Value, lookupEnv func(string) (string, bool)) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		name := v.Type().Field(i).Tag.Get("env")
+
+		if name == "" {
+			if field.Kind() == reflect.Struct {
+				if err := loadEnv(field, lookupEnv); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		value, ok := lookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case field.Type() == durationType:
+			d, err := time.ParseDuration(strings.TrimSpace(value))
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			field.SetInt(int64(d))
+		case field.Kind() == reflect.Slice:
+			field.Set(reflect.ValueOf(splitList(value)))
+		default:
+			field.SetString(value)
+		}
+	}
+
+	return nil
+}
+
+// splitList returns the non-empty items of the comma separated list
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setRequiredEnv sets the settings which have no default
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("JWT_ACCESS_SECRET", "access")
+	t.Setenv("JWT_REFRESH_SECRET", "refresh")
+	t.Setenv("JWT_INVITE_SECRET", "invite")
+}
+
+// writeConfigFile writes the YAML config file and sets CONFIG_FILE
+func writeConfigFile(t *testing.T, content string) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	t.Setenv("CONFIG_FILE", path)
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("SUCCESS: RETURN DEFAULTS WITH REQUIRED SETTINGS FROM ENV", func(t *testing.T) {
+		setRequiredEnv(t)
+
+		cfg, err := Load()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "us-east-1", cfg.AWS.Region)
+		assert.Equal(t, "access", cfg.JWT.AccessSecret)
+		assert.Equal(t, "APPLICATION", cfg.Tables.Application)
+		assert.Equal(t, "s3", cfg.Storage.Backend)
+		assert.Equal(t, 2*time.Second, cfg.Timeouts.DatabaseRead)
+		assert.Equal(t, ":8080", cfg.Server.Addr)
+		assert.Equal(t, 20*time.Second, cfg.Server.ShutdownTimeout)
+		assert.Equal(t, 5*time.Second, cfg.Health.CacheTTL)
+		assert.Len(t, cfg.CORS.AllowOrigins, 3)
+	})
+
+	t.Run("SUCCESS: ENV OVERRIDES CONFIG FILE", func(t *testing.T) {
+		setRequiredEnv(t)
+		writeConfigFile(t, `
+log_level: debug
+server:
+  addr: 127.0.0.1:9090
+tables:
+  application: APPLICATION_DEV
+storage:
+  backend: local
+cors:
+  allow_origins: [https://file.example]
+timeouts:
+  database_read: 500ms
+`)
+		t.Setenv("CORS_ALLOW_ORIGINS", "https://a.example, https://b.example,")
+		t.Setenv("TIMEOUT_DATABASE_QUERY", "4s")
+
+		cfg, err := Load()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "debug", cfg.LogLevel)
+		assert.Equal(t, "127.0.0.1:9090", cfg.Server.Addr)
+		assert.Equal(t, "APPLICATION_DEV", cfg.Tables.Application)
+		assert.Equal(t, "local", cfg.Storage.Backend)
+		assert.True(t, cfg.FileStorage().UseLocalStorage())
+		assert.Equal(t, []string{"https://a.example", "https://b.example"}, cfg.CORS.AllowOrigins)
+		assert.Equal(t, 500*time.Millisecond, cfg.Timeouts.DatabaseRead)
+		assert.Equal(t, 4*time.Second, cfg.Timeouts.DatabaseQuery)
+	})
+
+	t.Run("ERROR: RETURN ERROR WHEN JWT SECRETS ARE MISSING", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("JWT_ACCESS_SECRET", "")
+		t.Setenv("JWT_INVITE_SECRET", " ")
+
+		cfg, err := Load()
+
+		assert.Nil(t, cfg, "Config should be nil")
+		assert.ErrorContains(t, err, "JWT_ACCESS_SECRET is required")
+		assert.ErrorContains(t, err, "JWT_INVITE_SECRET is required")
+		assert.NotContains(t, err.Error(), "JWT_REFRESH_SECRET")
+	})
+
+	t.Run("ERROR: RETURN ERROR WHEN ACCESS AND REFRESH SECRETS ARE EQUAL", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("JWT_REFRESH_SECRET", "access")
+
+		_, err := Load()
+
+		assert.ErrorContains(t, err, "JWT_REFRESH_SECRET must be different from JWT_ACCESS_SECRET")
+	})
+
+	t.Run("ERROR: RETURN ERROR WHEN SETTINGS ARE INVALID", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("FILE_STORAGE_BACKEND", "ftp")
+		t.Setenv("LOG_LEVEL", "verbose")
+
+		_, err := Load()
+
+		assert.ErrorContains(t, err, "FILE_STORAGE_BACKEND must be s3 or local")
+		assert.ErrorContains(t, err, "LOG_LEVEL must be debug, info, warn or error")
+	})
+
+	t.Run("ERROR: RETURN ERROR WHEN SERVER TIMEOUTS ARE INVALID", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("SERVER_WRITE_TIMEOUT", "0s")
+		t.Setenv("SERVER_SHUTDOWN_DELAY", "-1s")
+
+		_, err := Load()
+
+		assert.ErrorContains(t, err, "SERVER_WRITE_TIMEOUT must be positive")
+		assert.ErrorContains(t, err, "SERVER_SHUTDOWN_DELAY must not be negative")
+	})
+
+	t.Run("ERROR: RETURN ERROR WHEN HEALTH SETTINGS ARE INVALID", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("HEALTH_CHECK_TIMEOUT", "0s")
+		t.Setenv("HEALTH_CACHE_TTL", "-5s")
+
+		_, err := Load()
+
+		assert.ErrorContains(t, err, "HEALTH_CHECK_TIMEOUT must be positive")
+		assert.ErrorContains(t, err, "HEALTH_CACHE_TTL must not be negative")
+	})
+
+	t.Run("ERROR: RETURN ERROR WHEN DURATION CAN'T BE PARSED", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("TIMEOUT_STORAGE_READ", "5")
+
+		_, err := Load()
+
+		assert.ErrorContains(t, err, "TIMEOUT_STORAGE_READ")
+	})
+
+	t.Run("ERROR: RETURN ERROR WHEN CONFIG FILE HAS UNKNOWN FIELD", func(t *testing.T) {
+		setRequiredEnv(t)
+		writeConfigFile(t, "tabels:\n  application: APPLICATION\n")
+
+		_, err := Load()
+
+		assert.ErrorContains(t, err, "tabels")
+	})
+}
@@ -0,0 +1,60 @@
+package feed
+
+var USER_PK string = "USER#%s"
+var EVENT_SK string = "EVENT#%s#%s"
+var FEED_SK string = "FEED#%s"
+var FEED_GSI_PK string = "FEED#%s"
+var FEED_GSI_SK string = "%s#%s"
+
+// EventType is the type of activity
+type EventType string
+
+const (
+	EventTripCreated EventType = "trip_created"
+	EventTripJoined  EventType = "trip_joined"
+	EventPhotoPosted EventType = "photo_posted"
+)
+
+// Audience of an event, private activity is never published
+type Audience string
+
+const (
+	AudiencePublic  Audience = "public"
+	AudienceFriends Audience = "friends"
+)
+
+// Event object which is an activity of a user on a trip.
+// ObjectID is the id of the object created by the activity, e.g. photo id.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      EventType `json:"type"`
+	ActorID   string    `json:"actor_id"`
+	TripID    string    `json:"trip_id"`
+	TripName  string    `json:"trip_name,omitempty"`
+	ObjectID  string    `json:"object_id,omitempty"`
+	Audience  Audience  `json:"audience"`
+	CreatedAt string    `json:"created_at"`
+}
+
+// Item object to store in database.
+// Events of an actor are stored with PK USER#<actor id> and SK in the format of EVENT_SK,
+// each recipient gets a copy with PK USER#<recipient id> and SK in the format of FEED_SK.
+// The feed SK only depends on the type, actor and trip, so a later event of the same activity
+// overwrites the recipient's copy, and GSI2PK and GSI2SK order the feed by time.
+type Item struct {
+	PK     string `json:"PK"`
+	SK     string `json:"SK"`
+	GSI2PK string `json:"-" dynamodbav:"GSI2PK,omitempty"`
+	GSI2SK string `json:"-" dynamodbav:"GSI2SK,omitempty"`
+	Event
+}
+
+// ItemResponse object which contains the feed item fields sent to clients
+type ItemResponse struct {
+	Event
+}
+
+// Response returns feed item as sent to clients
+func (item *Item) Response() ItemResponse {
+	return ItemResponse{Event: item.Event}
+}
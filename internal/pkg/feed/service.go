@@ -0,0 +1,258 @@
+package feed
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"speakeasy/internal/pkg/social"
+	"speakeasy/pkg"
+	"speakeasy/pkg/clock"
+	"speakeasy/pkg/database"
+	"strings"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+	backfillLimit    = 20
+
+	// createdAtLayout has fixed width so sort keys are ordered by time
+	createdAtLayout = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+type _Service struct {
+	db            database.Service[Item]
+	socialService social.Service
+	clock         clock.Clock
+}
+
+// NewFeedService returns _Service object
+func NewFeedService(db database.Service[Item], socialService social.Service, clock clock.Clock) Service {
+	return &_Service{db, socialService, clock}
+}
+
+// Service interface which contains activity feed operations
+type Service interface {
+	Publish(ctx context.Context, event *Event) *pkg.Error
+	GetFeed(ctx context.Context, userID string, page *social.PageRequest) (*database.Page[Item], *pkg.Error)
+	Backfill(ctx context.Context, followerID string, followeeID string) *pkg.Error
+}
+
+// Publish function to store event and fan it out to the feed of followers and friends of the actor.
+// Events with the same type, actor, trip and object share an id, and the recipients' copies of events
+// with the same type, actor and trip share a key, so repeated activity overwrites the previous feed item.
+func (service *_Service) Publish(ctx context.Context, event *Event) *pkg.Error {
+	if event.Audience != AudiencePublic && event.Audience != AudienceFriends {
+		return &pkg.Error{Code: 400, Reason: "Event audience is invalid"}
+	}
+
+	event.ID = eventID(event)
+	event.CreatedAt = service.clock.Now().UTC().Format(createdAtLayout)
+
+	recipients, err := service.recipients(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	items := []*Item{{
+		PK:    fmt.Sprintf(USER_PK, event.ActorID),
+		SK:    fmt.Sprintf(EVENT_SK, event.CreatedAt, event.ID),
+		Event: *event,
+	}}
+
+	for _, recipient := range recipients {
+		items = append(items, feedItem(recipient, event))
+	}
+
+	if err := service.db.Write(ctx, items...); err != nil {
+		return pkg.Unavailable("Publish", err)
+	}
+
+	return nil
+}
+
+// GetFeed function to get a page of the user's feed, most recent first.
+// Events of blocked users are removed.
+func (service *_Service) GetFeed(ctx context.Context, userID string, page *social.PageRequest) (*database.Page[Item], *pkg.Error) {
+	if page.Limit < 0 || page.Limit > maxPageLimit {
+		return nil, &pkg.Error{Code: 400, Reason: "Limit is invalid"}
+	}
+
+	options := &database.QueryOptions{
+		Index:      "APPLICATION_GSI_2",
+		Limit:      page.Limit,
+		Cursor:     page.Cursor,
+		Descending: true,
+	}
+
+	if options.Limit == 0 {
+		options.Limit = defaultPageLimit
+	}
+
+	filter := map[string]string{
+		":PK": fmt.Sprintf(FEED_GSI_PK, userID),
+	}
+
+	result, err := service.db.QueryPage(ctx, filter, "GSI2PK = :PK", options)
+	if errors.Is(err, database.ErrInvalidCursor) {
+		return nil, &pkg.Error{Code: 400, Reason: "Cursor is invalid"}
+	}
+
+	if err != nil {
+		return nil, pkg.Unavailable("GetFeed", err)
+	}
+
+	blocked, perr := service.socialService.GetBlockedSet(ctx, userID)
+	if perr != nil {
+		return nil, perr
+	}
+
+	items := []Item{}
+	for _, item := range result.Items {
+		if !blocked[item.ActorID] {
+			items = append(items, item)
+		}
+	}
+
+	result.Items = items
+
+	return result, nil
+}
+
+// Backfill function to copy recent events of a followed user into the follower's feed
+func (service *_Service) Backfill(ctx context.Context, followerID string, followeeID string) *pkg.Error {
+	filter := map[string]string{
+		":PK": fmt.Sprintf(USER_PK, followeeID),
+		":SK": "EVENT#",
+	}
+
+	options := &database.QueryOptions{
+		Limit:      backfillLimit,
+		Descending: true,
+	}
+
+	events, err := service.db.QueryPage(ctx, filter, "PK = :PK And begins_with(SK, :SK)", options)
+	if err != nil {
+		return pkg.Unavailable("Backfill", err)
+	}
+
+	// Events are most recent first, only the most recent event of each feed item is copied
+	items := []*Item{}
+	seen := map[string]bool{}
+	for _, event := range events.Items {
+		key := feedKey(&event.Event)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		// Friends only events are copied only if the users are friends
+		if event.Audience != AudiencePublic {
+			friends, err := service.socialService.AreFriends(ctx, followerID, followeeID)
+			if err != nil {
+				return err
+			}
+
+			if !friends {
+				continue
+			}
+		}
+
+		items = append(items, feedItem(followerID, &event.Event))
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	if err := service.db.Write(ctx, items...); err != nil {
+		return pkg.Unavailable("Backfill", err)
+	}
+
+	return nil
+}
+
+// recipients returns the users whose feed receives the event
+func (service *_Service) recipients(ctx context.Context, event *Event) ([]string, *pkg.Error) {
+	lists := []func(ctx context.Context, userID string, page *social.PageRequest) (*database.Page[social.Relationship], *pkg.Error){
+		service.socialService.GetFriends,
+	}
+
+	if event.Audience == AudiencePublic {
+		lists = append(lists, service.socialService.GetFollowers)
+	}
+
+	// Users blocked by or blocking the actor never receive the event
+	blocked, err := service.socialService.GetBlockedSet(ctx, event.ActorID)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := []string{}
+	seen := map[string]bool{event.ActorID: true}
+	for userID := range blocked {
+		seen[userID] = true
+	}
+
+	for _, list := range lists {
+		page := &social.PageRequest{Limit: maxPageLimit}
+		for {
+			result, err := list(ctx, event.ActorID, page)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, relationship := range result.Items {
+				// Friends are stored under the actor, followers under the follower
+				recipient := relationship.TargetID
+				if relationship.Type == social.RelationshipFollows {
+					recipient = relationship.UserID
+				}
+
+				if !seen[recipient] {
+					seen[recipient] = true
+					recipients = append(recipients, recipient)
+				}
+			}
+
+			if result.Cursor == "" {
+				break
+			}
+			page.Cursor = result.Cursor
+		}
+	}
+
+	return recipients, nil
+}
+
+// eventID returns the same id for the same activity so it can be deduplicated
+func eventID(event *Event) string {
+	hash := sha1.Sum([]byte(strings.Join([]string{
+		string(event.Type), event.ActorID, event.TripID, event.ObjectID,
+	}, "#")))
+
+	return hex.EncodeToString(hash[:])[:16]
+}
+
+// feedKey returns the same key for events with the same type, actor and trip,
+// so the recipient's feed keeps one item for them
+func feedKey(event *Event) string {
+	hash := sha1.Sum([]byte(strings.Join([]string{
+		string(event.Type), event.ActorID, event.TripID,
+	}, "#")))
+
+	return hex.EncodeToString(hash[:])[:16]
+}
+
+// feedItem returns the recipient's copy of the event
+func feedItem(recipient string, event *Event) *Item {
+	return &Item{
+		PK:     fmt.Sprintf(USER_PK, recipient),
+		SK:     fmt.Sprintf(FEED_SK, feedKey(event)),
+		GSI2PK: fmt.Sprintf(FEED_GSI_PK, recipient),
+		GSI2SK: fmt.Sprintf(FEED_GSI_SK, event.CreatedAt, event.ID),
+		Event:  *event,
+	}
+}
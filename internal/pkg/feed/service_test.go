@@ -0,0 +1,202 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"speakeasy/internal/pkg/social"
+	"speakeasy/pkg"
+	"speakeasy/pkg/clock"
+	"speakeasy/pkg/database"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testClock is the real clock, tests which check times set a fixed one
+var testClock = clock.System()
+
+// Mock DatabaseService which keeps feed items in memory in write order,
+// writing an item with an existing key replaces it
+type _DatabaseServiceMockInMemory struct {
+	database.Service[Item]
+	items []Item
+}
+
+func (db *_DatabaseServiceMockInMemory) Write(ctx context.Context, obj ...*Item) error {
+	for _, item := range obj {
+		items := []Item{}
+		for _, existing := range db.items {
+			if existing.PK != item.PK || existing.SK != item.SK {
+				items = append(items, existing)
+			}
+		}
+		db.items = append(items, *item)
+	}
+	return nil
+}
+
+func (db *_DatabaseServiceMockInMemory) QueryPage(ctx context.Context, filterObj interface{}, condition string, options *database.QueryOptions) (*database.Page[Item], error) {
+	filter := filterObj.(map[string]string)
+	page := &database.Page[Item]{Items: []Item{}}
+
+	// Items are written in order, return most recent first
+	for i := len(db.items) - 1; i >= 0; i-- {
+		item := db.items[i]
+		if options.Index == "APPLICATION_GSI_2" && item.GSI2PK == filter[":PK"] ||
+			options.Index == "" && item.PK == filter[":PK"] && strings.HasPrefix(item.SK, filter[":SK"]) {
+			page.Items = append(page.Items, item)
+		}
+	}
+	return page, nil
+}
+
+// Mock DatabaseService where every operation returns an error
+type _DatabaseServiceMockError struct {
+	database.Service[Item]
+}
+
+func (db *_DatabaseServiceMockError) Write(ctx context.Context, obj ...*Item) error {
+	return errors.New("ERROR")
+}
+
+// Mock SocialService where every user has "friend" as a friend and "follower" as a follower,
+// and "actor" and "blocker" have blocked each other
+type _SocialServiceMock struct {
+	social.Service
+}
+
+func (svc *_SocialServiceMock) GetFriends(ctx context.Context, userID string, page *social.PageRequest) (*database.Page[social.Relationship], *pkg.Error) {
+	return &database.Page[social.Relationship]{Items: []social.Relationship{
+		{UserID: userID, TargetID: "friend", Type: social.RelationshipFriend},
+	}}, nil
+}
+
+func (svc *_SocialServiceMock) GetFollowers(ctx context.Context, userID string, page *social.PageRequest) (*database.Page[social.Relationship], *pkg.Error) {
+	return &database.Page[social.Relationship]{Items: []social.Relationship{
+		{UserID: "follower", TargetID: userID, Type: social.RelationshipFollows},
+		{UserID: "friend", TargetID: userID, Type: social.RelationshipFollows},
+		{UserID: "blocker", TargetID: userID, Type: social.RelationshipFollows},
+	}}, nil
+}
+
+func (svc *_SocialServiceMock) GetBlockedSet(ctx context.Context, userID string) (map[string]bool, *pkg.Error) {
+	if userID == "actor" || userID == "blocker" {
+		return map[string]bool{"blocker": true, "actor": true}, nil
+	}
+	return map[string]bool{}, nil
+}
+
+func (svc *_SocialServiceMock) AreFriends(ctx context.Context, userID string, otherID string) (bool, *pkg.Error) {
+	return userID == "friend" || otherID == "friend", nil
+}
+
+func TestPublish(t *testing.T) {
+	t.Run("SUCCESS: PUBLIC EVENT IS FANNED OUT TO FRIENDS AND FOLLOWERS ONCE EXCEPT BLOCKED USERS", func(t *testing.T) {
+		db := &_DatabaseServiceMockInMemory{}
+		svc := &_Service{db: db, socialService: &_SocialServiceMock{}, clock: testClock}
+
+		err := svc.Publish(context.Background(), &Event{Type: EventTripCreated, ActorID: "actor", TripID: "trip", Audience: AudiencePublic})
+
+		assert.Empty(t, err)
+		pks := []string{}
+		for _, item := range db.items {
+			pks = append(pks, item.PK)
+		}
+		assert.ElementsMatch(t, []string{"USER#actor", "USER#friend", "USER#follower"}, pks)
+		assert.True(t, strings.HasPrefix(db.items[0].SK, "EVENT#"), "Actor should store the event")
+	})
+
+	t.Run("SUCCESS: FRIENDS EVENT IS ONLY FANNED OUT TO FRIENDS", func(t *testing.T) {
+		db := &_DatabaseServiceMockInMemory{}
+		svc := &_Service{db: db, socialService: &_SocialServiceMock{}, clock: testClock}
+
+		err := svc.Publish(context.Background(), &Event{Type: EventTripCreated, ActorID: "actor", TripID: "trip", Audience: AudienceFriends})
+
+		assert.Empty(t, err)
+		assert.Len(t, db.items, 2)
+		assert.Equal(t, "USER#friend", db.items[1].PK)
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN AUDIENCE IS INVALID", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockInMemory{}, socialService: &_SocialServiceMock{}, clock: testClock}
+
+		err := svc.Publish(context.Background(), &Event{Type: EventTripCreated, ActorID: "actor", TripID: "trip"})
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+	})
+
+	t.Run("ERROR: RETURN 503 WHEN DB WRITE RETURNS ERROR", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockError{}, socialService: &_SocialServiceMock{}, clock: testClock}
+
+		err := svc.Publish(context.Background(), &Event{Type: EventTripCreated, ActorID: "actor", TripID: "trip", Audience: AudiencePublic})
+
+		assert.Equal(t, 503, err.Code, "Error should be 503")
+	})
+}
+
+func TestGetFeed(t *testing.T) {
+	t.Run("SUCCESS: EVENTS WITH THE SAME TYPE, ACTOR AND TRIP OVERWRITE THE FEED ITEM", func(t *testing.T) {
+		db := &_DatabaseServiceMockInMemory{}
+		svc := &_Service{db: db, socialService: &_SocialServiceMock{}, clock: testClock}
+
+		events := []Event{
+			{Type: EventTripJoined, ActorID: "actor", TripID: "trip", Audience: AudiencePublic},
+			{Type: EventTripJoined, ActorID: "actor", TripID: "trip", Audience: AudiencePublic},
+			{Type: EventPhotoPosted, ActorID: "actor", TripID: "trip", ObjectID: "photo.1", Audience: AudiencePublic},
+			{Type: EventPhotoPosted, ActorID: "actor", TripID: "trip", ObjectID: "photo.2", Audience: AudiencePublic},
+			{Type: EventTripJoined, ActorID: "actor", TripID: "trip", Audience: AudiencePublic},
+		}
+		for _, event := range events {
+			event := event
+			assert.Empty(t, svc.Publish(context.Background(), &event))
+		}
+
+		result, err := svc.GetFeed(context.Background(), "follower", &social.PageRequest{})
+
+		assert.Empty(t, err)
+		assert.Len(t, result.Items, 2)
+		assert.Equal(t, EventTripJoined, result.Items[0].Type)
+		assert.Equal(t, EventPhotoPosted, result.Items[1].Type)
+		assert.Equal(t, "photo.2", result.Items[1].ObjectID)
+	})
+
+	t.Run("SUCCESS: EVENTS OF BLOCKED USERS ARE REMOVED", func(t *testing.T) {
+		db := &_DatabaseServiceMockInMemory{}
+		svc := &_Service{db: db, socialService: &_SocialServiceMock{}, clock: testClock}
+
+		// item written before the block happened
+		db.items = append(db.items, Item{PK: "USER#blocker", SK: "FEED#0", GSI2PK: "FEED#blocker", GSI2SK: "0#0", Event: Event{ID: "0", ActorID: "actor"}})
+
+		result, err := svc.GetFeed(context.Background(), "blocker", &social.PageRequest{})
+
+		assert.Empty(t, err)
+		assert.Empty(t, result.Items, "Items should be empty")
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN LIMIT IS INVALID", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockInMemory{}, socialService: &_SocialServiceMock{}, clock: testClock}
+
+		result, err := svc.GetFeed(context.Background(), "user", &social.PageRequest{Limit: -1})
+
+		assert.Empty(t, result, "Result should be empty")
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+	})
+}
+
+func TestBackfill(t *testing.T) {
+	t.Run("SUCCESS: COPY PUBLIC EVENTS TO NEW FOLLOWER", func(t *testing.T) {
+		db := &_DatabaseServiceMockInMemory{}
+		svc := &_Service{db: db, socialService: &_SocialServiceMock{}, clock: testClock}
+
+		assert.Empty(t, svc.Publish(context.Background(), &Event{Type: EventTripCreated, ActorID: "actor", TripID: "public", Audience: AudiencePublic}))
+		assert.Empty(t, svc.Publish(context.Background(), &Event{Type: EventTripCreated, ActorID: "actor", TripID: "friends", Audience: AudienceFriends}))
+
+		err := svc.Backfill(context.Background(), "stranger", "actor")
+		assert.Empty(t, err)
+
+		result, _ := svc.GetFeed(context.Background(), "stranger", &social.PageRequest{})
+		assert.Len(t, result.Items, 1)
+		assert.Equal(t, "public", result.Items[0].TripID)
+	})
+}
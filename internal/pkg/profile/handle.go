@@ -0,0 +1,174 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"speakeasy/pkg"
+	"speakeasy/pkg/database"
+	"strings"
+	"time"
+)
+
+// HANDLE_KEY is the PK and SK of the item which reserves a handle for a user
+var HANDLE_KEY string = "HANDLE#%s"
+
+const handleChangeCooldown = time.Hour * 24 * 30
+
+// handlePattern allows 3 to 30 lowercase letters, digits and underscores starting with a letter
+var handlePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{2,29}$`)
+
+// reservedHandles can't be used as handles, they could be mistaken for the service or routes
+var reservedHandles = map[string]bool{
+	"about":     true,
+	"admin":     true,
+	"api":       true,
+	"help":      true,
+	"login":     true,
+	"me":        true,
+	"moderator": true,
+	"null":      true,
+	"official":  true,
+	"root":      true,
+	"search":    true,
+	"settings":  true,
+	"signup":    true,
+	"speakeasy": true,
+	"staff":     true,
+	"support":   true,
+	"system":    true,
+	"undefined": true,
+}
+
+// handleItem object which reserves a handle, PK and SK are HANDLE_KEY values
+type handleItem struct {
+	PK     string `json:"PK"`
+	SK     string `json:"SK"`
+	UserID string `json:"user_id"`
+}
+
+// SetHandleRequest object which contains the new handle, empty handle removes the current handle
+type SetHandleRequest struct {
+	Handle string `json:"handle"`
+}
+
+// NormalizeHandle lowercases handle and removes the leading @
+func NormalizeHandle(handle string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+}
+
+// ValidateHandle returns 400 error if the normalized handle can't be used
+func ValidateHandle(handle string) *pkg.Error {
+	if len(handle) < 3 || len(handle) > 30 {
+		return &pkg.Error{Code: 400, Reason: "Handle must be between 3 and 30 characters"}
+	}
+
+	if !handlePattern.MatchString(handle) {
+		return &pkg.Error{Code: 400, Reason: "Handle can only contain letters, numbers and underscores and must start with a letter"}
+	}
+
+	if reservedHandles[handle] {
+		return &pkg.Error{Code: 400, Reason: "Handle is reserved"}
+	}
+
+	return nil
+}
+
+// SetHandle function to change the handle of the user's profile. The new handle is reserved
+// and the old handle released in the same transaction, so two users can't get the same handle.
+// Handle can be changed once per cooldown period. Returns 409 if the profile was changed after it was read.
+func (service *_Service) SetHandle(ctx context.Context, userID string, handle string) *pkg.Error {
+	handle = NormalizeHandle(handle)
+	if handle != "" {
+		if err := ValidateHandle(handle); err != nil {
+			return err
+		}
+	}
+
+	input := map[string]string{
+		"PK": fmt.Sprintf(PROFILE_PK, userID),
+		"SK": PROFILE_SK,
+	}
+
+	profile, err := service.db.Get(ctx, input)
+	if err != nil {
+		return pkg.Unavailable("SetHandle", err)
+	}
+
+	if profile == nil {
+		return &pkg.Error{Code: 404, Reason: "Profile not found"}
+	}
+
+	if profile.Handle == handle {
+		return nil
+	}
+
+	now := service.clock.Now().UTC()
+	if profile.HandleChangedAt != nil && now.Sub(*profile.HandleChangedAt) < handleChangeCooldown {
+		return &pkg.Error{Code: 429, Reason: "Handle can only be changed once every 30 days"}
+	}
+
+	// Only the handle fields are written, the condition makes concurrent changes reserve one handle each
+	update := &database.Update{
+		Set:       map[string]interface{}{"handle_changed_at": now, "updated_at": now},
+		Condition: "#updated_at = :updated_at",
+		Names:     map[string]string{"#updated_at": "updated_at"},
+		Values:    map[string]interface{}{":updated_at": profile.UpdatedAt},
+	}
+	setOrRemove(update, "handle", handle)
+
+	items := []database.TransactionItem{{Update: input, Changes: update}}
+
+	if handle != "" {
+		key := fmt.Sprintf(HANDLE_KEY, handle)
+		items = append(items, database.TransactionItem{
+			Put:       &handleItem{PK: key, SK: key, UserID: userID},
+			Condition: "attribute_not_exists(PK)",
+		})
+	}
+
+	if profile.Handle != "" {
+		key := fmt.Sprintf(HANDLE_KEY, profile.Handle)
+		items = append(items, database.TransactionItem{
+			Delete: map[string]string{"PK": key, "SK": key},
+		})
+	}
+
+	err = service.db.Transaction(ctx, items...)
+
+	var conditionErr *database.ConditionError
+	if errors.As(err, &conditionErr) && conditionErr.Index == 0 {
+		return &pkg.Error{Code: 409, Reason: "Profile was changed, please try again"}
+	}
+
+	if errors.Is(err, database.ErrConditionFailed) {
+		return &pkg.Error{Code: 409, Reason: "Handle is already taken"}
+	}
+
+	if err != nil {
+		return pkg.Unavailable("SetHandle", err)
+	}
+
+	return nil
+}
+
+// ResolveHandle function to get the id of the user with the handle
+func (service *_Service) ResolveHandle(ctx context.Context, handle string) (string, *pkg.Error) {
+	key := fmt.Sprintf(HANDLE_KEY, NormalizeHandle(handle))
+	input := map[string]string{
+		"PK": key,
+		"SK": key,
+	}
+
+	item, err := service.db.Get(ctx, input)
+	if err != nil {
+		return "", pkg.Unavailable("ResolveHandle", err)
+	}
+
+	if item == nil {
+		return "", &pkg.Error{Code: 404, Reason: "Profile not found"}
+	}
+
+	return item.UserID, nil
+}
@@ -0,0 +1,146 @@
+package profile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"speakeasy/pkg"
+	"speakeasy/pkg/database"
+)
+
+// patchableFields returns the values of the fields PatchProfile can change keyed by attribute name
+func patchableFields(request *ProfileRequest) map[string]interface{} {
+	return map[string]interface{}{
+		"name":         request.Name,
+		"bio":          request.Bio,
+		"home_city":    request.HomeCity,
+		"languages":    request.Languages,
+		"interests":    request.Interests,
+		"social_links": request.SocialLinks,
+		"phone":        request.Phone,
+		"birth_date":   request.BirthDate,
+		"privacy":      request.Privacy,
+	}
+}
+
+// PatchProfile function to apply JSON Merge Patch (RFC 7396) to the user's profile,
+// only the fields in the patch are written and UpdatedAt is bumped in the same update.
+// Returns 409 if the profile was changed after it was read.
+func (service *_Service) PatchProfile(ctx context.Context, userID string, patch []byte) (*Profile, *pkg.Error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &fields); err != nil || fields == nil {
+		return nil, &pkg.Error{Code: 400, Reason: "Patch must be a JSON object"}
+	}
+
+	validator := &pkg.Validator{}
+	patchable := patchableFields(&ProfileRequest{})
+	for name := range fields {
+		_, ok := patchable[name]
+		validator.Check(ok, name, "Cannot be changed")
+	}
+
+	if err := validator.Error(); err != nil {
+		return nil, err
+	}
+
+	key := map[string]string{
+		"PK": fmt.Sprintf(PROFILE_PK, userID),
+		"SK": PROFILE_SK,
+	}
+
+	current, err := service.db.Get(ctx, key)
+	if err != nil {
+		return nil, pkg.Unavailable("PatchProfile", err)
+	}
+
+	if current == nil {
+		return nil, &pkg.Error{Code: 404, Reason: "Profile not found"}
+	}
+
+	if len(fields) == 0 {
+		return service.GetProfile(ctx, userID)
+	}
+
+	// Merge the fields the user can change in memory to validate the whole result, only the patched fields are written
+	document, err := json.Marshal(current.request())
+	if err != nil {
+		return nil, pkg.Internal("PatchProfile", err)
+	}
+
+	merged, err := pkg.MergePatch(document, patch)
+	if err != nil {
+		return nil, &pkg.Error{Code: 400, Reason: "Patch must be a JSON object"}
+	}
+
+	var request ProfileRequest
+	if err := json.Unmarshal(merged, &request); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, &pkg.Error{Code: 400, Type: pkg.TypeValidationFailed, Reason: "Validation failed", Details: map[string]string{typeErr.Field: "Has invalid type"}}
+		}
+
+		return nil, &pkg.Error{Code: 400, Reason: "Patch is invalid"}
+	}
+
+	if err := pkg.Validate(&request); err != nil {
+		return nil, err
+	}
+
+	patched := *current
+	request.apply(&patched)
+
+	now := service.clock.Now().UTC()
+	if err := validateProfile(&patched, now); err != nil {
+		return nil, err
+	}
+
+	update := &database.Update{
+		Set:       map[string]interface{}{"updated_at": now},
+		Condition: "#updated_at = :updated_at",
+		Names:     map[string]string{"#updated_at": "updated_at"},
+		Values:    map[string]interface{}{":updated_at": current.UpdatedAt},
+	}
+
+	values := patchableFields(&request)
+	for name := range fields {
+		setOrRemove(update, name, values[name])
+	}
+
+	if _, ok := fields["name"]; ok {
+		setSearchFields(&patched)
+		setOrRemove(update, "normalized_name", patched.NormalizedName)
+		setOrRemove(update, "GSI2PK", patched.GSI2PK)
+		setOrRemove(update, "GSI2SK", patched.GSI2SK)
+	}
+
+	profile, err := service.db.Update(ctx, key, update)
+	if errors.Is(err, database.ErrConditionFailed) {
+		return nil, &pkg.Error{Code: 409, Reason: "Profile was changed, please try again"}
+	}
+
+	if err != nil {
+		return nil, pkg.Unavailable("PatchProfile", err)
+	}
+
+	service.setPictureUrls(profile)
+
+	return profile, nil
+}
+
+// setOrRemove adds the attribute to the update, empty values are removed like they are omitted by Write
+func setOrRemove(update *database.Update, name string, value interface{}) {
+	v := reflect.ValueOf(value)
+	empty := !v.IsValid() || v.IsZero()
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Map {
+		empty = v.Len() == 0
+	}
+
+	if empty {
+		update.Remove = append(update.Remove, name)
+		return
+	}
+
+	update.Set[name] = value
+}
@@ -0,0 +1,128 @@
+package profile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"speakeasy/pkg"
+	"speakeasy/pkg/database"
+	"speakeasy/pkg/imaging"
+	"strconv"
+)
+
+// PROFILE_PIC_KEY is the storage key of a profile picture size: user id, version and size
+var PROFILE_PIC_KEY string = "%s/%s/%d.jpg"
+
+// ProfilePictureSizes are the square sizes generated for each profile picture
+var ProfilePictureSizes = []int{64, 256, 1024}
+
+const (
+	defaultProfilePictureSize = 256
+
+	// MaxProfilePictureSize is the maximum size of an uploaded profile picture in bytes
+	MaxProfilePictureSize = 10 << 20
+)
+
+// UploadProfilePicture function to validate the uploaded image and store it as square JPEGs in
+// ProfilePictureSizes. Every upload is stored under a new version so cached pictures are never stale,
+// re-encoding the image strips EXIF data. The files of the previous version are deleted once the profile
+// points to the new one, returns 409 if the profile was changed while the picture was processed.
+func (service *_Service) UploadProfilePicture(ctx context.Context, userID string, file io.Reader) (*Profile, *pkg.Error) {
+	data, err := io.ReadAll(io.LimitReader(file, MaxProfilePictureSize+1))
+	if err != nil {
+		return nil, &pkg.Error{Code: 400, Reason: "Profile picture can't be read", Err: err}
+	}
+
+	if len(data) > MaxProfilePictureSize {
+		return nil, &pkg.Error{Code: 413, Reason: "Profile picture is too large"}
+	}
+
+	img, _, err := imaging.Decode(data)
+	if errors.Is(err, imaging.ErrUnsupportedFormat) {
+		return nil, &pkg.Error{Code: 415, Reason: "Profile picture must be a JPEG, PNG or WebP image"}
+	}
+
+	if errors.Is(err, imaging.ErrTooManyPixels) {
+		return nil, &pkg.Error{Code: 413, Reason: "Profile picture dimensions are too large"}
+	}
+
+	if err != nil {
+		return nil, &pkg.Error{Code: 400, Reason: "Profile picture can't be decoded", Err: err}
+	}
+
+	version := service.ids.NewID()
+	keys := map[string]string{}
+	for _, size := range ProfilePictureSizes {
+		encoded, err := imaging.EncodeJPEG(imaging.Square(img, size))
+		if err != nil {
+			return nil, pkg.Internal("UploadProfilePicture", err)
+		}
+
+		key := fmt.Sprintf(PROFILE_PIC_KEY, userID, version, size)
+		if err := service.storage.UploadFile(ctx, key, bytes.NewReader(encoded), imaging.ContentTypeJPEG); err != nil {
+			service.deletePictures(ctx, keys)
+			return nil, pkg.Unavailable("UploadProfilePicture", err)
+		}
+
+		keys[strconv.Itoa(size)] = key
+	}
+
+	input := map[string]string{
+		"PK": fmt.Sprintf(PROFILE_PK, userID),
+		"SK": PROFILE_SK,
+	}
+
+	current, err := service.db.Get(ctx, input)
+	if err != nil {
+		service.deletePictures(ctx, keys)
+		return nil, pkg.Unavailable("UploadProfilePicture", err)
+	}
+
+	// Only the picture fields are written, the condition makes sure the previous version read here
+	// is the one replaced so its files can be deleted
+	update := &database.Update{
+		Set: map[string]interface{}{
+			"user_id":          userID,
+			"profile_pic_keys": keys,
+			"updated_at":       service.clock.Now().UTC(),
+		},
+		Condition: "attribute_not_exists(PK)",
+	}
+
+	if current != nil {
+		update.Condition = "#updated_at = :updated_at"
+		update.Names = map[string]string{"#updated_at": "updated_at"}
+		update.Values = map[string]interface{}{":updated_at": current.UpdatedAt}
+	}
+
+	profile, err := service.db.Update(ctx, input, update)
+	if errors.Is(err, database.ErrConditionFailed) {
+		service.deletePictures(ctx, keys)
+		return nil, &pkg.Error{Code: 409, Reason: "Profile was changed, please try again"}
+	}
+
+	if err != nil {
+		service.deletePictures(ctx, keys)
+		return nil, pkg.Unavailable("UploadProfilePicture", err)
+	}
+
+	if current != nil {
+		service.deletePictures(ctx, current.ProfilePicKeys)
+	}
+
+	service.setPictureUrls(profile)
+
+	return profile, nil
+}
+
+// deletePictures deletes the files of a profile picture version, errors are only logged
+// because the profile doesn't point to the files anymore
+func (service *_Service) deletePictures(ctx context.Context, keys map[string]string) {
+	for _, key := range keys {
+		if err := service.storage.DeleteFile(ctx, key); err != nil {
+			service.logger.Warn("delete profile picture failed", "key", key, "error", err.Error())
+		}
+	}
+}
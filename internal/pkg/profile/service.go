@@ -1,19 +1,76 @@
 package profile
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
-	"mime/multipart"
+	"io"
+	"log/slog"
 	"speakeasy/pkg"
+	"speakeasy/pkg/clock"
 	"speakeasy/pkg/database"
 	"speakeasy/pkg/filestorage"
+	"speakeasy/pkg/ids"
+	"strconv"
 	"time"
+	"unicode/utf8"
 )
 
 var PROFILE_PK string = "USER#%s"
 var PROFILE_SK string = "__PROFILE__"
 
-// Profile object to store in database.
+// PROFILE_SEARCH_PK is the APPLICATION_GSI_2 partition key of profiles,
+// profiles are partitioned by the first letter of their normalized name.
+var PROFILE_SEARCH_PK string = "PROFILE#%s"
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// Audience of profile information
+type Audience string
+
+const (
+	AudienceEveryone Audience = "everyone"
+	AudienceFriends  Audience = "friends"
+	AudienceOnlyMe   Audience = "only_me"
+)
+
+// Valid returns true if audience is empty or one of the known audiences
+func (audience Audience) Valid() bool {
+	return audience == "" || audience == AudienceEveryone || audience == AudienceFriends || audience == AudienceOnlyMe
+}
+
+// Allows returns true if a viewer with the relation is part of the audience, empty audience is everyone
+func (audience Audience) Allows(relation Relation) bool {
+	switch audience {
+	case AudienceFriends:
+		return relation >= RelationFriend
+	case AudienceOnlyMe:
+		return relation == RelationSelf
+	default:
+		return true
+	}
+}
+
+// Relation of a viewer to the profile owner
+type Relation int
+
+const (
+	RelationStranger Relation = iota
+	RelationFriend
+	RelationSelf
+)
+
+// Privacy object which contains who can see profile information
+type Privacy struct {
+	Bio         Audience `json:"bio,omitempty" binding:"omitempty,oneof=everyone friends only_me"`
+	Picture     Audience `json:"picture,omitempty" binding:"omitempty,oneof=everyone friends only_me"`
+	TripHistory Audience `json:"trip_history,omitempty" binding:"omitempty,oneof=everyone friends only_me"`
+}
+
+// Profile object to store in database, it is never sent to clients, see ProfileResponse.
 // PK (Primary Key) should be in the format of PROFILE_PK value,
 // SK (Sort Key) should be PROFILE_SK value.
 type Profile struct {
@@ -23,77 +80,444 @@ type Profile struct {
 	UserID        string    `json:"user_id"`
 	Name          string    `json:"name"`
 	Bio           string    `json:"bio"`
-	ProfilePicUrl string    `json:"profile_pic_url,omitempty"`
+	HomeCity      string    `json:"home_city,omitempty"`
+	ProfilePicUrl string    `json:"profile_pic_url,omitempty" dynamodbav:"-"`
+	Privacy       Privacy   `json:"privacy"`
+
+	// Languages and Interests are shown on the profile, SocialLinks contains url of each social network keyed by name
+	Languages   []string          `json:"languages,omitempty"`
+	Interests   []string          `json:"interests,omitempty"`
+	SocialLinks map[string]string `json:"social_links,omitempty"`
+
+	// Phone in E.164 format and BirthDate in YYYY-MM-DD format are only visible to the user
+	Phone     string `json:"phone,omitempty"`
+	BirthDate string `json:"birth_date,omitempty"`
+
+	// ProfilePicKeys contains the storage key of each profile picture size keyed by size,
+	// it can only be changed with UploadProfilePicture.
+	ProfilePicKeys map[string]string `json:"-" dynamodbav:"profile_pic_keys,omitempty"`
+
+	// ProfilePicUrls contains a short-lived url of each profile picture size keyed by size,
+	// ProfilePicUrl is the default size. Both are signed from ProfilePicKeys when the profile is read.
+	ProfilePicUrls map[string]string `json:"profile_pic_urls,omitempty" dynamodbav:"-"`
+
+	// Handle is unique, it can only be changed with SetHandle
+	Handle          string     `json:"handle,omitempty"`
+	HandleChangedAt *time.Time `json:"handle_changed_at,omitempty"`
+
+	// NormalizedName, GSI2PK and GSI2SK are used to search profiles by name prefix
+	NormalizedName string `json:"-" dynamodbav:"normalized_name,omitempty"`
+	GSI2PK         string `json:"-" dynamodbav:"GSI2PK,omitempty"`
+	GSI2SK         string `json:"-" dynamodbav:"GSI2SK,omitempty"`
+}
+
+// ProfileRequest object which is the request to create or patch a profile,
+// it only contains the fields the user can change
+type ProfileRequest struct {
+	Name        string            `json:"name" binding:"max=100"`
+	Bio         string            `json:"bio" binding:"max=500"`
+	HomeCity    string            `json:"home_city,omitempty" binding:"max=100"`
+	Languages   []string          `json:"languages,omitempty" binding:"max=20,dive,notblank,max=50"`
+	Interests   []string          `json:"interests,omitempty" binding:"max=20,dive,notblank,max=50"`
+	SocialLinks map[string]string `json:"social_links,omitempty" binding:"max=10,dive,keys,notblank,max=30,endkeys,http_url,max=200"`
+	Phone       string            `json:"phone,omitempty" binding:"omitempty,phone"`
+	BirthDate   string            `json:"birth_date,omitempty" binding:"omitempty,date"`
+	Privacy     Privacy           `json:"privacy"`
+}
+
+// Profile returns profile of the user with the requested fields
+func (request *ProfileRequest) Profile(userID string) *Profile {
+	profile := &Profile{UserID: userID}
+	request.apply(profile)
+
+	return profile
+}
+
+// apply sets the requested fields on the profile
+func (request *ProfileRequest) apply(profile *Profile) {
+	profile.Name = request.Name
+	profile.Bio = request.Bio
+	profile.HomeCity = request.HomeCity
+	profile.Languages = request.Languages
+	profile.Interests = request.Interests
+	profile.SocialLinks = request.SocialLinks
+	profile.Phone = request.Phone
+	profile.BirthDate = request.BirthDate
+	profile.Privacy = request.Privacy
+}
+
+// request returns the fields of the profile the user can change
+func (profile *Profile) request() *ProfileRequest {
+	return &ProfileRequest{
+		Name:        profile.Name,
+		Bio:         profile.Bio,
+		HomeCity:    profile.HomeCity,
+		Languages:   profile.Languages,
+		Interests:   profile.Interests,
+		SocialLinks: profile.SocialLinks,
+		Phone:       profile.Phone,
+		BirthDate:   profile.BirthDate,
+		Privacy:     profile.Privacy,
+	}
+}
+
+// ProfileResponse object which contains the profile fields sent to the user
+type ProfileResponse struct {
+	UserID          string            `json:"user_id"`
+	Handle          string            `json:"handle,omitempty"`
+	HandleChangedAt *time.Time        `json:"handle_changed_at,omitempty"`
+	Name            string            `json:"name"`
+	Bio             string            `json:"bio"`
+	HomeCity        string            `json:"home_city,omitempty"`
+	ProfilePicUrl   string            `json:"profile_pic_url,omitempty"`
+	ProfilePicUrls  map[string]string `json:"profile_pic_urls,omitempty"`
+	Languages       []string          `json:"languages,omitempty"`
+	Interests       []string          `json:"interests,omitempty"`
+	SocialLinks     map[string]string `json:"social_links,omitempty"`
+	Phone           string            `json:"phone,omitempty"`
+	BirthDate       string            `json:"birth_date,omitempty"`
+	Privacy         Privacy           `json:"privacy"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// Response returns profile as sent to the user
+func (profile *Profile) Response() *ProfileResponse {
+	return &ProfileResponse{
+		UserID:          profile.UserID,
+		Handle:          profile.Handle,
+		HandleChangedAt: profile.HandleChangedAt,
+		Name:            profile.Name,
+		Bio:             profile.Bio,
+		HomeCity:        profile.HomeCity,
+		ProfilePicUrl:   profile.ProfilePicUrl,
+		ProfilePicUrls:  profile.ProfilePicUrls,
+		Languages:       profile.Languages,
+		Interests:       profile.Interests,
+		SocialLinks:     profile.SocialLinks,
+		Phone:           profile.Phone,
+		BirthDate:       profile.BirthDate,
+		Privacy:         profile.Privacy,
+		UpdatedAt:       profile.UpdatedAt,
+	}
+}
+
+// PublicProfile object which contains the profile fields other users can see
+type PublicProfile struct {
+	UserID        string            `json:"user_id"`
+	Handle        string            `json:"handle,omitempty"`
+	Name          string            `json:"name"`
+	Bio           string            `json:"bio,omitempty"`
+	ProfilePicUrl string            `json:"profile_pic_url,omitempty"`
+	HomeCity      string            `json:"home_city,omitempty"`
+	Languages     []string          `json:"languages,omitempty"`
+	Interests     []string          `json:"interests,omitempty"`
+	SocialLinks   map[string]string `json:"social_links,omitempty"`
+}
+
+// SearchFilter object which contains profile search query parameters
+type SearchFilter struct {
+	Query  string `form:"q"`
+	Cursor string `form:"cursor"`
+	Limit  int64  `form:"limit" binding:"min=0"`
+}
+
+// ForViewer returns copy of profile without the information hidden from a viewer with the relation
+func (profile *Profile) ForViewer(relation Relation) *Profile {
+	out := *profile
+
+	if relation != RelationSelf {
+		out.Phone = ""
+		out.BirthDate = ""
+	}
+
+	if !profile.Privacy.Bio.Allows(relation) {
+		out.Bio = ""
+	}
+
+	if !profile.Privacy.Picture.Allows(relation) {
+		out.ProfilePicUrl = ""
+		out.ProfilePicUrls = nil
+		out.ProfilePicKeys = nil
+	}
+
+	return &out
+}
+
+// Public returns public projection of profile as seen by a viewer with the relation
+func (profile *Profile) Public(relation Relation) *PublicProfile {
+	visible := profile.ForViewer(relation)
+
+	return &PublicProfile{
+		UserID:        visible.UserID,
+		Handle:        visible.Handle,
+		Name:          visible.Name,
+		Bio:           visible.Bio,
+		ProfilePicUrl: visible.ProfilePicUrl,
+		HomeCity:      visible.HomeCity,
+		Languages:     visible.Languages,
+		Interests:     visible.Interests,
+		SocialLinks:   visible.SocialLinks,
+	}
+}
+
+// Summary object which contains the profile fields shown next to content created by the user
+type Summary struct {
+	UserID        string `json:"user_id"`
+	Name          string `json:"name"`
+	ProfilePicUrl string `json:"profile_pic_url,omitempty"`
 }
 
 type _Service struct {
 	db      database.Service[Profile]
 	storage filestorage.Service
+	clock   clock.Clock
+	ids     ids.Generator
+	logger  *slog.Logger
 }
 
 type Service interface {
-	PutProfile(profile *Profile) *pkg.Error
-	GetProfile(id string) (*Profile, *pkg.Error)
-	UploadProfilePicture(userID string, file multipart.File) error
+	PutProfile(ctx context.Context, profile *Profile) *pkg.Error
+	PatchProfile(ctx context.Context, userID string, patch []byte) (*Profile, *pkg.Error)
+	GetProfile(ctx context.Context, id string) (*Profile, *pkg.Error)
+	ProfileExists(ctx context.Context, userID string) (bool, *pkg.Error)
+	GetPublicProfile(ctx context.Context, userID string, relation Relation) (*PublicProfile, *pkg.Error)
+	GetProfileSummaries(ctx context.Context, userIDs []string) (map[string]Summary, *pkg.Error)
+	SearchProfiles(ctx context.Context, filter *SearchFilter) (*database.Page[PublicProfile], *pkg.Error)
+	UploadProfilePicture(ctx context.Context, userID string, file io.Reader) (*Profile, *pkg.Error)
+	SetHandle(ctx context.Context, userID string, handle string) *pkg.Error
+	ResolveHandle(ctx context.Context, handle string) (string, *pkg.Error)
 }
 
-// NewProfileService initializes database and returns Service object
-func NewProfileService() Service {
-	db := database.NewDatabaseService[Profile]("APPLICATION")
-	storage := filestorage.NewFileStorageService("profile.image.amuel.org")
-
-	return &_Service{db, storage}
+// NewProfileService returns Service object which stores profiles in db and profile pictures in storage
+func NewProfileService(db database.Service[Profile], storage filestorage.Service, clock clock.Clock, ids ids.Generator, logger *slog.Logger) Service {
+	return &_Service{db, storage, clock, ids, logger}
 }
 
-// PutProfile function to configure db keys and update information.
-func (service *_Service) PutProfile(profile *Profile) *pkg.Error {
-	profile.PK = fmt.Sprintf(PROFILE_PK, profile.UserID)
-	profile.SK = PROFILE_SK
+// PutProfile function to configure db keys and update information,
+// returns 400 with the reason of each invalid field if the profile is invalid.
+func (service *_Service) PutProfile(ctx context.Context, profile *Profile) *pkg.Error {
+	if err := validateProfile(profile, service.clock.Now().UTC()); err != nil {
+		return err
+	}
+
+	key := map[string]string{
+		"PK": fmt.Sprintf(PROFILE_PK, profile.UserID),
+		"SK": PROFILE_SK,
+	}
+
+	// Only the fields the user can change are written, so the reserved handle and the uploaded picture
+	// changed with SetHandle and UploadProfilePicture are kept without reading the profile first
+	update := &database.Update{
+		Set: map[string]interface{}{
+			"user_id":    profile.UserID,
+			"updated_at": service.clock.Now().UTC(),
+		},
+	}
 
-	profile.UpdatedAt = time.Now().UTC()
+	for name, value := range patchableFields(profile.request()) {
+		setOrRemove(update, name, value)
+	}
+
+	setSearchFields(profile)
+	setOrRemove(update, "normalized_name", profile.NormalizedName)
+	setOrRemove(update, "GSI2PK", profile.GSI2PK)
+	setOrRemove(update, "GSI2SK", profile.GSI2SK)
 
-	err := service.db.Write(profile)
+	updated, err := service.db.Update(ctx, key, update)
 	if err != nil {
-		log.Printf("(PutProfile) error: %s", err)
-		return &pkg.Error{Code: 503, Reason: "Internal Server Error"}
+		return pkg.Unavailable("PutProfile", err)
 	}
 
+	*profile = *updated
+
 	return nil
 }
 
 // GetProfile function to configure db keys and update information.
-func (service *_Service) GetProfile(userID string) (*Profile, *pkg.Error) {
+func (service *_Service) GetProfile(ctx context.Context, userID string) (*Profile, *pkg.Error) {
 	input := map[string]string{
 		"PK": fmt.Sprintf(PROFILE_PK, userID),
 		"SK": PROFILE_SK,
 	}
 
-	profile, err := service.db.Get(input)
+	profile, err := service.db.Get(ctx, input)
 	if err != nil {
-		log.Println("(GetProfile) error:", err)
-		return nil, &pkg.Error{Code: 503, Reason: "Internal Server Error"}
+		return nil, pkg.Unavailable("GetProfile", err)
 	}
 
 	if profile == nil {
-		log.Println("(GetProfile) error: item not found")
+		service.logger.Debug("profile not found", "user_id", userID)
 		return &Profile{UserID: userID}, nil
 	}
 
-	removePrivateFieldsFromJSON(profile)
-
-	// TODO: add profile pic url based on environment
+	service.setPictureUrls(profile)
 
 	return profile, nil
 }
 
-// UploadProfilePicture function to upload file with userID as name.
-// Profile picture can be seen by anyone, it's ok to use userID as name
-func (service *_Service) UploadProfilePicture(userID string, file multipart.File) error {
-	return service.storage.UploadFile(userID, &file)
+// ProfileExists function to check the user has a profile, every user gets a profile at signup
+func (service *_Service) ProfileExists(ctx context.Context, userID string) (bool, *pkg.Error) {
+	input := map[string]string{
+		"PK": fmt.Sprintf(PROFILE_PK, userID),
+		"SK": PROFILE_SK,
+	}
+
+	profile, err := service.db.Get(ctx, input)
+	if err != nil {
+		return false, pkg.Unavailable("ProfileExists", err)
+	}
+
+	return profile != nil, nil
+}
+
+// GetPublicProfile function to get public projection of user's profile, returns 404 if the user has no profile
+func (service *_Service) GetPublicProfile(ctx context.Context, userID string, relation Relation) (*PublicProfile, *pkg.Error) {
+	input := map[string]string{
+		"PK": fmt.Sprintf(PROFILE_PK, userID),
+		"SK": PROFILE_SK,
+	}
+
+	profile, err := service.db.Get(ctx, input)
+	if err != nil {
+		return nil, pkg.Unavailable("GetPublicProfile", err)
+	}
+
+	if profile == nil {
+		return nil, &pkg.Error{Code: 404, Reason: "Profile not found"}
+	}
+
+	visible := profile.ForViewer(relation)
+	service.setPictureUrls(visible)
+
+	return visible.Public(relation), nil
+}
+
+// GetProfileSummaries function to get summaries of multiple profiles keyed by user id,
+// users without a profile are not included and pictures follow privacy settings.
+func (service *_Service) GetProfileSummaries(ctx context.Context, userIDs []string) (map[string]Summary, *pkg.Error) {
+	summaries := map[string]Summary{}
+
+	keys := []interface{}{}
+	seen := map[string]bool{}
+	for _, userID := range userIDs {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		keys = append(keys, map[string]string{
+			"PK": fmt.Sprintf(PROFILE_PK, userID),
+			"SK": PROFILE_SK,
+		})
+	}
+
+	if len(keys) == 0 {
+		return summaries, nil
+	}
+
+	profiles, err := service.db.BatchGet(ctx, keys...)
+	if err != nil {
+		return nil, pkg.Unavailable("GetProfileSummaries", err)
+	}
+
+	// Summaries can be shown to anyone, only include what strangers can see
+	for _, profile := range *profiles {
+		visible := profile.ForViewer(RelationStranger)
+		service.setPictureUrls(visible)
+		summaries[profile.UserID] = Summary{
+			UserID:        visible.UserID,
+			Name:          visible.Name,
+			ProfilePicUrl: visible.ProfilePicUrl,
+		}
+	}
+
+	return summaries, nil
+}
+
+// SearchProfiles function to search profiles by name prefix sorted by name,
+// results only include what strangers can see.
+func (service *_Service) SearchProfiles(ctx context.Context, filter *SearchFilter) (*database.Page[PublicProfile], *pkg.Error) {
+	if filter.Limit < 0 || filter.Limit > maxSearchLimit {
+		return nil, &pkg.Error{Code: 400, Reason: "Limit is invalid"}
+	}
+
+	query := pkg.NormalizeText(filter.Query)
+	if query == "" {
+		return nil, &pkg.Error{Code: 400, Reason: "Query is required"}
+	}
+
+	options := &database.QueryOptions{
+		Index:  "APPLICATION_GSI_2",
+		Limit:  filter.Limit,
+		Cursor: filter.Cursor,
+	}
+
+	if options.Limit == 0 {
+		options.Limit = defaultSearchLimit
+	}
+
+	values := map[string]string{
+		":PK":    searchPartition(query),
+		":query": query,
+	}
+	condition := "GSI2PK = :PK And begins_with(GSI2SK, :query)"
+
+	page, err := service.db.QueryPage(ctx, values, condition, options)
+	if errors.Is(err, database.ErrInvalidCursor) {
+		return nil, &pkg.Error{Code: 400, Reason: "Cursor is invalid"}
+	}
+
+	if err != nil {
+		return nil, pkg.Unavailable("SearchProfiles", err)
+	}
+
+	result := &database.Page[PublicProfile]{Items: []PublicProfile{}, Cursor: page.Cursor}
+	for _, profile := range page.Items {
+		visible := profile.ForViewer(RelationStranger)
+		service.setPictureUrls(visible)
+		result.Items = append(result.Items, *visible.Public(RelationStranger))
+	}
+
+	return result, nil
+}
+
+// setPictureUrls signs short-lived urls for the stored profile picture keys,
+// sizes which can't be signed are left out and logged.
+func (service *_Service) setPictureUrls(profile *Profile) {
+	if len(profile.ProfilePicKeys) == 0 {
+		return
+	}
+
+	profile.ProfilePicUrls = map[string]string{}
+	for size, key := range profile.ProfilePicKeys {
+		url, err := service.storage.GetDownloadUrl(key)
+		if err != nil {
+			service.logger.Warn("sign profile picture url failed", "user_id", profile.UserID, "size", size, "error", err.Error())
+			continue
+		}
+
+		profile.ProfilePicUrls[size] = url
+	}
+
+	profile.ProfilePicUrl = profile.ProfilePicUrls[strconv.Itoa(defaultProfilePictureSize)]
+}
+
+// setSearchFields adds profile to APPLICATION_GSI_2 by normalized name, profiles without a name are not indexed
+func setSearchFields(profile *Profile) {
+	profile.NormalizedName = pkg.NormalizeText(profile.Name)
+
+	if profile.NormalizedName == "" {
+		profile.GSI2PK = ""
+		profile.GSI2SK = ""
+		return
+	}
+
+	profile.GSI2PK = searchPartition(profile.NormalizedName)
+	profile.GSI2SK = profile.NormalizedName
 }
 
-// removePrivateFieldsFromJSON sets keys to empty and removes from being serialized
-func removePrivateFieldsFromJSON(profile *Profile) {
-	profile.PK = ""
-	profile.SK = ""
+// searchPartition returns APPLICATION_GSI_2 partition key of the normalized name
+func searchPartition(normalizedName string) string {
+	first, _ := utf8.DecodeRuneInString(normalizedName)
+	return fmt.Sprintf(PROFILE_SEARCH_PK, string(first))
 }
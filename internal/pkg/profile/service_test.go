@@ -1 +1,814 @@
 package profile
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"sort"
+	"speakeasy/pkg"
+	"speakeasy/pkg/clock"
+	"speakeasy/pkg/database"
+	"speakeasy/pkg/filestorage"
+	"speakeasy/pkg/ids"
+	"speakeasy/pkg/logging"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/stretchr/testify/assert"
+)
+
+// testLogger drops the log records of the services under test
+var testLogger = logging.Discard()
+
+// TestMain registers the custom binding tags PatchProfile validates the merged request with
+func TestMain(m *testing.M) {
+	if err := pkg.RegisterValidators(); err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+// testClock and testIDs are the real clock and uuid generator, tests which check times or ids set fixed ones
+var (
+	testClock = clock.System()
+	testIDs   = ids.UUID()
+)
+
+// Mock DatabaseService where items exist
+type _DatabaseServiceMockItemsExist struct {
+	database.Service[Profile]
+	keys []interface{}
+}
+
+func (db *_DatabaseServiceMockItemsExist) BatchGet(ctx context.Context, keyObjs ...interface{}) (*[]Profile, error) {
+	db.keys = keyObjs
+	return &[]Profile{
+		{UserID: "0000", Name: "user.name", Bio: "user.bio", ProfilePicKeys: map[string]string{"256": "0000/1/256.jpg"}},
+	}, nil
+}
+
+func (db *_DatabaseServiceMockItemsExist) Get(ctx context.Context, keyObj interface{}) (*Profile, error) {
+	db.keys = []interface{}{keyObj}
+	return &Profile{
+		UserID:  "0000",
+		Name:    "user.name",
+		Bio:     "user.bio",
+		Privacy: Privacy{Bio: AudienceFriends},
+	}, nil
+}
+
+// Mock DatabaseService which records search queries
+type _DatabaseServiceMockQueryPage struct {
+	database.Service[Profile]
+	values    map[string]string
+	condition string
+	options   *database.QueryOptions
+}
+
+func (db *_DatabaseServiceMockQueryPage) QueryPage(ctx context.Context, filterObj interface{}, condition string, options *database.QueryOptions) (*database.Page[Profile], error) {
+	db.values = filterObj.(map[string]string)
+	db.condition = condition
+	db.options = options
+	return &database.Page[Profile]{
+		Items: []Profile{
+			{PK: "USER#0000", UserID: "0000", Name: "Jane Doe", Bio: "user.bio", Privacy: Privacy{Bio: AudienceOnlyMe}},
+		},
+		Cursor: "next",
+	}, nil
+}
+
+// Mock DatabaseService where .Get returns no item
+type _DatabaseServiceMockItemNotFound struct {
+	database.Service[Profile]
+}
+
+func (db *_DatabaseServiceMockItemNotFound) Get(ctx context.Context, keyObj interface{}) (*Profile, error) {
+	return nil, nil
+}
+
+// Mock DatabaseService where .BatchGet returns an error
+type _DatabaseServiceMockGetError struct {
+	database.Service[Profile]
+}
+
+func (db *_DatabaseServiceMockGetError) Get(ctx context.Context, keyObj interface{}) (*Profile, error) {
+	return nil, errors.New("ERROR")
+}
+
+func (db *_DatabaseServiceMockGetError) QueryPage(ctx context.Context, filterObj interface{}, condition string, options *database.QueryOptions) (*database.Page[Profile], error) {
+	return nil, errors.New("ERROR")
+}
+
+func (db *_DatabaseServiceMockGetError) BatchGet(ctx context.Context, keyObjs ...interface{}) (*[]Profile, error) {
+	return nil, errors.New("ERROR")
+}
+
+func TestGetProfileSummaries(t *testing.T) {
+	t.Run("SUCCESS: RETURN SUMMARIES KEYED BY USER ID", func(t *testing.T) {
+		db := &_DatabaseServiceMockItemsExist{}
+		svc := &_Service{db: db, storage: &_StorageServiceMock{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.GetProfileSummaries(context.Background(), []string{"0000", "0000", "1111"})
+
+		assert.Empty(t, err, "Error should be empty")
+		assert.Len(t, db.keys, 2, "Duplicate user ids should be read once")
+		assert.Equal(t, Summary{UserID: "0000", Name: "user.name", ProfilePicUrl: "https://storage/0000/1/256.jpg?signature=test"}, result["0000"])
+		assert.NotContains(t, result, "1111")
+	})
+
+	t.Run("SUCCESS: RETURN EMPTY SUMMARIES WITHOUT READING DB", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockGetError{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.GetProfileSummaries(context.Background(), []string{})
+
+		assert.Empty(t, err, "Error should be empty")
+		assert.Empty(t, result, "Result should be empty")
+	})
+
+	t.Run("ERROR: RETURN 503 WHEN DB READ RETURNS ERROR", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockGetError{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.GetProfileSummaries(context.Background(), []string{"0000"})
+
+		assert.Equal(t, 503, err.Code, "Error should be 503")
+		assert.Empty(t, result, "Result should be empty")
+	})
+}
+
+func TestForViewer(t *testing.T) {
+	profile := &Profile{
+		UserID:         "0000",
+		Bio:            "user.bio",
+		ProfilePicUrl:  "user.picture",
+		ProfilePicKeys: map[string]string{"256": "user.picture.key"},
+		Phone:          "+14155550100",
+		BirthDate:      "1990-01-31",
+		Privacy:        Privacy{Bio: AudienceFriends, Picture: AudienceOnlyMe},
+	}
+
+	t.Run("SUCCESS: HIDE FIELDS FROM STRANGERS", func(t *testing.T) {
+		result := profile.ForViewer(RelationStranger)
+
+		assert.Empty(t, result.Bio, "Bio should be hidden")
+		assert.Empty(t, result.ProfilePicUrl, "Picture should be hidden")
+		assert.Empty(t, result.ProfilePicKeys, "Picture keys should be hidden")
+		assert.Equal(t, "user.bio", profile.Bio, "Original profile should not change")
+	})
+
+	t.Run("SUCCESS: SHOW FIELDS TO FRIENDS", func(t *testing.T) {
+		result := profile.ForViewer(RelationFriend)
+
+		assert.Equal(t, "user.bio", result.Bio)
+		assert.Empty(t, result.ProfilePicUrl, "Picture should be hidden")
+		assert.Empty(t, result.Phone, "Phone should only be visible to self")
+		assert.Empty(t, result.BirthDate, "Birth date should only be visible to self")
+	})
+
+	t.Run("SUCCESS: SHOW ALL FIELDS TO SELF", func(t *testing.T) {
+		result := profile.ForViewer(RelationSelf)
+
+		assert.Equal(t, profile, result)
+	})
+}
+
+func TestProfileResponse(t *testing.T) {
+	t.Run("SUCCESS: REQUEST ONLY SETS FIELDS THE USER CAN CHANGE", func(t *testing.T) {
+		request := &ProfileRequest{Name: "Jane Doe", Languages: []string{"English"}, Privacy: Privacy{Bio: AudienceFriends}}
+
+		result := request.Profile("0000")
+
+		assert.Equal(t, &Profile{UserID: "0000", Name: "Jane Doe", Languages: []string{"English"}, Privacy: Privacy{Bio: AudienceFriends}}, result)
+	})
+
+	t.Run("SUCCESS: RESPONSE DOES NOT CONTAIN STORAGE FIELDS", func(t *testing.T) {
+		profile := &Profile{PK: "USER#0000", SK: PROFILE_SK, UserID: "0000", Name: "Jane Doe", ProfilePicKeys: map[string]string{"256": "picture.key"}, GSI2PK: "PROFILE#j"}
+
+		body, _ := json.Marshal(profile.Response())
+
+		assert.Contains(t, string(body), `"user_id":"0000"`)
+		for _, field := range []string{"PK", "SK", PROFILE_SK, "picture.key", "PROFILE#j"} {
+			assert.NotContains(t, string(body), field)
+		}
+	})
+}
+
+func TestProfileExists(t *testing.T) {
+	t.Run("SUCCESS: RETURN TRUE WHEN PROFILE EXISTS", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockItemsExist{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		exists, err := svc.ProfileExists(context.Background(), "0000")
+
+		assert.Empty(t, err, "Error should be empty")
+		assert.True(t, exists)
+	})
+
+	t.Run("SUCCESS: RETURN FALSE WHEN PROFILE DOES NOT EXIST", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockItemNotFound{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		exists, err := svc.ProfileExists(context.Background(), "0000")
+
+		assert.Empty(t, err, "Error should be empty")
+		assert.False(t, exists)
+	})
+}
+
+func TestGetPublicProfile(t *testing.T) {
+	t.Run("SUCCESS: RETURN PUBLIC PROFILE FOR STRANGER", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockItemsExist{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.GetPublicProfile(context.Background(), "0000", RelationStranger)
+
+		assert.Empty(t, err, "Error should be empty")
+		assert.Equal(t, &PublicProfile{UserID: "0000", Name: "user.name"}, result)
+	})
+
+	t.Run("SUCCESS: RETURN PUBLIC PROFILE FOR FRIEND", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockItemsExist{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.GetPublicProfile(context.Background(), "0000", RelationFriend)
+
+		assert.Empty(t, err, "Error should be empty")
+		assert.Equal(t, "user.bio", result.Bio)
+	})
+
+	t.Run("ERROR: RETURN 404 WHEN PROFILE DOES NOT EXIST", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockItemNotFound{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.GetPublicProfile(context.Background(), "0000", RelationStranger)
+
+		assert.Equal(t, 404, err.Code, "Error should be 404")
+		assert.Empty(t, result, "Result should be empty")
+	})
+
+	t.Run("ERROR: RETURN 503 WHEN DB READ RETURNS ERROR", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockGetError{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.GetPublicProfile(context.Background(), "0000", RelationStranger)
+
+		assert.Equal(t, 503, err.Code, "Error should be 503")
+		assert.Empty(t, result, "Result should be empty")
+	})
+}
+
+func TestSearchProfiles(t *testing.T) {
+	t.Run("SUCCESS: SEARCH BY NORMALIZED NAME PREFIX", func(t *testing.T) {
+		db := &_DatabaseServiceMockQueryPage{}
+		svc := &_Service{db: db, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.SearchProfiles(context.Background(), &SearchFilter{Query: "  Jane  D"})
+
+		assert.Empty(t, err, "Error should be empty")
+		assert.Equal(t, "PROFILE#j", db.values[":PK"])
+		assert.Equal(t, "jane d", db.values[":query"])
+		assert.Equal(t, "APPLICATION_GSI_2", db.options.Index)
+		assert.Equal(t, int64(defaultSearchLimit), db.options.Limit)
+		assert.Equal(t, "next", result.Cursor)
+		assert.Equal(t, []PublicProfile{{UserID: "0000", Name: "Jane Doe"}}, result.Items)
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN QUERY IS EMPTY", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockQueryPage{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.SearchProfiles(context.Background(), &SearchFilter{Query: " "})
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+		assert.Empty(t, result, "Result should be empty")
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN LIMIT IS INVALID", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockQueryPage{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.SearchProfiles(context.Background(), &SearchFilter{Query: "jane", Limit: 1000})
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+		assert.Empty(t, result, "Result should be empty")
+	})
+
+	t.Run("ERROR: RETURN 503 WHEN DB QUERY RETURNS ERROR", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockGetError{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.SearchProfiles(context.Background(), &SearchFilter{Query: "jane"})
+
+		assert.Equal(t, 503, err.Code, "Error should be 503")
+		assert.Empty(t, result, "Result should be empty")
+	})
+}
+
+func TestValidateProfile(t *testing.T) {
+	now := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+
+	t.Run("SUCCESS: ACCEPT COMPLETE PROFILE", func(t *testing.T) {
+		err := validateProfile(&Profile{
+			Name:        "Jane Doe",
+			HomeCity:    "Lisbon",
+			Languages:   []string{"English", "Portuguese"},
+			Interests:   []string{"hiking", "food"},
+			SocialLinks: map[string]string{"instagram": "https://instagram.com/jane"},
+			Phone:       "+351912345678",
+			BirthDate:   "2011-06-15",
+			Privacy:     Privacy{Bio: AudienceFriends},
+		}, now)
+
+		assert.Empty(t, err, "Error should be empty")
+	})
+
+	t.Run("ERROR: RETURN 400 WITH DETAILS OF EACH REPEATED ITEM", func(t *testing.T) {
+		err := validateProfile(&Profile{
+			Languages: []string{"English", "english "},
+			Interests: []string{"hiking", "food", "Hiking"},
+		}, now)
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+		assert.Equal(t, []string{"interests[2]", "languages[1]"}, sortedKeys(err.Details))
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN USER IS YOUNGER THAN MINIMUM AGE", func(t *testing.T) {
+		err := validateProfile(&Profile{BirthDate: "2011-06-16"}, now)
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+		assert.Contains(t, err.Details, "birth_date")
+	})
+}
+
+func TestProfileRequestBinding(t *testing.T) {
+	t.Run("ERROR: RETURN 400 WITH DETAILS OF EACH INVALID FIELD", func(t *testing.T) {
+		err := pkg.Validate(&ProfileRequest{
+			Name:        strings.Repeat("a", 101),
+			Interests:   []string{""},
+			SocialLinks: map[string]string{"site": "javascript:alert(1)"},
+			Phone:       "0912345678",
+			BirthDate:   "15/06/2000",
+			Privacy:     Privacy{Picture: "public"},
+		})
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+		assert.Equal(t, []string{
+			"birth_date", "interests[0]", "name", "phone", "privacy.picture", "social_links[site]",
+		}, sortedKeys(err.Details))
+	})
+}
+
+func sortedKeys(details map[string]string) []string {
+	keys := []string{}
+	for key := range details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestSetSearchFields(t *testing.T) {
+	t.Run("SUCCESS: INDEX PROFILE BY NORMALIZED NAME", func(t *testing.T) {
+		profile := &Profile{Name: "Émile  Zola"}
+
+		setSearchFields(profile)
+
+		assert.Equal(t, "émile zola", profile.NormalizedName)
+		assert.Equal(t, "PROFILE#é", profile.GSI2PK)
+		assert.Equal(t, "émile zola", profile.GSI2SK)
+	})
+
+	t.Run("SUCCESS: DO NOT INDEX PROFILE WITHOUT NAME", func(t *testing.T) {
+		profile := &Profile{GSI2PK: "PROFILE#a", GSI2SK: "a"}
+
+		setSearchFields(profile)
+
+		assert.Empty(t, profile.GSI2PK)
+		assert.Empty(t, profile.GSI2SK)
+	})
+}
+
+// Mock DatabaseService which keeps profiles and handles in memory
+type _DatabaseServiceMockHandles struct {
+	database.Service[Profile]
+	items map[string]Profile
+}
+
+func newHandlesDatabase(profile *Profile) *_DatabaseServiceMockHandles {
+	db := &_DatabaseServiceMockHandles{items: map[string]Profile{}}
+	profile.PK = "USER#" + profile.UserID
+	profile.SK = PROFILE_SK
+	db.items[profile.PK] = *profile
+	return db
+}
+
+func (db *_DatabaseServiceMockHandles) Get(ctx context.Context, keyObj interface{}) (*Profile, error) {
+	item, ok := db.items[keyObj.(map[string]string)["PK"]]
+	if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+func (db *_DatabaseServiceMockHandles) Transaction(ctx context.Context, items ...database.TransactionItem) error {
+	for i, item := range items {
+		if handle, ok := item.Put.(*handleItem); ok {
+			if _, exists := db.items[handle.PK]; exists {
+				return &database.ConditionError{Index: i}
+			}
+		}
+
+		if item.Update != nil && !db.conditionMet(item.Update, item.Changes) {
+			return &database.ConditionError{Index: i}
+		}
+	}
+
+	for _, item := range items {
+		if put, ok := item.Put.(*handleItem); ok {
+			db.items[put.PK] = Profile{PK: put.PK, SK: put.SK, UserID: put.UserID}
+		}
+
+		if item.Update != nil {
+			if _, err := db.apply(item.Update, item.Changes); err != nil {
+				return err
+			}
+		}
+
+		if item.Delete != nil {
+			delete(db.items, item.Delete.(map[string]string)["PK"])
+		}
+	}
+	return nil
+}
+
+func TestValidateHandle(t *testing.T) {
+	for _, handle := range []string{"jane_doe", "j99", "abcdefghijklmnopqrstuvwxyz1234"} {
+		assert.Empty(t, ValidateHandle(handle), handle+" should be valid")
+	}
+
+	for _, handle := range []string{"ab", "abcdefghijklmnopqrstuvwxyz12345", "9lives", "jane.doe", "jane doe", "admin"} {
+		assert.Equal(t, 400, ValidateHandle(handle).Code, handle+" should be invalid")
+	}
+}
+
+func TestSetHandle(t *testing.T) {
+	t.Run("SUCCESS: RESERVE HANDLE", func(t *testing.T) {
+		db := newHandlesDatabase(&Profile{UserID: "0000"})
+		svc := &_Service{db: db, clock: testClock, ids: testIDs, logger: testLogger}
+
+		assert.Empty(t, svc.SetHandle(context.Background(), "0000", "@Jane_Doe"))
+
+		userID, err := svc.ResolveHandle(context.Background(), "@jane_doe")
+		assert.Empty(t, err)
+		assert.Equal(t, "0000", userID)
+		assert.Equal(t, "jane_doe", db.items["USER#0000"].Handle)
+		assert.NotEmpty(t, db.items["USER#0000"].HandleChangedAt)
+	})
+
+	t.Run("SUCCESS: RELEASE OLD HANDLE AFTER COOLDOWN", func(t *testing.T) {
+		changedAt := time.Now().Add(-handleChangeCooldown - time.Hour)
+		db := newHandlesDatabase(&Profile{UserID: "0000", Handle: "jane", HandleChangedAt: &changedAt})
+		db.items["HANDLE#jane"] = Profile{PK: "HANDLE#jane", UserID: "0000"}
+		svc := &_Service{db: db, clock: testClock, ids: testIDs, logger: testLogger}
+
+		assert.Empty(t, svc.SetHandle(context.Background(), "0000", "jane_doe"))
+
+		_, err := svc.ResolveHandle(context.Background(), "jane")
+		assert.Equal(t, 404, err.Code, "Old handle should be released")
+	})
+
+	t.Run("ERROR: RETURN 409 WHEN HANDLE IS TAKEN", func(t *testing.T) {
+		db := newHandlesDatabase(&Profile{UserID: "0000"})
+		db.items["HANDLE#jane"] = Profile{PK: "HANDLE#jane", UserID: "1111"}
+		svc := &_Service{db: db, clock: testClock, ids: testIDs, logger: testLogger}
+
+		err := svc.SetHandle(context.Background(), "0000", "jane")
+
+		assert.Equal(t, 409, err.Code, "Error should be 409")
+		assert.Empty(t, db.items["USER#0000"].Handle, "Profile should not change")
+	})
+
+	t.Run("ERROR: RETURN 409 WHEN PROFILE WAS CHANGED AFTER IT WAS READ", func(t *testing.T) {
+		db := newHandlesDatabase(&Profile{UserID: "0000"})
+		svc := &_Service{db: &_DatabaseServiceMockHandlesStale{db}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		err := svc.SetHandle(context.Background(), "0000", "jane")
+
+		assert.Equal(t, 409, err.Code, "Error should be 409")
+		assert.NotContains(t, db.items, "HANDLE#jane", "Handle should not be reserved")
+	})
+
+	t.Run("ERROR: RETURN 429 DURING COOLDOWN", func(t *testing.T) {
+		changedAt := time.Now()
+		svc := &_Service{db: newHandlesDatabase(&Profile{UserID: "0000", Handle: "jane", HandleChangedAt: &changedAt}), clock: testClock, ids: testIDs, logger: testLogger}
+
+		err := svc.SetHandle(context.Background(), "0000", "jane_doe")
+
+		assert.Equal(t, 429, err.Code, "Error should be 429")
+	})
+
+	t.Run("ERROR: RETURN 404 WHEN PROFILE DOES NOT EXIST", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockItemNotFound{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		err := svc.SetHandle(context.Background(), "0000", "jane")
+
+		assert.Equal(t, 404, err.Code, "Error should be 404")
+	})
+}
+
+// Mock DatabaseService which returns profiles as they were before the last change
+type _DatabaseServiceMockHandlesStale struct {
+	*_DatabaseServiceMockHandles
+}
+
+func (db *_DatabaseServiceMockHandlesStale) Get(ctx context.Context, keyObj interface{}) (*Profile, error) {
+	item, err := db._DatabaseServiceMockHandles.Get(ctx, keyObj)
+	if item != nil {
+		item.UpdatedAt = item.UpdatedAt.Add(-time.Minute)
+	}
+	return item, err
+}
+
+func TestPutProfile(t *testing.T) {
+	t.Run("SUCCESS: REPLACE FIELDS THE USER CAN CHANGE AND KEEP HANDLE AND PICTURE", func(t *testing.T) {
+		db := newHandlesDatabase(&Profile{
+			UserID:         "0000",
+			Name:           "Jane Doe",
+			Bio:            "user.bio",
+			Handle:         "jane",
+			ProfilePicKeys: map[string]string{"256": "0000/1/256.jpg"},
+		})
+		svc := &_Service{db: db, clock: testClock, ids: testIDs, logger: testLogger}
+
+		err := svc.PutProfile(context.Background(), &Profile{UserID: "0000", Name: "Zoe Smith"})
+
+		assert.Empty(t, err, "Error should be empty")
+		stored := db.items["USER#0000"]
+		assert.Equal(t, "Zoe Smith", stored.Name)
+		assert.Empty(t, stored.Bio, "Omitted field should be cleared")
+		assert.Equal(t, "jane", stored.Handle, "Handle should be kept")
+		assert.Equal(t, map[string]string{"256": "0000/1/256.jpg"}, stored.ProfilePicKeys, "Picture should be kept")
+		assert.Equal(t, "PROFILE#z", stored.GSI2PK)
+	})
+}
+
+func (db *_DatabaseServiceMockHandles) Write(ctx context.Context, obj ...*Profile) error {
+	for _, item := range obj {
+		db.items[item.PK] = *item
+	}
+	return nil
+}
+
+// Update applies the update to the stored attributes, the condition only compares updated_at
+func (db *_DatabaseServiceMockHandles) Update(ctx context.Context, keyObj interface{}, update *database.Update) (*Profile, error) {
+	if !db.conditionMet(keyObj, update) {
+		return nil, database.ErrConditionFailed
+	}
+
+	return db.apply(keyObj, update)
+}
+
+// conditionMet returns true if the update has no condition, the condition expects no item and there is none,
+// or the stored updated_at is the expected one
+func (db *_DatabaseServiceMockHandles) conditionMet(keyObj interface{}, update *database.Update) bool {
+	current, ok := db.items[keyObj.(map[string]string)["PK"]]
+
+	switch update.Condition {
+	case "":
+		return true
+	case "attribute_not_exists(PK)":
+		return !ok
+	default:
+		return ok && current.UpdatedAt.Equal(update.Values[":updated_at"].(time.Time))
+	}
+}
+
+// apply creates the item if it doesn't exist like UpdateItem
+func (db *_DatabaseServiceMockHandles) apply(keyObj interface{}, update *database.Update) (*Profile, error) {
+	key := keyObj.(map[string]string)
+	current, ok := db.items[key["PK"]]
+	if !ok {
+		current = Profile{PK: key["PK"], SK: key["SK"]}
+	}
+
+	attributes, _ := dynamodbattribute.MarshalMap(current)
+	for name, value := range update.Set {
+		attributes[name], _ = dynamodbattribute.Marshal(value)
+	}
+	for _, name := range update.Remove {
+		delete(attributes, name)
+	}
+
+	var updated Profile
+	if err := dynamodbattribute.UnmarshalMap(attributes, &updated); err != nil {
+		return nil, err
+	}
+
+	db.items[key["PK"]] = updated
+	return &updated, nil
+}
+
+func TestPatchProfile(t *testing.T) {
+	updatedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newDatabase := func() *_DatabaseServiceMockHandles {
+		return newHandlesDatabase(&Profile{
+			UserID:         "0000",
+			Name:           "Jane Doe",
+			Bio:            "user.bio",
+			HomeCity:       "Lisbon",
+			Languages:      []string{"English"},
+			SocialLinks:    map[string]string{"instagram": "https://instagram.com/jane"},
+			ProfilePicKeys: map[string]string{"256": "0000/1/256.jpg"},
+			Privacy:        Privacy{Bio: AudienceFriends, Picture: AudienceFriends},
+			UpdatedAt:      updatedAt,
+		})
+	}
+
+	t.Run("SUCCESS: CHANGE ONLY PATCHED FIELDS", func(t *testing.T) {
+		db := newDatabase()
+		svc := &_Service{db: db, storage: &_StorageServiceMock{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.PatchProfile(context.Background(), "0000", []byte(`{"bio": "new.bio", "home_city": null, "privacy": {"bio": "everyone"}}`))
+
+		assert.Empty(t, err, "Error should be empty")
+		assert.Equal(t, "new.bio", result.Bio)
+		assert.Empty(t, result.HomeCity, "Null should clear the field")
+		assert.Equal(t, Privacy{Bio: AudienceEveryone, Picture: AudienceFriends}, result.Privacy, "Privacy should be merged")
+		assert.Equal(t, "https://storage/0000/1/256.jpg?signature=test", result.ProfilePicUrl)
+		assert.True(t, result.UpdatedAt.After(updatedAt), "UpdatedAt should be bumped")
+
+		stored := db.items["USER#0000"]
+		assert.Equal(t, "Jane Doe", stored.Name, "Name should not change")
+		assert.Equal(t, []string{"English"}, stored.Languages, "Languages should not change")
+		assert.Equal(t, map[string]string{"instagram": "https://instagram.com/jane"}, stored.SocialLinks)
+		assert.Equal(t, map[string]string{"256": "0000/1/256.jpg"}, stored.ProfilePicKeys, "Picture should be kept")
+	})
+
+	t.Run("SUCCESS: UPDATE SEARCH FIELDS WHEN NAME CHANGES", func(t *testing.T) {
+		db := newDatabase()
+		svc := &_Service{db: db, storage: &_StorageServiceMock{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		_, err := svc.PatchProfile(context.Background(), "0000", []byte(`{"name": "Zoe Smith"}`))
+
+		assert.Empty(t, err, "Error should be empty")
+		assert.Equal(t, "zoe smith", db.items["USER#0000"].GSI2SK)
+		assert.Equal(t, "PROFILE#z", db.items["USER#0000"].GSI2PK)
+	})
+
+	t.Run("ERROR: RETURN 400 WITH DETAILS WHEN FIELD CAN'T BE CHANGED", func(t *testing.T) {
+		svc := &_Service{db: newDatabase(), clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.PatchProfile(context.Background(), "0000", []byte(`{"user_id": "1111", "handle": "jane"}`))
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+		assert.Contains(t, err.Details, "user_id")
+		assert.Contains(t, err.Details, "handle")
+		assert.Empty(t, result, "Result should be empty")
+	})
+
+	t.Run("ERROR: RETURN 400 WITH DETAILS WHEN MERGED PROFILE IS INVALID", func(t *testing.T) {
+		db := newDatabase()
+		svc := &_Service{db: db, clock: testClock, ids: testIDs, logger: testLogger}
+
+		_, err := svc.PatchProfile(context.Background(), "0000", []byte(`{"social_links": {"site": "not a url"}}`))
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+		assert.Contains(t, err.Details, "social_links[site]")
+		assert.Equal(t, updatedAt, db.items["USER#0000"].UpdatedAt, "Profile should not change")
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN PATCH IS NOT AN OBJECT", func(t *testing.T) {
+		svc := &_Service{db: newDatabase(), clock: testClock, ids: testIDs, logger: testLogger}
+
+		_, err := svc.PatchProfile(context.Background(), "0000", []byte(`["bio"]`))
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+	})
+
+	t.Run("ERROR: RETURN 404 WHEN PROFILE DOESN'T EXIST", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockItemNotFound{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		_, err := svc.PatchProfile(context.Background(), "0000", []byte(`{"bio": "new.bio"}`))
+
+		assert.Equal(t, 404, err.Code, "Error should be 404")
+	})
+}
+
+// Mock StorageService which keeps uploaded files in memory
+type _StorageServiceMock struct {
+	filestorage.Service
+	files        map[string][]byte
+	contentTypes map[string]string
+}
+
+func (storage *_StorageServiceMock) UploadFile(ctx context.Context, filename string, body io.ReadSeeker, contentType string) error {
+	data, _ := io.ReadAll(body)
+	storage.files[filename] = data
+	storage.contentTypes[filename] = contentType
+	return nil
+}
+
+func (storage *_StorageServiceMock) DeleteFile(ctx context.Context, filename string) error {
+	delete(storage.files, filename)
+	return nil
+}
+
+func (storage *_StorageServiceMock) GetDownloadUrl(filename string) (string, error) {
+	return "https://storage/" + filename + "?signature=test", nil
+}
+
+func encodePNG(width int, height int) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)))
+	return buf.Bytes()
+}
+
+func TestUploadProfilePicture(t *testing.T) {
+	t.Run("SUCCESS: STORE SQUARE PICTURES IN ALL SIZES", func(t *testing.T) {
+		db := newHandlesDatabase(&Profile{UserID: "0000", Name: "user.name"})
+		storage := &_StorageServiceMock{files: map[string][]byte{}, contentTypes: map[string]string{}}
+		svc := &_Service{db: db, storage: storage, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.UploadProfilePicture(context.Background(), "0000", bytes.NewReader(encodePNG(300, 200)))
+
+		assert.Empty(t, err, "Error should be empty")
+		assert.Len(t, storage.files, len(ProfilePictureSizes))
+		for key, data := range storage.files {
+			img, format, _ := image.DecodeConfig(bytes.NewReader(data))
+			assert.Equal(t, "jpeg", format)
+			assert.Equal(t, img.Width, img.Height, "Picture should be square")
+			assert.Equal(t, "image/jpeg", storage.contentTypes[key])
+		}
+
+		assert.Contains(t, result.ProfilePicUrl, "/0000/")
+		assert.Contains(t, result.ProfilePicUrl, "/256.jpg?signature=")
+		assert.Len(t, result.ProfilePicUrls, len(ProfilePictureSizes))
+		assert.Len(t, db.items["USER#0000"].ProfilePicKeys, len(ProfilePictureSizes))
+		assert.True(t, strings.HasSuffix(db.items["USER#0000"].ProfilePicKeys["256"], "/256.jpg"), "Keys should be stored instead of urls")
+		assert.Equal(t, "user.name", db.items["USER#0000"].Name, "Profile should be kept")
+	})
+
+	t.Run("SUCCESS: STORE EACH UPLOAD UNDER NEW VERSION", func(t *testing.T) {
+		db := newHandlesDatabase(&Profile{UserID: "0000"})
+		storage := &_StorageServiceMock{files: map[string][]byte{}, contentTypes: map[string]string{}}
+		svc := &_Service{db: db, storage: storage, clock: testClock, ids: testIDs, logger: testLogger}
+
+		first, _ := svc.UploadProfilePicture(context.Background(), "0000", bytes.NewReader(encodePNG(10, 10)))
+		second, _ := svc.UploadProfilePicture(context.Background(), "0000", bytes.NewReader(encodePNG(10, 10)))
+
+		assert.NotEqual(t, first.ProfilePicUrl, second.ProfilePicUrl)
+		assert.Len(t, storage.files, len(ProfilePictureSizes), "Previous version should be deleted")
+		for _, key := range db.items["USER#0000"].ProfilePicKeys {
+			assert.Contains(t, storage.files, key)
+		}
+	})
+
+	t.Run("SUCCESS: CREATE PROFILE WHEN IT DOES NOT EXIST", func(t *testing.T) {
+		db := &_DatabaseServiceMockHandles{items: map[string]Profile{}}
+		storage := &_StorageServiceMock{files: map[string][]byte{}, contentTypes: map[string]string{}}
+		svc := &_Service{db: db, storage: storage, clock: testClock, ids: testIDs, logger: testLogger}
+
+		_, err := svc.UploadProfilePicture(context.Background(), "0000", bytes.NewReader(encodePNG(10, 10)))
+
+		assert.Empty(t, err, "Error should be empty")
+		assert.Equal(t, "0000", db.items["USER#0000"].UserID)
+		assert.Len(t, db.items["USER#0000"].ProfilePicKeys, len(ProfilePictureSizes))
+	})
+
+	t.Run("ERROR: RETURN 409 AND DELETE NEW VERSION WHEN PROFILE WAS CHANGED", func(t *testing.T) {
+		db := newHandlesDatabase(&Profile{UserID: "0000", ProfilePicKeys: map[string]string{"256": "0000/1/256.jpg"}})
+		storage := &_StorageServiceMock{files: map[string][]byte{"0000/1/256.jpg": {}}, contentTypes: map[string]string{}}
+		svc := &_Service{db: &_DatabaseServiceMockHandlesStale{db}, storage: storage, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.UploadProfilePicture(context.Background(), "0000", bytes.NewReader(encodePNG(10, 10)))
+
+		assert.Equal(t, 409, err.Code, "Error should be 409")
+		assert.Empty(t, result, "Result should be empty")
+		assert.Equal(t, map[string][]byte{"0000/1/256.jpg": {}}, storage.files, "Only the current version should be kept")
+	})
+
+	t.Run("ERROR: RETURN 415 WHEN FILE IS NOT AN IMAGE", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockItemNotFound{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.UploadProfilePicture(context.Background(), "0000", strings.NewReader("not an image"))
+
+		assert.Equal(t, 415, err.Code, "Error should be 415")
+		assert.Empty(t, result, "Result should be empty")
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN IMAGE IS CORRUPTED", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockItemNotFound{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.UploadProfilePicture(context.Background(), "0000", bytes.NewReader(encodePNG(10, 10)[:40]))
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+		assert.Empty(t, result, "Result should be empty")
+	})
+
+	t.Run("ERROR: RETURN 413 WHEN FILE IS TOO LARGE", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockItemNotFound{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.UploadProfilePicture(context.Background(), "0000", bytes.NewReader(make([]byte, MaxProfilePictureSize+1)))
+
+		assert.Equal(t, 413, err.Code, "Error should be 413")
+		assert.Empty(t, result, "Result should be empty")
+	})
+}
@@ -0,0 +1,30 @@
+package profile
+
+import (
+	"fmt"
+	"speakeasy/pkg"
+	"time"
+)
+
+// validateProfile checks the rules the binding tags of ProfileRequest can't express,
+// the returned error contains the reason of each invalid field.
+func validateProfile(profile *Profile, now time.Time) *pkg.Error {
+	validator := &pkg.Validator{}
+
+	validator.BirthDate("birth_date", profile.BirthDate, now)
+
+	validateUnique(validator, "languages", profile.Languages)
+	validateUnique(validator, "interests", profile.Interests)
+
+	return validator.Error()
+}
+
+// validateUnique checks items are not repeated, ignoring case and whitespace
+func validateUnique(validator *pkg.Validator, field string, items []string) {
+	seen := map[string]bool{}
+	for i, item := range items {
+		normalized := pkg.NormalizeText(item)
+		validator.Check(!seen[normalized], fmt.Sprintf("%s[%d]", field, i), "Cannot be repeated")
+		seen[normalized] = true
+	}
+}
@@ -0,0 +1,54 @@
+package social
+
+var USER_PK string = "USER#%s"
+var FOLLOWS_SK string = "FOLLOWS#%s"
+var FRIEND_SK string = "FRIEND#%s"
+var FRIEND_REQUEST_SK string = "FRIENDREQ#%s"
+var BLOCKS_SK string = "BLOCKS#%s"
+
+// RelationshipType is the type of relationship between two users
+type RelationshipType string
+
+const (
+	RelationshipFollows       RelationshipType = "follows"
+	RelationshipFriend        RelationshipType = "friend"
+	RelationshipFriendRequest RelationshipType = "friend_request"
+	RelationshipBlocks        RelationshipType = "blocks"
+)
+
+// Relationship object to store in database, UserID has a relationship with TargetID.
+// PK should be USER#<user id> and SK should be in the format of FOLLOWS_SK, FRIEND_SK, FRIEND_REQUEST_SK or BLOCKS_SK.
+// Friend requests are stored under the recipient, so UserID received a request from TargetID.
+// APPLICATION_GSI_1 (SK as partition key) is used for reverse lookups, e.g. followers of a user.
+type Relationship struct {
+	PK        string           `json:"PK"`
+	SK        string           `json:"SK"`
+	UserID    string           `json:"user_id"`
+	TargetID  string           `json:"target_id"`
+	Type      RelationshipType `json:"type"`
+	CreatedAt string           `json:"created_at"`
+}
+
+// RelationshipResponse object which contains the relationship fields sent to clients
+type RelationshipResponse struct {
+	UserID    string           `json:"user_id"`
+	TargetID  string           `json:"target_id"`
+	Type      RelationshipType `json:"type"`
+	CreatedAt string           `json:"created_at"`
+}
+
+// Response returns relationship as sent to clients
+func (relationship *Relationship) Response() RelationshipResponse {
+	return RelationshipResponse{
+		UserID:    relationship.UserID,
+		TargetID:  relationship.TargetID,
+		Type:      relationship.Type,
+		CreatedAt: relationship.CreatedAt,
+	}
+}
+
+// PageRequest object which contains the requested page of a list
+type PageRequest struct {
+	Cursor string `form:"cursor"`
+	Limit  int64  `form:"limit" binding:"min=0"`
+}
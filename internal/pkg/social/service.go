@@ -0,0 +1,395 @@
+package social
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"speakeasy/pkg"
+	"speakeasy/pkg/clock"
+	"speakeasy/pkg/database"
+	"time"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+type _Service struct {
+	db     database.Service[Relationship]
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+// NewSocialService returns _Service object
+func NewSocialService(db database.Service[Relationship], clock clock.Clock, logger *slog.Logger) Service {
+	return &_Service{db, clock, logger}
+}
+
+// Service interface which contains follow and friend operations
+type Service interface {
+	Follow(ctx context.Context, userID string, targetID string) *pkg.Error
+	Unfollow(ctx context.Context, userID string, targetID string) *pkg.Error
+	GetFollowers(ctx context.Context, userID string, page *PageRequest) (*database.Page[Relationship], *pkg.Error)
+	GetFollowing(ctx context.Context, userID string, page *PageRequest) (*database.Page[Relationship], *pkg.Error)
+	SendFriendRequest(ctx context.Context, userID string, targetID string) *pkg.Error
+	AcceptFriendRequest(ctx context.Context, userID string, requesterID string) *pkg.Error
+	DeclineFriendRequest(ctx context.Context, userID string, requesterID string) *pkg.Error
+	RemoveFriend(ctx context.Context, userID string, friendID string) *pkg.Error
+	GetFriends(ctx context.Context, userID string, page *PageRequest) (*database.Page[Relationship], *pkg.Error)
+	GetFriendRequests(ctx context.Context, userID string, page *PageRequest) (*database.Page[Relationship], *pkg.Error)
+	AreFriends(ctx context.Context, userID string, otherID string) (bool, *pkg.Error)
+	Block(ctx context.Context, userID string, targetID string) *pkg.Error
+	Unblock(ctx context.Context, userID string, targetID string) *pkg.Error
+	GetBlocked(ctx context.Context, userID string, page *PageRequest) (*database.Page[Relationship], *pkg.Error)
+	IsBlocked(ctx context.Context, userID string, otherID string) (bool, *pkg.Error)
+	GetBlockedSet(ctx context.Context, userID string) (map[string]bool, *pkg.Error)
+}
+
+// Follow function to follow another user
+func (service *_Service) Follow(ctx context.Context, userID string, targetID string) *pkg.Error {
+	if userID == targetID {
+		return &pkg.Error{Code: 400, Reason: "Cannot follow yourself"}
+	}
+
+	if err := service.checkNotBlocked(ctx, userID, targetID); err != nil {
+		return err
+	}
+
+	follow := service.newRelationship(userID, targetID, RelationshipFollows, FOLLOWS_SK)
+
+	if err := service.db.Write(ctx, follow); err != nil {
+		return pkg.Unavailable("Follow", err)
+	}
+
+	return nil
+}
+
+// Unfollow function to stop following another user
+func (service *_Service) Unfollow(ctx context.Context, userID string, targetID string) *pkg.Error {
+	if err := service.db.Delete(ctx, relationshipKey(userID, targetID, FOLLOWS_SK)); err != nil {
+		return pkg.Unavailable("Unfollow", err)
+	}
+
+	return nil
+}
+
+// GetFollowers function to list users following the user
+func (service *_Service) GetFollowers(ctx context.Context, userID string, page *PageRequest) (*database.Page[Relationship], *pkg.Error) {
+	filter := map[string]string{
+		":SK": fmt.Sprintf(FOLLOWS_SK, userID),
+	}
+
+	return service.queryPage(ctx, filter, "SK = :SK", "APPLICATION_GSI_1", page)
+}
+
+// GetFollowing function to list users the user follows
+func (service *_Service) GetFollowing(ctx context.Context, userID string, page *PageRequest) (*database.Page[Relationship], *pkg.Error) {
+	filter := map[string]string{
+		":PK": fmt.Sprintf(USER_PK, userID),
+		":SK": fmt.Sprintf(FOLLOWS_SK, ""),
+	}
+
+	return service.queryPage(ctx, filter, "PK = :PK And begins_with(SK, :SK)", "", page)
+}
+
+// SendFriendRequest function to send friend request,
+// if the target already sent a request to the user, the request is accepted instead.
+func (service *_Service) SendFriendRequest(ctx context.Context, userID string, targetID string) *pkg.Error {
+	if userID == targetID {
+		return &pkg.Error{Code: 400, Reason: "Cannot send friend request to yourself"}
+	}
+
+	if err := service.checkNotBlocked(ctx, userID, targetID); err != nil {
+		return err
+	}
+
+	friends, err := service.AreFriends(ctx, userID, targetID)
+	if err != nil {
+		return err
+	}
+
+	if friends {
+		return &pkg.Error{Code: 409, Reason: "Already friends"}
+	}
+
+	incoming, dberr := service.db.Get(ctx, relationshipKey(userID, targetID, FRIEND_REQUEST_SK))
+	if dberr != nil {
+		return pkg.Unavailable("SendFriendRequest", dberr)
+	}
+
+	if incoming != nil {
+		return service.AcceptFriendRequest(ctx, userID, targetID)
+	}
+
+	// Request is stored under the recipient
+	request := service.newRelationship(targetID, userID, RelationshipFriendRequest, FRIEND_REQUEST_SK)
+
+	if err := service.db.Write(ctx, request); err != nil {
+		return pkg.Unavailable("SendFriendRequest", err)
+	}
+
+	return nil
+}
+
+// AcceptFriendRequest function to accept friend request, both users become friends
+func (service *_Service) AcceptFriendRequest(ctx context.Context, userID string, requesterID string) *pkg.Error {
+	// The request is deleted in the same transaction which adds the friends,
+	// the condition fails if it was never sent, or declined or accepted by another request
+	err := service.db.Transaction(ctx,
+		database.TransactionItem{Put: service.newRelationship(userID, requesterID, RelationshipFriend, FRIEND_SK)},
+		database.TransactionItem{Put: service.newRelationship(requesterID, userID, RelationshipFriend, FRIEND_SK)},
+		database.TransactionItem{
+			Delete:    relationshipKey(userID, requesterID, FRIEND_REQUEST_SK),
+			Condition: "attribute_exists(PK)",
+		},
+	)
+
+	if errors.Is(err, database.ErrConditionFailed) {
+		return &pkg.Error{Code: 404, Reason: "Friend request not found"}
+	}
+
+	if err != nil {
+		return pkg.Unavailable("AcceptFriendRequest", err)
+	}
+
+	return nil
+}
+
+// DeclineFriendRequest function to decline friend request
+func (service *_Service) DeclineFriendRequest(ctx context.Context, userID string, requesterID string) *pkg.Error {
+	key := relationshipKey(userID, requesterID, FRIEND_REQUEST_SK)
+
+	request, err := service.db.Get(ctx, key)
+	if err != nil {
+		return pkg.Unavailable("DeclineFriendRequest", err)
+	}
+
+	if request == nil {
+		return &pkg.Error{Code: 404, Reason: "Friend request not found"}
+	}
+
+	if err := service.db.Delete(ctx, key); err != nil {
+		return pkg.Unavailable("DeclineFriendRequest", err)
+	}
+
+	return nil
+}
+
+// RemoveFriend function to remove friend relationship for both users
+func (service *_Service) RemoveFriend(ctx context.Context, userID string, friendID string) *pkg.Error {
+	for _, key := range []map[string]string{
+		relationshipKey(userID, friendID, FRIEND_SK),
+		relationshipKey(friendID, userID, FRIEND_SK),
+	} {
+		if err := service.db.Delete(ctx, key); err != nil {
+			return pkg.Unavailable("RemoveFriend", err)
+		}
+	}
+
+	return nil
+}
+
+// GetFriends function to list friends of the user
+func (service *_Service) GetFriends(ctx context.Context, userID string, page *PageRequest) (*database.Page[Relationship], *pkg.Error) {
+	filter := map[string]string{
+		":PK": fmt.Sprintf(USER_PK, userID),
+		":SK": fmt.Sprintf(FRIEND_SK, ""),
+	}
+
+	return service.queryPage(ctx, filter, "PK = :PK And begins_with(SK, :SK)", "", page)
+}
+
+// GetFriendRequests function to list friend requests received by the user
+func (service *_Service) GetFriendRequests(ctx context.Context, userID string, page *PageRequest) (*database.Page[Relationship], *pkg.Error) {
+	filter := map[string]string{
+		":PK": fmt.Sprintf(USER_PK, userID),
+		":SK": fmt.Sprintf(FRIEND_REQUEST_SK, ""),
+	}
+
+	return service.queryPage(ctx, filter, "PK = :PK And begins_with(SK, :SK)", "", page)
+}
+
+// AreFriends function to check if two users are friends
+func (service *_Service) AreFriends(ctx context.Context, userID string, otherID string) (bool, *pkg.Error) {
+	friend, err := service.db.Get(ctx, relationshipKey(userID, otherID, FRIEND_SK))
+	if err != nil {
+		return false, pkg.Unavailable("AreFriends", err)
+	}
+
+	return friend != nil, nil
+}
+
+// Block function to block another user, follows, friendship and friend requests
+// between the users are removed in both directions
+func (service *_Service) Block(ctx context.Context, userID string, targetID string) *pkg.Error {
+	if userID == targetID {
+		return &pkg.Error{Code: 400, Reason: "Cannot block yourself"}
+	}
+
+	// The block and the removal of every relationship between the users are written together
+	items := []database.TransactionItem{
+		{Put: service.newRelationship(userID, targetID, RelationshipBlocks, BLOCKS_SK)},
+	}
+
+	for _, sk := range []string{FOLLOWS_SK, FRIEND_SK, FRIEND_REQUEST_SK} {
+		items = append(items,
+			database.TransactionItem{Delete: relationshipKey(userID, targetID, sk)},
+			database.TransactionItem{Delete: relationshipKey(targetID, userID, sk)},
+		)
+	}
+
+	if err := service.db.Transaction(ctx, items...); err != nil {
+		return pkg.Unavailable("Block", err)
+	}
+
+	return nil
+}
+
+// Unblock function to unblock user, removed relationships are not restored
+func (service *_Service) Unblock(ctx context.Context, userID string, targetID string) *pkg.Error {
+	if err := service.db.Delete(ctx, relationshipKey(userID, targetID, BLOCKS_SK)); err != nil {
+		return pkg.Unavailable("Unblock", err)
+	}
+
+	return nil
+}
+
+// GetBlocked function to list users blocked by the user
+func (service *_Service) GetBlocked(ctx context.Context, userID string, page *PageRequest) (*database.Page[Relationship], *pkg.Error) {
+	filter := map[string]string{
+		":PK": fmt.Sprintf(USER_PK, userID),
+		":SK": fmt.Sprintf(BLOCKS_SK, ""),
+	}
+
+	return service.queryPage(ctx, filter, "PK = :PK And begins_with(SK, :SK)", "", page)
+}
+
+// IsBlocked function to check if either user has blocked the other
+func (service *_Service) IsBlocked(ctx context.Context, userID string, otherID string) (bool, *pkg.Error) {
+	for _, key := range []map[string]string{
+		relationshipKey(userID, otherID, BLOCKS_SK),
+		relationshipKey(otherID, userID, BLOCKS_SK),
+	} {
+		block, err := service.db.Get(ctx, key)
+		if err != nil {
+			return false, pkg.Unavailable("IsBlocked", err)
+		}
+
+		if block != nil {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GetBlockedSet function to get the ids of users the user blocked or was blocked by,
+// used to filter lists of content
+func (service *_Service) GetBlockedSet(ctx context.Context, userID string) (map[string]bool, *pkg.Error) {
+	blocked := map[string]bool{}
+
+	lists := []struct {
+		filter    map[string]string
+		condition string
+		index     string
+	}{
+		{
+			filter:    map[string]string{":PK": fmt.Sprintf(USER_PK, userID), ":SK": fmt.Sprintf(BLOCKS_SK, "")},
+			condition: "PK = :PK And begins_with(SK, :SK)",
+		},
+		{
+			filter:    map[string]string{":SK": fmt.Sprintf(BLOCKS_SK, userID)},
+			condition: "SK = :SK",
+			index:     "APPLICATION_GSI_1",
+		},
+	}
+
+	for _, list := range lists {
+		page := &PageRequest{Limit: maxPageLimit}
+		for {
+			result, err := service.queryPage(ctx, list.filter, list.condition, list.index, page)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, block := range result.Items {
+				if block.UserID == userID {
+					blocked[block.TargetID] = true
+				} else {
+					blocked[block.UserID] = true
+				}
+			}
+
+			if result.Cursor == "" {
+				break
+			}
+			page.Cursor = result.Cursor
+		}
+	}
+
+	return blocked, nil
+}
+
+// checkNotBlocked returns 403 error if either user has blocked the other
+func (service *_Service) checkNotBlocked(ctx context.Context, userID string, otherID string) *pkg.Error {
+	blocked, err := service.IsBlocked(ctx, userID, otherID)
+	if err != nil {
+		return err
+	}
+
+	if blocked {
+		return &pkg.Error{Code: 403, Reason: "Forbidden"}
+	}
+
+	return nil
+}
+
+// queryPage function to query a page of relationships
+func (service *_Service) queryPage(ctx context.Context, filter map[string]string, condition string, index string, page *PageRequest) (*database.Page[Relationship], *pkg.Error) {
+	if page.Limit < 0 || page.Limit > maxPageLimit {
+		return nil, &pkg.Error{Code: 400, Reason: "Limit is invalid"}
+	}
+
+	options := &database.QueryOptions{
+		Index:  index,
+		Limit:  page.Limit,
+		Cursor: page.Cursor,
+	}
+
+	if options.Limit == 0 {
+		options.Limit = defaultPageLimit
+	}
+
+	result, err := service.db.QueryPage(ctx, filter, condition, options)
+	if errors.Is(err, database.ErrInvalidCursor) {
+		return nil, &pkg.Error{Code: 400, Reason: "Cursor is invalid"}
+	}
+
+	if err != nil {
+		return nil, pkg.Unavailable("queryPage", err)
+	}
+
+	return result, nil
+}
+
+// newRelationship returns relationship item from user to target
+func (service *_Service) newRelationship(userID string, targetID string, relationshipType RelationshipType, sk string) *Relationship {
+	return &Relationship{
+		PK:        fmt.Sprintf(USER_PK, userID),
+		SK:        fmt.Sprintf(sk, targetID),
+		UserID:    userID,
+		TargetID:  targetID,
+		Type:      relationshipType,
+		CreatedAt: service.clock.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// relationshipKey returns database key of relationship item from user to target
+func relationshipKey(userID string, targetID string, sk string) map[string]string {
+	return map[string]string{
+		"PK": fmt.Sprintf(USER_PK, userID),
+		"SK": fmt.Sprintf(sk, targetID),
+	}
+}
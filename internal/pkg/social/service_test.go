@@ -0,0 +1,330 @@
+package social
+
+import (
+	"context"
+	"errors"
+	"speakeasy/pkg/clock"
+	"speakeasy/pkg/database"
+	"speakeasy/pkg/logging"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testLogger drops the log records of the services under test
+var testLogger = logging.Discard()
+
+// testClock is the real clock, tests which check times set a fixed one
+var testClock = clock.System()
+
+// Mock DatabaseService which keeps relationships in memory
+type _DatabaseServiceMockInMemory struct {
+	database.Service[Relationship]
+	items   map[string]Relationship
+	options *database.QueryOptions
+}
+
+func newInMemoryDatabase() *_DatabaseServiceMockInMemory {
+	return &_DatabaseServiceMockInMemory{items: map[string]Relationship{}}
+}
+
+func (db *_DatabaseServiceMockInMemory) Get(ctx context.Context, keyObj interface{}) (*Relationship, error) {
+	key := keyObj.(map[string]string)
+	item, ok := db.items[key["PK"]+"|"+key["SK"]]
+	if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+func (db *_DatabaseServiceMockInMemory) Write(ctx context.Context, obj ...*Relationship) error {
+	for _, item := range obj {
+		db.items[item.PK+"|"+item.SK] = *item
+	}
+	return nil
+}
+
+func (db *_DatabaseServiceMockInMemory) Delete(ctx context.Context, keyObj interface{}) error {
+	key := keyObj.(map[string]string)
+	delete(db.items, key["PK"]+"|"+key["SK"])
+	return nil
+}
+
+// Transaction puts and deletes the relationships if every item to delete with a condition exists
+func (db *_DatabaseServiceMockInMemory) Transaction(ctx context.Context, items ...database.TransactionItem) error {
+	for i, item := range items {
+		if item.Delete == nil || item.Condition == "" {
+			continue
+		}
+		if existing, _ := db.Get(ctx, item.Delete); existing == nil {
+			return &database.ConditionError{Index: i}
+		}
+	}
+
+	for _, item := range items {
+		if item.Put != nil {
+			db.Write(ctx, item.Put.(*Relationship))
+			continue
+		}
+		db.Delete(ctx, item.Delete)
+	}
+	return nil
+}
+
+func (db *_DatabaseServiceMockInMemory) QueryPage(ctx context.Context, filterObj interface{}, condition string, options *database.QueryOptions) (*database.Page[Relationship], error) {
+	filter := filterObj.(map[string]string)
+	db.options = options
+
+	page := &database.Page[Relationship]{Items: []Relationship{}}
+	for _, item := range db.items {
+		if options.Index == "APPLICATION_GSI_1" && item.SK == filter[":SK"] {
+			page.Items = append(page.Items, item)
+		} else if options.Index == "" && item.PK == filter[":PK"] && strings.HasPrefix(item.SK, filter[":SK"]) {
+			page.Items = append(page.Items, item)
+		}
+	}
+	return page, nil
+}
+
+// Mock DatabaseService where every operation returns an error
+type _DatabaseServiceMockError struct {
+	database.Service[Relationship]
+}
+
+func (db *_DatabaseServiceMockError) Get(ctx context.Context, keyObj interface{}) (*Relationship, error) {
+	return nil, errors.New("ERROR")
+}
+
+func (db *_DatabaseServiceMockError) Write(ctx context.Context, obj ...*Relationship) error {
+	return errors.New("ERROR")
+}
+
+func (db *_DatabaseServiceMockError) Transaction(ctx context.Context, items ...database.TransactionItem) error {
+	return errors.New("ERROR")
+}
+
+func (db *_DatabaseServiceMockError) QueryPage(ctx context.Context, filterObj interface{}, condition string, options *database.QueryOptions) (*database.Page[Relationship], error) {
+	return nil, errors.New("ERROR")
+}
+
+func TestNewSocialService(t *testing.T) {
+	t.Run("SUCCESS: RETURN NEW SOCIAL SERVICE", func(t *testing.T) {
+		svc := NewSocialService(newInMemoryDatabase(), testClock, testLogger)
+
+		assert.NotEmpty(t, svc, "Service should not empty")
+	})
+}
+
+func TestFollow(t *testing.T) {
+	t.Run("SUCCESS: LIST FOLLOWERS AND FOLLOWING", func(t *testing.T) {
+		db := newInMemoryDatabase()
+		svc := &_Service{db: db, clock: testClock, logger: testLogger}
+
+		assert.Empty(t, svc.Follow(context.Background(), "a", "b"))
+		assert.Empty(t, svc.Follow(context.Background(), "c", "b"))
+
+		followers, err := svc.GetFollowers(context.Background(), "b", &PageRequest{})
+		assert.Empty(t, err)
+		assert.Len(t, followers.Items, 2)
+		assert.Equal(t, "APPLICATION_GSI_1", db.options.Index)
+
+		following, err := svc.GetFollowing(context.Background(), "a", &PageRequest{})
+		assert.Empty(t, err)
+		assert.Len(t, following.Items, 1)
+		assert.Equal(t, "b", following.Items[0].TargetID)
+	})
+
+	t.Run("SUCCESS: UNFOLLOW USER", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), clock: testClock, logger: testLogger}
+
+		assert.Empty(t, svc.Follow(context.Background(), "a", "b"))
+		assert.Empty(t, svc.Unfollow(context.Background(), "a", "b"))
+
+		followers, err := svc.GetFollowers(context.Background(), "b", &PageRequest{})
+		assert.Empty(t, err)
+		assert.Empty(t, followers.Items)
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN FOLLOWING YOURSELF", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), clock: testClock, logger: testLogger}
+
+		err := svc.Follow(context.Background(), "a", "a")
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN LIMIT IS INVALID", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), clock: testClock, logger: testLogger}
+
+		result, err := svc.GetFollowers(context.Background(), "a", &PageRequest{Limit: 1000})
+
+		assert.Empty(t, result, "Result should be empty")
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+	})
+
+	t.Run("ERROR: RETURN 503 WHEN DB WRITE RETURNS ERROR", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockError{}, clock: testClock, logger: testLogger}
+
+		err := svc.Follow(context.Background(), "a", "b")
+
+		assert.Equal(t, 503, err.Code, "Error should be 503")
+	})
+}
+
+func TestFriendRequests(t *testing.T) {
+	t.Run("SUCCESS: ACCEPT FRIEND REQUEST", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), clock: testClock, logger: testLogger}
+
+		assert.Empty(t, svc.SendFriendRequest(context.Background(), "a", "b"))
+
+		requests, err := svc.GetFriendRequests(context.Background(), "b", &PageRequest{})
+		assert.Empty(t, err)
+		assert.Len(t, requests.Items, 1)
+		assert.Equal(t, "a", requests.Items[0].TargetID)
+
+		assert.Empty(t, svc.AcceptFriendRequest(context.Background(), "b", "a"))
+
+		friends, _ := svc.AreFriends(context.Background(), "a", "b")
+		assert.True(t, friends, "Users should be friends")
+		friends, _ = svc.AreFriends(context.Background(), "b", "a")
+		assert.True(t, friends, "Users should be friends")
+
+		requests, _ = svc.GetFriendRequests(context.Background(), "b", &PageRequest{})
+		assert.Empty(t, requests.Items, "Friend request should be removed")
+	})
+
+	t.Run("SUCCESS: MUTUAL FRIEND REQUESTS ARE ACCEPTED", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), clock: testClock, logger: testLogger}
+
+		assert.Empty(t, svc.SendFriendRequest(context.Background(), "a", "b"))
+		assert.Empty(t, svc.SendFriendRequest(context.Background(), "b", "a"))
+
+		friends, _ := svc.AreFriends(context.Background(), "a", "b")
+		assert.True(t, friends, "Users should be friends")
+	})
+
+	t.Run("SUCCESS: DECLINE FRIEND REQUEST", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), clock: testClock, logger: testLogger}
+
+		assert.Empty(t, svc.SendFriendRequest(context.Background(), "a", "b"))
+		assert.Empty(t, svc.DeclineFriendRequest(context.Background(), "b", "a"))
+
+		friends, _ := svc.AreFriends(context.Background(), "a", "b")
+		assert.False(t, friends, "Users should not be friends")
+	})
+
+	t.Run("SUCCESS: REMOVE FRIEND", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), clock: testClock, logger: testLogger}
+
+		assert.Empty(t, svc.SendFriendRequest(context.Background(), "a", "b"))
+		assert.Empty(t, svc.AcceptFriendRequest(context.Background(), "b", "a"))
+		assert.Empty(t, svc.RemoveFriend(context.Background(), "a", "b"))
+
+		friends, _ := svc.AreFriends(context.Background(), "b", "a")
+		assert.False(t, friends, "Users should not be friends")
+	})
+
+	t.Run("ERROR: RETURN 404 WHEN ACCEPTING MISSING REQUEST", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), clock: testClock, logger: testLogger}
+
+		err := svc.AcceptFriendRequest(context.Background(), "b", "a")
+
+		assert.Equal(t, 404, err.Code, "Error should be 404")
+	})
+
+	t.Run("ERROR: RETURN 404 WHEN ACCEPTING DECLINED REQUEST", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), clock: testClock, logger: testLogger}
+
+		assert.Empty(t, svc.SendFriendRequest(context.Background(), "a", "b"))
+		assert.Empty(t, svc.DeclineFriendRequest(context.Background(), "b", "a"))
+
+		err := svc.AcceptFriendRequest(context.Background(), "b", "a")
+
+		assert.Equal(t, 404, err.Code, "Error should be 404")
+		friends, _ := svc.AreFriends(context.Background(), "a", "b")
+		assert.False(t, friends, "Users should not be friends")
+	})
+
+	t.Run("ERROR: RETURN 409 WHEN ALREADY FRIENDS", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), clock: testClock, logger: testLogger}
+
+		assert.Empty(t, svc.SendFriendRequest(context.Background(), "a", "b"))
+		assert.Empty(t, svc.AcceptFriendRequest(context.Background(), "b", "a"))
+
+		err := svc.SendFriendRequest(context.Background(), "a", "b")
+
+		assert.Equal(t, 409, err.Code, "Error should be 409")
+	})
+
+	t.Run("ERROR: RETURN 503 WHEN DB READ RETURNS ERROR", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockError{}, clock: testClock, logger: testLogger}
+
+		err := svc.SendFriendRequest(context.Background(), "a", "b")
+
+		assert.Equal(t, 503, err.Code, "Error should be 503")
+	})
+}
+
+func TestBlock(t *testing.T) {
+	t.Run("SUCCESS: BLOCK REMOVES RELATIONSHIPS", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), clock: testClock, logger: testLogger}
+
+		assert.Empty(t, svc.Follow(context.Background(), "a", "b"))
+		assert.Empty(t, svc.Follow(context.Background(), "b", "a"))
+		assert.Empty(t, svc.SendFriendRequest(context.Background(), "a", "b"))
+		assert.Empty(t, svc.AcceptFriendRequest(context.Background(), "b", "a"))
+
+		assert.Empty(t, svc.Block(context.Background(), "a", "b"))
+
+		friends, _ := svc.AreFriends(context.Background(), "a", "b")
+		assert.False(t, friends, "Users should not be friends")
+		following, _ := svc.GetFollowing(context.Background(), "b", &PageRequest{})
+		assert.Empty(t, following.Items, "Follow should be removed")
+
+		blocked, err := svc.GetBlocked(context.Background(), "a", &PageRequest{})
+		assert.Empty(t, err)
+		assert.Len(t, blocked.Items, 1)
+		assert.Equal(t, "b", blocked.Items[0].TargetID)
+	})
+
+	t.Run("SUCCESS: BLOCK APPLIES IN BOTH DIRECTIONS", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), clock: testClock, logger: testLogger}
+
+		assert.Empty(t, svc.Block(context.Background(), "a", "b"))
+
+		blocked, _ := svc.IsBlocked(context.Background(), "b", "a")
+		assert.True(t, blocked, "Users should be blocked")
+
+		set, err := svc.GetBlockedSet(context.Background(), "b")
+		assert.Empty(t, err)
+		assert.Equal(t, map[string]bool{"a": true}, set)
+	})
+
+	t.Run("SUCCESS: UNBLOCK USER", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), clock: testClock, logger: testLogger}
+
+		assert.Empty(t, svc.Block(context.Background(), "a", "b"))
+		assert.Empty(t, svc.Unblock(context.Background(), "a", "b"))
+
+		blocked, _ := svc.IsBlocked(context.Background(), "a", "b")
+		assert.False(t, blocked, "Users should not be blocked")
+	})
+
+	t.Run("ERROR: RETURN 503 WHEN DB TRANSACTION RETURNS ERROR", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockError{}, clock: testClock, logger: testLogger}
+
+		err := svc.Block(context.Background(), "a", "b")
+
+		assert.Equal(t, 503, err.Code, "Error should be 503")
+	})
+
+	t.Run("ERROR: RETURN 403 WHEN FOLLOWING BLOCKED USER", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), clock: testClock, logger: testLogger}
+
+		assert.Empty(t, svc.Block(context.Background(), "b", "a"))
+
+		assert.Equal(t, 403, svc.Follow(context.Background(), "a", "b").Code, "Error should be 403")
+		assert.Equal(t, 403, svc.SendFriendRequest(context.Background(), "a", "b").Code, "Error should be 403")
+	})
+}
@@ -0,0 +1,103 @@
+package trip
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"speakeasy/pkg"
+	"speakeasy/pkg/imaging"
+)
+
+// COVER_PHOTO_KEY is the storage key of a trip cover photo: trip id and version
+var COVER_PHOTO_KEY string = "trips/%s/covers/%s.jpg"
+
+const (
+	// MaxCoverPhotoSize is the maximum size of an uploaded cover photo in bytes
+	MaxCoverPhotoSize = 10 << 20
+
+	maxCoverPhotoDimension = 2048
+)
+
+// SetCoverPhoto function to validate the uploaded image and store it as the cover photo of the trip,
+// owners and editors can change it. The image is re-encoded as JPEG which strips EXIF data, every cover
+// is stored under a new version and the previous version is deleted once the trip points to the new one.
+// Returns the trip with the signed url of the new cover photo.
+func (service *_Service) SetCoverPhoto(ctx context.Context, userID string, tripID string, file io.Reader) (*Trip, *pkg.Error) {
+	if _, err := service.checkPermission(ctx, tripID, userID, PermissionEdit); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, MaxCoverPhotoSize+1))
+	if err != nil {
+		return nil, &pkg.Error{Code: 400, Reason: "Cover photo can't be read", Err: err}
+	}
+
+	if len(data) > MaxCoverPhotoSize {
+		return nil, &pkg.Error{Code: 413, Reason: "Cover photo is too large"}
+	}
+
+	img, _, err := imaging.Decode(data)
+	if errors.Is(err, imaging.ErrUnsupportedFormat) {
+		return nil, &pkg.Error{Code: 415, Reason: "Cover photo must be a JPEG, PNG or WebP image"}
+	}
+
+	if errors.Is(err, imaging.ErrTooManyPixels) {
+		return nil, &pkg.Error{Code: 413, Reason: "Cover photo dimensions are too large"}
+	}
+
+	if err != nil {
+		return nil, &pkg.Error{Code: 400, Reason: "Cover photo can't be decoded", Err: err}
+	}
+
+	encoded, err := imaging.EncodeJPEG(imaging.Fit(img, maxCoverPhotoDimension))
+	if err != nil {
+		return nil, pkg.Internal("SetCoverPhoto", err)
+	}
+
+	current, perr := service.GetTrip(ctx, tripID)
+	if perr != nil {
+		return nil, perr
+	}
+
+	participants, perr := service.GetTripParticipants(ctx, tripID)
+	if perr != nil {
+		return nil, perr
+	}
+
+	key := fmt.Sprintf(COVER_PHOTO_KEY, tripID, service.ids.NewID())
+	if err := service.storage.UploadFile(ctx, key, bytes.NewReader(encoded), imaging.ContentTypeJPEG); err != nil {
+		return nil, pkg.Unavailable("SetCoverPhoto", err)
+	}
+
+	// Trip details are copied to every reference item, update all of them
+	items := []*Trip{}
+	for _, item := range append([]Trip{*current}, *participants...) {
+		item := item
+		item.CoverPhotoKey = key
+		items = append(items, &item)
+	}
+
+	if err := service.db.Write(ctx, items...); err != nil {
+		service.deleteCoverPhoto(ctx, key)
+		return nil, pkg.Unavailable("SetCoverPhoto", err)
+	}
+
+	if current.CoverPhotoKey != "" {
+		service.deleteCoverPhoto(ctx, current.CoverPhotoKey)
+	}
+
+	current.CoverPhotoKey = key
+	service.setCoverPhotoUrl(current)
+
+	return current, nil
+}
+
+// deleteCoverPhoto deletes the file of a cover photo version, errors are only logged
+// because the trip doesn't point to the file anymore
+func (service *_Service) deleteCoverPhoto(ctx context.Context, key string) {
+	if err := service.storage.DeleteFile(ctx, key); err != nil {
+		service.logger.Warn("delete cover photo failed", "key", key, "error", err.Error())
+	}
+}
@@ -0,0 +1,209 @@
+package trip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"speakeasy/pkg"
+	"speakeasy/pkg/database"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	defaultInviteLinkExpiry = time.Hour * 24 * 7
+	maxInviteLinkExpiry     = time.Hour * 24 * 30
+	inviteTokenType         = "trip_invite"
+)
+
+// CreateInviteLink function to create a signed invite link token, only the owner can create links
+func (service *_Service) CreateInviteLink(ctx context.Context, userID string, tripID string, request *CreateInviteLinkRequest) (*InviteLinkResponse, *pkg.Error) {
+	if request.MaxUses < 0 || request.ExpiresIn < 0 {
+		return nil, &pkg.Error{Code: 400, Reason: "Invite link options are invalid"}
+	}
+
+	expiresIn := defaultInviteLinkExpiry
+	if request.ExpiresIn > 0 {
+		expiresIn = time.Duration(request.ExpiresIn) * time.Second
+	}
+
+	if expiresIn > maxInviteLinkExpiry {
+		return nil, &pkg.Error{Code: 400, Reason: "Invite link expiry is too long"}
+	}
+
+	if _, err := service.checkPermission(ctx, tripID, userID, PermissionManage); err != nil {
+		return nil, err
+	}
+
+	now := service.clock.Now().UTC()
+	id := service.ids.NewID()
+
+	link := InviteLink{
+		PK:        fmt.Sprintf("TRIP#%s", tripID),
+		SK:        fmt.Sprintf("LINK#%s", id),
+		ID:        id,
+		TripID:    tripID,
+		CreatedBy: userID,
+		CreatedAt: now.Format(time.RFC3339),
+		ExpiresAt: now.Add(expiresIn).Format(time.RFC3339),
+		MaxUses:   request.MaxUses,
+	}
+
+	token, err := createInviteToken(service.secret, &link, now.Add(expiresIn))
+	if err != nil {
+		return nil, pkg.Internal("CreateInviteLink", err)
+	}
+
+	if err := service.links.Write(ctx, &link); err != nil {
+		return nil, pkg.Unavailable("CreateInviteLink", err)
+	}
+
+	return link.Response(token), nil
+}
+
+// RevokeInviteLink function to delete invite link so its token can no longer be used
+func (service *_Service) RevokeInviteLink(ctx context.Context, userID string, tripID string, linkID string) *pkg.Error {
+	if _, err := service.checkPermission(ctx, tripID, userID, PermissionManage); err != nil {
+		return err
+	}
+
+	key := map[string]string{
+		"PK": fmt.Sprintf("TRIP#%s", tripID),
+		"SK": fmt.Sprintf("LINK#%s", linkID),
+	}
+
+	if err := service.links.Delete(ctx, key); err != nil {
+		return pkg.Unavailable("RevokeInviteLink", err)
+	}
+
+	return nil
+}
+
+// JoinTrip function to add user as a trip participant using an invite link token,
+// canJoin is called before the user is added and can reject the invitation, e.g. blocked users.
+func (service *_Service) JoinTrip(ctx context.Context, userID string, token string, canJoin func(trip *Trip, link *InviteLink) *pkg.Error) (*Trip, *pkg.Error) {
+	tripID, linkID, err := verifyInviteToken(service.secret, token)
+	if err != nil {
+		return nil, &pkg.Error{Code: 400, Reason: "Invite link is invalid", Err: err}
+	}
+
+	input := map[string]string{
+		"PK": fmt.Sprintf("TRIP#%s", tripID),
+		"SK": fmt.Sprintf("LINK#%s", linkID),
+	}
+
+	link, err := service.links.Get(ctx, input)
+	if err != nil {
+		return nil, pkg.Unavailable("JoinTrip", err)
+	}
+
+	if link == nil {
+		return nil, &pkg.Error{Code: 400, Reason: "Invite link is invalid"}
+	}
+
+	if expiresAt, err := time.Parse(time.RFC3339, link.ExpiresAt); err != nil || service.clock.Now().After(expiresAt) {
+		return nil, &pkg.Error{Code: 410, Reason: "Invite link has expired"}
+	}
+
+	// Checked again when the use is counted, concurrent joins may use up the link in between
+	if link.MaxUses > 0 && link.Uses >= link.MaxUses {
+		return nil, &pkg.Error{Code: 410, Reason: "Invite link has been used up"}
+	}
+
+	participant, perr := service.getParticipant(ctx, tripID, userID)
+	if perr != nil {
+		return nil, perr
+	}
+
+	if participant != nil {
+		return nil, &pkg.Error{Code: 409, Reason: "Already a participant"}
+	}
+
+	trip, perr := service.GetTrip(ctx, tripID)
+	if perr != nil {
+		return nil, perr
+	}
+
+	if canJoin != nil {
+		if err := canJoin(trip, link); err != nil {
+			return nil, err
+		}
+	}
+
+	// Same reference item GetTripParticipants and GetTripsByUser read
+	userTrip := *trip
+	userTrip.PK = fmt.Sprintf("USER#%s", userID)
+	userTrip.ParticipantID = userID
+	userTrip.Role = RoleViewer
+	setPublicIndexFields(&userTrip)
+
+	// The use is counted in the same transaction which adds the participant, the conditions fail
+	// if the user joined or the link was revoked or used up since they were read
+	err = service.links.Transaction(ctx,
+		database.TransactionItem{Put: &userTrip, Condition: "attribute_not_exists(PK)"},
+		database.TransactionItem{
+			Update: input,
+			Changes: &database.Update{
+				Add:       map[string]interface{}{"uses": 1},
+				Condition: "attribute_exists(PK) AND (attribute_not_exists(#max) OR #max = :zero OR #count < #max)",
+				Names:     map[string]string{"#max": "max_uses", "#count": "uses"},
+				Values:    map[string]interface{}{":zero": 0},
+			},
+		},
+	)
+
+	var conditionErr *database.ConditionError
+	if errors.As(err, &conditionErr) && conditionErr.Index == 0 {
+		return nil, &pkg.Error{Code: 409, Reason: "Already a participant", Err: err}
+	}
+
+	if errors.Is(err, database.ErrConditionFailed) {
+		return nil, &pkg.Error{Code: 410, Reason: "Invite link has been used up or revoked", Err: err}
+	}
+
+	if err != nil {
+		return nil, pkg.Unavailable("JoinTrip", err)
+	}
+
+	return trip, nil
+}
+
+// createInviteToken function to sign invite link token
+func createInviteToken(secret string, link *InviteLink, expiresAt time.Time) (string, error) {
+	claims := jwt.MapClaims{}
+	claims["type"] = inviteTokenType
+	claims["trip_id"] = link.TripID
+	claims["link_id"] = link.ID
+	claims["exp"] = expiresAt.Unix()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+// verifyInviteToken function to verify invite link token, returns trip and link id
+func verifyInviteToken(secret string, tokenString string) (string, string, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+
+	if err != nil {
+		return "", "", err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["type"] != inviteTokenType {
+		return "", "", fmt.Errorf("invalid invite token")
+	}
+
+	tripID, _ := claims["trip_id"].(string)
+	linkID, _ := claims["link_id"].(string)
+	if tripID == "" || linkID == "" {
+		return "", "", fmt.Errorf("invalid invite token claims")
+	}
+
+	return tripID, linkID, nil
+}
@@ -39,19 +39,244 @@ type Participant struct {
 }
 
 type Location struct {
-	City    string `json:"city"`
-	State   string `json:"state"`
-	Country string `json:"country"`
+	City    string `json:"city" binding:"max=100"`
+	State   string `json:"state" binding:"max=100"`
+	Country string `json:"country" binding:"max=100"`
 }
 
+// Role of a participant within a trip
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+// Permission is an action a participant can perform on a trip
+type Permission int
+
+const (
+	// PermissionView allows reading the trip and its participants
+	PermissionView Permission = iota
+	// PermissionEdit allows updating trip details
+	PermissionEdit
+	// PermissionManage allows managing participants and ownership
+	PermissionManage
+)
+
+// Can returns true if the role is allowed to perform the permission
+func (role Role) Can(permission Permission) bool {
+	switch role {
+	case RoleOwner:
+		return true
+	case RoleEditor:
+		return permission <= PermissionEdit
+	case RoleViewer:
+		return permission == PermissionView
+	default:
+		return false
+	}
+}
+
+// Valid returns true if role is one of the known roles
+func (role Role) Valid() bool {
+	return role == RoleOwner || role == RoleEditor || role == RoleViewer
+}
+
+// Visibility of a trip to users who are not participants
+type Visibility string
+
+const (
+	VisibilityPrivate Visibility = "private"
+	VisibilityFriends Visibility = "friends"
+	VisibilityPublic  Visibility = "public"
+)
+
+// Valid returns true if visibility is one of the known visibilities
+func (visibility Visibility) Valid() bool {
+	return visibility == VisibilityPrivate || visibility == VisibilityFriends || visibility == VisibilityPublic
+}
+
+// PUBLIC_TRIPS_PK is the APPLICATION_GSI_2 partition key of public trips
+var PUBLIC_TRIPS_PK string = "TRIP#PUBLIC"
+
+// Trip object to store in database, it is never sent to clients, see TripResponse.
+// The trip item has PK and SK in the format of TRIP#<id>, each participant
+// has a reference item copy with PK in the format of USER#<user id>, and
+// Role and ParticipantID only set on the reference items.
 type Trip struct {
-	PK          string   `json:"PK"`
-	SK          string   `json:"SK"`
-	ID          string   `json:"id"`
-	CreatedBy   string   `json:"created_by"`
-	FromDate    string   `json:"from_date"`
-	ToDate      string   `json:"to_date"`
-	Name        string   `json:"name"`
-	Description string   `json:"description"`
-	Location    Location `json:"location"`
+	PK            string     `json:"PK"`
+	SK            string     `json:"SK"`
+	ID            string     `json:"id"`
+	CreatedBy     string     `json:"created_by"`
+	FromDate      string     `json:"from_date"`
+	ToDate        string     `json:"to_date"`
+	Name          string     `json:"name"`
+	Description   string     `json:"description"`
+	Location      Location   `json:"location"`
+	Visibility    Visibility `json:"visibility,omitempty"`
+	ParticipantID string     `json:"participant_id,omitempty"`
+	Role          Role       `json:"role,omitempty"`
+
+	// CoverPhotoKey is the storage key of the cover photo, it can only be changed with SetCoverPhoto.
+	// CoverPhotoUrl is a short-lived url signed from it when the trip is read.
+	CoverPhotoKey string `json:"-" dynamodbav:"cover_photo_key,omitempty"`
+	CoverPhotoUrl string `json:"cover_photo_url,omitempty" dynamodbav:"-"`
+
+	// NormalizedName is only stored for searching by name
+	NormalizedName string `json:"-" dynamodbav:"normalized_name,omitempty"`
+
+	// GSI2PK and GSI2SK are only set on public trip items to list them by from date
+	GSI2PK string `json:"-" dynamodbav:"GSI2PK,omitempty"`
+	GSI2SK string `json:"-" dynamodbav:"GSI2SK,omitempty"`
+}
+
+// TripRequest object which is the request to create or update a trip,
+// it only contains the fields the user can set
+type TripRequest struct {
+	FromDate    string     `json:"from_date" binding:"required,rfc3339"`
+	ToDate      string     `json:"to_date" binding:"required,rfc3339"`
+	Name        string     `json:"name" binding:"required,notblank,max=100"`
+	Description string     `json:"description" binding:"max=2000"`
+	Location    Location   `json:"location"`
+	Visibility  Visibility `json:"visibility,omitempty" binding:"omitempty,oneof=private friends public"`
+}
+
+// Trip returns trip with the requested fields
+func (request *TripRequest) Trip() *Trip {
+	return &Trip{
+		FromDate:    request.FromDate,
+		ToDate:      request.ToDate,
+		Name:        request.Name,
+		Description: request.Description,
+		Location:    request.Location,
+		Visibility:  request.Visibility,
+	}
+}
+
+// TripResponse object which contains the trip fields sent to clients,
+// ParticipantID and Role are only set on participants.
+type TripResponse struct {
+	ID            string     `json:"id"`
+	CreatedBy     string     `json:"created_by"`
+	FromDate      string     `json:"from_date"`
+	ToDate        string     `json:"to_date"`
+	Name          string     `json:"name"`
+	Description   string     `json:"description"`
+	Location      Location   `json:"location"`
+	Visibility    Visibility `json:"visibility,omitempty"`
+	CoverPhotoUrl string     `json:"cover_photo_url,omitempty"`
+	ParticipantID string     `json:"participant_id,omitempty"`
+	Role          Role       `json:"role,omitempty"`
+}
+
+// Response returns trip as sent to clients
+func (trip *Trip) Response() TripResponse {
+	return TripResponse{
+		ID:            trip.ID,
+		CreatedBy:     trip.CreatedBy,
+		FromDate:      trip.FromDate,
+		ToDate:        trip.ToDate,
+		Name:          trip.Name,
+		Description:   trip.Description,
+		Location:      trip.Location,
+		Visibility:    trip.Visibility,
+		CoverPhotoUrl: trip.CoverPhotoUrl,
+		ParticipantID: trip.ParticipantID,
+		Role:          trip.Role,
+	}
+}
+
+// TripResponses returns trips as sent to clients
+func TripResponses(trips []Trip) []TripResponse {
+	responses := make([]TripResponse, 0, len(trips))
+	for i := range trips {
+		responses = append(responses, trips[i].Response())
+	}
+
+	return responses
+}
+
+// PublicTripFilter object which contains the filters and page for public trips
+type PublicTripFilter struct {
+	City    string `form:"city"`
+	Country string `form:"country"`
+	Cursor  string `form:"cursor"`
+	Limit   int64  `form:"limit" binding:"min=0"`
+}
+
+// TripFilter object which contains the search filters for trips.
+// From and To accept RFC3339 timestamps or dates in the format of YYYY-MM-DD.
+type TripFilter struct {
+	From    string `form:"from"`
+	To      string `form:"to"`
+	City    string `form:"city"`
+	Country string `form:"country"`
+	Query   string `form:"q"`
+}
+
+// TripSearchResult object which contains trips split by their dates
+type TripSearchResult struct {
+	Upcoming []TripResponse `json:"upcoming"`
+	Ongoing  []TripResponse `json:"ongoing"`
+	Past     []TripResponse `json:"past"`
+}
+
+// UpdateRoleRequest object which is the request to change a participant role
+type UpdateRoleRequest struct {
+	Role Role `json:"role" binding:"required,oneof=owner editor viewer"`
+}
+
+// TransferOwnershipRequest object which is the request to transfer trip ownership
+type TransferOwnershipRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+}
+
+// InviteLink object to store in database.
+// PK should be TRIP#<trip id> and SK should be LINK#<link id>,
+// a deleted link item means the link has been revoked.
+type InviteLink struct {
+	PK        string `json:"PK"`
+	SK        string `json:"SK"`
+	ID        string `json:"id"`
+	TripID    string `json:"trip_id"`
+	CreatedBy string `json:"created_by"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at"`
+	MaxUses   int    `json:"max_uses,omitempty"`
+	Uses      int    `json:"uses"`
+}
+
+// CreateInviteLinkRequest object which is the request to create an invite link.
+// ExpiresIn is in seconds, MaxUses of zero means the link can be used any number of times.
+type CreateInviteLinkRequest struct {
+	ExpiresIn int `json:"expires_in" binding:"min=0"`
+	MaxUses   int `json:"max_uses" binding:"min=0"`
+}
+
+// InviteLinkResponse object which is the response for CreateInviteLink function
+type InviteLinkResponse struct {
+	ID        string `json:"id"`
+	TripID    string `json:"trip_id"`
+	CreatedBy string `json:"created_by"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at"`
+	MaxUses   int    `json:"max_uses,omitempty"`
+	Uses      int    `json:"uses"`
+	Token     string `json:"token"`
+}
+
+// Response returns invite link with its token as sent to clients
+func (link *InviteLink) Response(token string) *InviteLinkResponse {
+	return &InviteLinkResponse{
+		ID:        link.ID,
+		TripID:    link.TripID,
+		CreatedBy: link.CreatedBy,
+		CreatedAt: link.CreatedAt,
+		ExpiresAt: link.ExpiresAt,
+		MaxUses:   link.MaxUses,
+		Uses:      link.Uses,
+		Token:     token,
+	}
 }
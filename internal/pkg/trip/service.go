@@ -1,112 +1,147 @@
 package trip
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"sort"
 	"speakeasy/pkg"
+	"speakeasy/pkg/clock"
 	"speakeasy/pkg/database"
+	"speakeasy/pkg/filestorage"
+	"speakeasy/pkg/ids"
 	"strings"
 	"time"
+)
 
-	"github.com/google/uuid"
+const (
+	defaultPublicTripsLimit = 20
+	maxPublicTripsLimit     = 100
 )
 
 type _Service struct {
-	db database.Service[Trip]
+	db      database.Service[Trip]
+	links   database.Service[InviteLink]
+	storage filestorage.Service
+	secret  string
+	clock   clock.Clock
+	ids     ids.Generator
+	logger  *slog.Logger
 }
 
-// NewTripService returns _Service object
-func NewTripService() Service {
-	db := database.NewDatabaseService[Trip]("APPLICATION")
-
+// NewTripService returns _Service object, invite link tokens are signed with the invite secret
+func NewTripService(
+	db database.Service[Trip],
+	links database.Service[InviteLink],
+	storage filestorage.Service,
+	inviteSecret string,
+	clock clock.Clock,
+	ids ids.Generator,
+	logger *slog.Logger,
+) Service {
 	return &_Service{
 		db,
+		links,
+		storage,
+		inviteSecret,
+		clock,
+		ids,
+		logger,
 	}
 }
 
 // Service interface which contains trip operations
 type Service interface {
-	CreateTrip(trip *Trip) *pkg.Error
-	GetTrip(tripID string) (*Trip, *pkg.Error)
-	GetTripsByUser(userID string) (*[]Trip, *pkg.Error)
-	GetTripParticipants(tripID string) (*[]Trip, *pkg.Error)
+	CreateTrip(ctx context.Context, trip *Trip) *pkg.Error
+	GetTrip(ctx context.Context, tripID string) (*Trip, *pkg.Error)
+	GetTripsByUser(ctx context.Context, userID string) (*[]Trip, *pkg.Error)
+	SearchTripsByUser(ctx context.Context, userID string, filter *TripFilter) (*TripSearchResult, *pkg.Error)
+	GetPublicTrips(ctx context.Context, filter *PublicTripFilter) (*database.Page[Trip], *pkg.Error)
+	GetTripParticipants(ctx context.Context, tripID string) (*[]Trip, *pkg.Error)
+	UpdateTrip(ctx context.Context, userID string, trip *Trip) *pkg.Error
+	UpdateParticipantRole(ctx context.Context, userID string, tripID string, participantID string, role Role) *pkg.Error
+	TransferOwnership(ctx context.Context, userID string, tripID string, newOwnerID string) *pkg.Error
+	RemoveParticipant(ctx context.Context, userID string, tripID string, participantID string) *pkg.Error
+	CreateInviteLink(ctx context.Context, userID string, tripID string, request *CreateInviteLinkRequest) (*InviteLinkResponse, *pkg.Error)
+	RevokeInviteLink(ctx context.Context, userID string, tripID string, linkID string) *pkg.Error
+	JoinTrip(ctx context.Context, userID string, token string, canJoin func(trip *Trip, link *InviteLink) *pkg.Error) (*Trip, *pkg.Error)
+	CheckPermission(ctx context.Context, tripID string, userID string, permission Permission) (*Trip, *pkg.Error)
+	SetCoverPhoto(ctx context.Context, userID string, tripID string, file io.Reader) (*Trip, *pkg.Error)
 }
 
 // CreateTrip function to create trip
-func (service *_Service) CreateTrip(trip *Trip) *pkg.Error {
+func (service *_Service) CreateTrip(ctx context.Context, trip *Trip) *pkg.Error {
 	// Validate create trip request
 	if len(strings.TrimSpace(trip.CreatedBy)) == 0 {
 		return &pkg.Error{Code: 400, Reason: "User ID cannot be empty"}
 	}
 
-	if len(strings.TrimSpace(trip.Name)) == 0 {
-		return &pkg.Error{Code: 400, Reason: "Trip name cannot be empty"}
-	}
-
-	// Validate trip dates
-	from, err := time.Parse(time.RFC3339, trip.FromDate)
-	if err != nil {
-		log.Println("CreateTripError:", err)
-		return &pkg.Error{Code: 400, Reason: "Trip dates are invalid"}
-	}
-
-	to, err := time.Parse(time.RFC3339, trip.ToDate)
-	if err != nil {
-		log.Println("CreateTripError:", err)
-		return &pkg.Error{Code: 400, Reason: "Trip dates are invalid"}
+	from, to, validationErr := validateTrip(trip)
+	if validationErr != nil {
+		return validationErr
 	}
 
-	if from.Before(time.Now()) || to.Before(time.Now()) {
-		log.Println("CreateTripError: dates are in the past")
+	now := service.clock.Now()
+	if from.Before(now) || to.Before(now) {
 		return &pkg.Error{Code: 400, Reason: "Trip dates are invalid"}
 	}
 
-	if from.After(to) {
-		log.Println("CreateTripError: from_date if after to_date ")
-		return &pkg.Error{Code: 400, Reason: "Trip dates are invalid"}
-	}
+	setSearchFields(trip, from, to)
 
 	// Add primary and sort key to item
-	uid := uuid.New().String()
+	uid := service.ids.NewID()
 	trip.ID = uid
 	trip.PK = fmt.Sprintf("TRIP#%s", uid)
 	trip.SK = fmt.Sprintf("TRIP#%s", uid)
 
-	// Create another item for user reference
+	if trip.Visibility == "" {
+		trip.Visibility = VisibilityPrivate
+	}
+	setPublicIndexFields(trip)
+
+	// Cover photo is set after uploading it
+	trip.CoverPhotoKey = ""
+	trip.CoverPhotoUrl = ""
+
+	// Create another item for user reference, creator is the trip owner
 	userTrip := *trip
 	userTrip.PK = fmt.Sprintf("USER#%s", userTrip.CreatedBy)
+	userTrip.ParticipantID = userTrip.CreatedBy
+	userTrip.Role = RoleOwner
+	setPublicIndexFields(&userTrip)
 
-	err = service.db.Write(trip, &userTrip)
+	err := service.db.Write(ctx, trip, &userTrip)
 	if err != nil {
-		log.Println("CreateTripError:", err)
-		return &pkg.Error{Code: 503, Reason: "Internal Server Error"}
+		return pkg.Unavailable("CreateTrip", err)
 	}
 
 	return nil
 }
 
 // GetTrip function to get trip by id
-func (service *_Service) GetTrip(tripID string) (*Trip, *pkg.Error) {
+func (service *_Service) GetTrip(ctx context.Context, tripID string) (*Trip, *pkg.Error) {
 	input := map[string]string{
 		"PK": fmt.Sprintf("TRIP#%s", tripID),
 		"SK": fmt.Sprintf("TRIP#%s", tripID),
 	}
 
-	result, err := service.db.Get(input)
+	result, err := service.db.Get(ctx, input)
 	if err != nil {
-		log.Println("GetTrip:", err)
-		return nil, &pkg.Error{Code: 503, Reason: "Internal Server Error"}
+		return nil, pkg.Unavailable("GetTrip", err)
 	}
 
 	if result == nil {
-		log.Println("GetTrip: item not found")
 		return nil, &pkg.Error{Code: 400, Reason: "Trip not found"}
 	}
 
+	service.setCoverPhotoUrl(result)
+
 	return result, nil
 }
 
-func (service *_Service) GetTripsByUser(userID string) (*[]Trip, *pkg.Error) {
+func (service *_Service) GetTripsByUser(ctx context.Context, userID string) (*[]Trip, *pkg.Error) {
 	filter := map[string]string{
 		":PK": fmt.Sprintf("USER#%s", userID),
 		":SK": "TRIP",
@@ -114,31 +149,506 @@ func (service *_Service) GetTripsByUser(userID string) (*[]Trip, *pkg.Error) {
 
 	condition := "PK = :PK And begins_with(SK, :SK)"
 
-	results, err := service.db.Query(filter, condition)
+	results, err := service.db.Query(ctx, filter, condition)
 	if err != nil {
-		log.Println("GetTripsByUser:", err)
-		return nil, &pkg.Error{Code: 503, Reason: "Internal Server Error"}
+		return nil, pkg.Unavailable("GetTripsByUser", err)
 	}
 
+	service.setCoverPhotoUrls(*results)
+
 	return results, nil
 }
 
-func (service *_Service) GetTripParticipants(tripID string) (*[]Trip, *pkg.Error) {
+// SearchTripsByUser function to filter user trips by dates, location and name,
+// trips are split into upcoming, ongoing and past trips.
+func (service *_Service) SearchTripsByUser(ctx context.Context, userID string, filter *TripFilter) (*TripSearchResult, *pkg.Error) {
+	values := map[string]string{
+		":PK": fmt.Sprintf("USER#%s", userID),
+		":SK": "TRIP",
+	}
+	names := map[string]string{}
+	expressions := []string{}
+
+	// Trips overlapping the requested date range
+	if filter.From != "" {
+		from, err := parseFilterDate(filter.From, false)
+		if err != nil {
+			return nil, &pkg.Error{Code: 400, Reason: "From date is invalid"}
+		}
+
+		values[":from"] = from
+		expressions = append(expressions, "to_date >= :from")
+	}
+
+	if filter.To != "" {
+		to, err := parseFilterDate(filter.To, true)
+		if err != nil {
+			return nil, &pkg.Error{Code: 400, Reason: "To date is invalid"}
+		}
+
+		values[":to"] = to
+		expressions = append(expressions, "from_date <= :to")
+	}
+
+	if filter.City != "" {
+		values[":city"] = filter.City
+		names["#location"] = "location"
+		names["#city"] = "city"
+		expressions = append(expressions, "#location.#city = :city")
+	}
+
+	if filter.Country != "" {
+		values[":country"] = filter.Country
+		names["#location"] = "location"
+		names["#country"] = "country"
+		expressions = append(expressions, "#location.#country = :country")
+	}
+
+	if query := pkg.NormalizeText(filter.Query); query != "" {
+		values[":q"] = query
+		expressions = append(expressions, "contains(normalized_name, :q)")
+	}
+
+	condition := "PK = :PK And begins_with(SK, :SK)"
+
+	results, err := service.db.QueryWithFilter(ctx, values, condition, strings.Join(expressions, " And "), names)
+	if err != nil {
+		return nil, pkg.Unavailable("SearchTripsByUser", err)
+	}
+
+	service.setCoverPhotoUrls(*results)
+
+	return service.splitTripsByDate(*results, service.clock.Now()), nil
+}
+
+// GetPublicTrips function to list upcoming public trips sorted by from date
+func (service *_Service) GetPublicTrips(ctx context.Context, filter *PublicTripFilter) (*database.Page[Trip], *pkg.Error) {
+	if filter.Limit < 0 || filter.Limit > maxPublicTripsLimit {
+		return nil, &pkg.Error{Code: 400, Reason: "Limit is invalid"}
+	}
+
+	options := &database.QueryOptions{
+		Index:  "APPLICATION_GSI_2",
+		Names:  map[string]string{},
+		Limit:  filter.Limit,
+		Cursor: filter.Cursor,
+	}
+
+	if options.Limit == 0 {
+		options.Limit = defaultPublicTripsLimit
+	}
+
+	values := map[string]string{
+		":PK":  PUBLIC_TRIPS_PK,
+		":now": service.clock.Now().UTC().Format(time.RFC3339),
+	}
+	expressions := []string{}
+
+	if filter.City != "" {
+		values[":city"] = filter.City
+		options.Names["#location"] = "location"
+		options.Names["#city"] = "city"
+		expressions = append(expressions, "#location.#city = :city")
+	}
+
+	if filter.Country != "" {
+		values[":country"] = filter.Country
+		options.Names["#location"] = "location"
+		options.Names["#country"] = "country"
+		expressions = append(expressions, "#location.#country = :country")
+	}
+
+	options.FilterExpression = strings.Join(expressions, " And ")
+	condition := "GSI2PK = :PK And GSI2SK >= :now"
+
+	page, err := service.db.QueryPage(ctx, values, condition, options)
+	if errors.Is(err, database.ErrInvalidCursor) {
+		return nil, &pkg.Error{Code: 400, Reason: "Cursor is invalid"}
+	}
+
+	if err != nil {
+		return nil, pkg.Unavailable("GetPublicTrips", err)
+	}
+
+	service.setCoverPhotoUrls(page.Items)
+
+	return page, nil
+}
+
+func (service *_Service) GetTripParticipants(ctx context.Context, tripID string) (*[]Trip, *pkg.Error) {
 	filter := map[string]string{
 		":SK": fmt.Sprintf("TRIP#%s", tripID),
 		":PK": "USER",
 	}
 
-	log.Println(filter)
-
 	condition := "SK = :SK"
 	filterExpr := "begins_with(PK, :PK)"
 
-	results, err := service.db.QueryWithIndex(filter, condition, filterExpr, "APPLICATION_GSI_1")
+	results, err := service.db.QueryWithIndex(ctx, filter, condition, filterExpr, "APPLICATION_GSI_1")
 	if err != nil {
-		log.Println("GetTripParticipants:", err)
-		return nil, &pkg.Error{Code: 503, Reason: "Internal Server Error"}
+		return nil, pkg.Unavailable("GetTripParticipants", err)
 	}
 
 	return results, nil
 }
+
+// UpdateTrip function to update trip details, user must be allowed to edit the trip
+func (service *_Service) UpdateTrip(ctx context.Context, userID string, trip *Trip) *pkg.Error {
+	if _, err := service.checkPermission(ctx, trip.ID, userID, PermissionEdit); err != nil {
+		return err
+	}
+
+	from, to, validationErr := validateTrip(trip)
+	if validationErr != nil {
+		return validationErr
+	}
+
+	setSearchFields(trip, from, to)
+
+	current, err := service.GetTrip(ctx, trip.ID)
+	if err != nil {
+		return err
+	}
+
+	participants, err := service.GetTripParticipants(ctx, trip.ID)
+	if err != nil {
+		return err
+	}
+
+	if trip.Visibility == "" {
+		trip.Visibility = current.Visibility
+	}
+
+	// Trip details are copied to every reference item, update all of them
+	items := []*Trip{}
+	for _, item := range append([]Trip{*current}, *participants...) {
+		item := item
+		item.Name = trip.Name
+		item.Description = trip.Description
+		item.FromDate = trip.FromDate
+		item.ToDate = trip.ToDate
+		item.Location = trip.Location
+		item.Visibility = trip.Visibility
+		item.NormalizedName = trip.NormalizedName
+		setPublicIndexFields(&item)
+		items = append(items, &item)
+	}
+
+	if err := service.db.Write(ctx, items...); err != nil {
+		return pkg.Unavailable("UpdateTrip", err)
+	}
+
+	return nil
+}
+
+// UpdateParticipantRole function to change the role of a participant, only the owner can change roles
+func (service *_Service) UpdateParticipantRole(ctx context.Context, userID string, tripID string, participantID string, role Role) *pkg.Error {
+	if role != RoleEditor && role != RoleViewer {
+		return &pkg.Error{Code: 400, Reason: "Role is invalid"}
+	}
+
+	if userID == participantID {
+		return &pkg.Error{Code: 400, Reason: "Cannot change your own role"}
+	}
+
+	if _, err := service.checkPermission(ctx, tripID, userID, PermissionManage); err != nil {
+		return err
+	}
+
+	participant, err := service.getParticipant(ctx, tripID, participantID)
+	if err != nil {
+		return err
+	}
+
+	if participant == nil {
+		return &pkg.Error{Code: 404, Reason: "Participant not found"}
+	}
+
+	participant.Role = role
+
+	if err := service.db.Write(ctx, participant); err != nil {
+		return pkg.Unavailable("UpdateParticipantRole", err)
+	}
+
+	return nil
+}
+
+// TransferOwnership function to make another participant the owner, previous owner becomes an editor.
+// CreatedBy decides who can see the trip, so it follows the owner on the trip item and every reference item.
+func (service *_Service) TransferOwnership(ctx context.Context, userID string, tripID string, newOwnerID string) *pkg.Error {
+	if userID == newOwnerID {
+		return &pkg.Error{Code: 400, Reason: "You are already the owner"}
+	}
+
+	owner, err := service.checkPermission(ctx, tripID, userID, PermissionManage)
+	if err != nil {
+		return err
+	}
+
+	newOwner, err := service.getParticipant(ctx, tripID, newOwnerID)
+	if err != nil {
+		return err
+	}
+
+	if newOwner == nil {
+		return &pkg.Error{Code: 404, Reason: "Participant not found"}
+	}
+
+	participants, err := service.GetTripParticipants(ctx, tripID)
+	if err != nil {
+		return err
+	}
+
+	// Every item must still exist and belong to the owner, otherwise the trip was changed by another request
+	transfer := func(pk string, role Role) database.TransactionItem {
+		set := map[string]interface{}{"created_by": newOwnerID}
+		if role != "" {
+			set["role"] = role
+		}
+
+		return database.TransactionItem{
+			Update: map[string]string{
+				"PK": pk,
+				"SK": fmt.Sprintf("TRIP#%s", tripID),
+			},
+			Changes: &database.Update{
+				Set:       set,
+				Condition: "attribute_exists(PK) And created_by = :created_by",
+				Values:    map[string]interface{}{":created_by": userID},
+			},
+		}
+	}
+
+	items := []database.TransactionItem{
+		transfer(fmt.Sprintf("TRIP#%s", tripID), ""),
+		transfer(owner.PK, RoleEditor),
+		transfer(newOwner.PK, RoleOwner),
+	}
+
+	for _, participant := range *participants {
+		if participant.PK != owner.PK && participant.PK != newOwner.PK {
+			items = append(items, transfer(participant.PK, ""))
+		}
+	}
+
+	transactionErr := service.db.Transaction(ctx, items...)
+	if errors.Is(transactionErr, database.ErrConditionFailed) {
+		return &pkg.Error{Code: 409, Reason: "Trip was changed, please try again"}
+	}
+
+	if transactionErr != nil {
+		return pkg.Unavailable("TransferOwnership", transactionErr)
+	}
+
+	return nil
+}
+
+// RemoveParticipant function to remove participant from trip.
+// The owner can remove anyone else, and participants can remove themselves.
+func (service *_Service) RemoveParticipant(ctx context.Context, userID string, tripID string, participantID string) *pkg.Error {
+	participant, err := service.getParticipant(ctx, tripID, participantID)
+	if err != nil {
+		return err
+	}
+
+	if participant == nil {
+		return &pkg.Error{Code: 404, Reason: "Participant not found"}
+	}
+
+	if userID == participantID {
+		if participant.Role == RoleOwner {
+			return &pkg.Error{Code: 400, Reason: "Owner must transfer ownership before leaving the trip"}
+		}
+	} else if _, err := service.checkPermission(ctx, tripID, userID, PermissionManage); err != nil {
+		return err
+	}
+
+	key := map[string]string{
+		"PK": participant.PK,
+		"SK": participant.SK,
+	}
+
+	if err := service.db.Delete(ctx, key); err != nil {
+		return pkg.Unavailable("RemoveParticipant", err)
+	}
+
+	return nil
+}
+
+// CheckPermission function to check if user is a participant whose role allows the permission,
+// returns the user's trip reference item.
+func (service *_Service) CheckPermission(ctx context.Context, tripID string, userID string, permission Permission) (*Trip, *pkg.Error) {
+	return service.checkPermission(ctx, tripID, userID, permission)
+}
+
+// checkPermission returns the user's trip reference item if their role allows the permission
+func (service *_Service) checkPermission(ctx context.Context, tripID string, userID string, permission Permission) (*Trip, *pkg.Error) {
+	participant, err := service.getParticipant(ctx, tripID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if participant == nil || !participant.Role.Can(permission) {
+		service.logger.Debug("permission denied", "user_id", userID, "trip_id", tripID, "permission", permission)
+		return nil, &pkg.Error{Code: 403, Reason: "Forbidden"}
+	}
+
+	return participant, nil
+}
+
+// getParticipant returns the user's trip reference item, or nil if the user is not a participant
+func (service *_Service) getParticipant(ctx context.Context, tripID string, userID string) (*Trip, *pkg.Error) {
+	input := map[string]string{
+		"PK": fmt.Sprintf("USER#%s", userID),
+		"SK": fmt.Sprintf("TRIP#%s", tripID),
+	}
+
+	result, err := service.db.Get(ctx, input)
+	if err != nil {
+		return nil, pkg.Unavailable("getParticipant", err)
+	}
+
+	if result == nil {
+		return nil, nil
+	}
+
+	// Reference items created before roles existed only belong to the creator
+	if result.ParticipantID == "" {
+		result.ParticipantID = userID
+	}
+
+	if result.Role == "" && result.CreatedBy == userID {
+		result.Role = RoleOwner
+	} else if result.Role == "" {
+		result.Role = RoleViewer
+	}
+
+	return result, nil
+}
+
+// validateTrip validates trip name and dates, returns the parsed dates
+func validateTrip(trip *Trip) (time.Time, time.Time, *pkg.Error) {
+	if len(strings.TrimSpace(trip.Name)) == 0 {
+		return time.Time{}, time.Time{}, &pkg.Error{Code: 400, Reason: "Trip name cannot be empty"}
+	}
+
+	if trip.Visibility != "" && !trip.Visibility.Valid() {
+		return time.Time{}, time.Time{}, &pkg.Error{Code: 400, Reason: "Trip visibility is invalid"}
+	}
+
+	// Validate trip dates
+	from, err := time.Parse(time.RFC3339, trip.FromDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, &pkg.Error{Code: 400, Reason: "Trip dates are invalid", Err: err}
+	}
+
+	to, err := time.Parse(time.RFC3339, trip.ToDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, &pkg.Error{Code: 400, Reason: "Trip dates are invalid", Err: err}
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, &pkg.Error{Code: 400, Reason: "Trip dates are invalid"}
+	}
+
+	return from, to, nil
+}
+
+// setCoverPhotoUrl signs a short-lived url for the stored cover photo key,
+// the url is left empty when it can't be signed.
+func (service *_Service) setCoverPhotoUrl(trip *Trip) {
+	trip.CoverPhotoUrl = ""
+	if trip.CoverPhotoKey == "" {
+		return
+	}
+
+	url, err := service.storage.GetDownloadUrl(trip.CoverPhotoKey)
+	if err != nil {
+		service.logger.Warn("sign cover photo url failed", "trip_id", trip.ID, "error", err.Error())
+		return
+	}
+
+	trip.CoverPhotoUrl = url
+}
+
+// setCoverPhotoUrls signs cover photo urls of all trips
+func (service *_Service) setCoverPhotoUrls(trips []Trip) {
+	for i := range trips {
+		service.setCoverPhotoUrl(&trips[i])
+	}
+}
+
+// setPublicIndexFields adds public trip items to APPLICATION_GSI_2, reference items are never indexed
+func setPublicIndexFields(trip *Trip) {
+	if trip.PK == trip.SK && trip.Visibility == VisibilityPublic {
+		trip.GSI2PK = PUBLIC_TRIPS_PK
+		trip.GSI2SK = trip.FromDate
+	} else {
+		trip.GSI2PK = ""
+		trip.GSI2SK = ""
+	}
+}
+
+// setSearchFields stores dates in UTC so they can be compared as strings, and sets normalized name
+func setSearchFields(trip *Trip, from time.Time, to time.Time) {
+	trip.FromDate = from.UTC().Format(time.RFC3339)
+	trip.ToDate = to.UTC().Format(time.RFC3339)
+	trip.NormalizedName = pkg.NormalizeText(trip.Name)
+}
+
+// parseFilterDate parses RFC3339 timestamp or date, a date is the end of the day if endOfDay is set
+func parseFilterDate(value string, endOfDay bool) (string, error) {
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date.UTC().Format(time.RFC3339), nil
+	}
+
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return "", err
+	}
+
+	if endOfDay {
+		date = date.Add(time.Hour*24 - time.Second)
+	}
+
+	return date.Format(time.RFC3339), nil
+}
+
+// splitTripsByDate splits trips into upcoming, ongoing and past trips relative to now
+func (service *_Service) splitTripsByDate(trips []Trip, now time.Time) *TripSearchResult {
+	result := &TripSearchResult{
+		Upcoming: []TripResponse{},
+		Ongoing:  []TripResponse{},
+		Past:     []TripResponse{},
+	}
+
+	for _, trip := range trips {
+		from, fromErr := time.Parse(time.RFC3339, trip.FromDate)
+		to, toErr := time.Parse(time.RFC3339, trip.ToDate)
+
+		switch {
+		case fromErr != nil || toErr != nil:
+			service.logger.Warn("trip has invalid dates", "trip_id", trip.ID)
+			result.Past = append(result.Past, trip.Response())
+		case now.Before(from):
+			result.Upcoming = append(result.Upcoming, trip.Response())
+		case now.After(to):
+			result.Past = append(result.Past, trip.Response())
+		default:
+			result.Ongoing = append(result.Ongoing, trip.Response())
+		}
+	}
+
+	// Soonest upcoming and ongoing trips first, most recent past trips first
+	sort.SliceStable(result.Upcoming, func(i, j int) bool {
+		return result.Upcoming[i].FromDate < result.Upcoming[j].FromDate
+	})
+	sort.SliceStable(result.Ongoing, func(i, j int) bool {
+		return result.Ongoing[i].ToDate < result.Ongoing[j].ToDate
+	})
+	sort.SliceStable(result.Past, func(i, j int) bool {
+		return result.Past[i].ToDate > result.Past[j].ToDate
+	})
+
+	return result
+}
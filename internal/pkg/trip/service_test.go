@@ -1,20 +1,41 @@
 package trip
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"image"
+	"image/png"
+	"io"
+	"speakeasy/pkg"
+	"speakeasy/pkg/clock"
 	"speakeasy/pkg/database"
+	"speakeasy/pkg/filestorage"
+	"speakeasy/pkg/ids"
+	"speakeasy/pkg/logging"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// testLogger drops the log records of the services under test
+var testLogger = logging.Discard()
+
+// testClock and testIDs are the real clock and uuid generator, tests which check times or ids set fixed ones
+var (
+	testClock = clock.System()
+	testIDs   = ids.UUID()
+)
+
 // Mock DatabaseService where item exists
 type _DatabaseServiceMockItemExists struct {
 	database.Service[Trip]
 }
 
-func (db *_DatabaseServiceMockItemExists) Get(keyObj interface{}) (*Trip, error) {
+func (db *_DatabaseServiceMockItemExists) Get(ctx context.Context, keyObj interface{}) (*Trip, error) {
 	return &Trip{
 		CreatedBy:   "0000-0000-0000-0000",
 		FromDate:    time.Now().Add(time.Hour * 24).UTC().Format(time.RFC3339),
@@ -29,11 +50,11 @@ func (db *_DatabaseServiceMockItemExists) Get(keyObj interface{}) (*Trip, error)
 	}, nil
 }
 
-func (db *_DatabaseServiceMockItemExists) Write(obj ...*Trip) error {
+func (db *_DatabaseServiceMockItemExists) Write(ctx context.Context, obj ...*Trip) error {
 	return nil
 }
 
-func (db *_DatabaseServiceMockItemExists) Query(filterObj interface{}, condition string) (*[]Trip, error) {
+func (db *_DatabaseServiceMockItemExists) Query(ctx context.Context, filterObj interface{}, condition string) (*[]Trip, error) {
 	return &[]Trip{
 		{
 			CreatedBy:   "0000-0000-0000-0000",
@@ -55,11 +76,11 @@ type _DatabaseServiceMockItemNotFound struct {
 	database.Service[Trip]
 }
 
-func (db *_DatabaseServiceMockItemNotFound) Get(keyObj interface{}) (*Trip, error) {
+func (db *_DatabaseServiceMockItemNotFound) Get(ctx context.Context, keyObj interface{}) (*Trip, error) {
 	return nil, nil
 }
 
-func (db *_DatabaseServiceMockItemNotFound) Write(obj ...*Trip) error {
+func (db *_DatabaseServiceMockItemNotFound) Write(ctx context.Context, obj ...*Trip) error {
 	return nil
 }
 
@@ -68,34 +89,183 @@ type _DatabaseServiceMockGetError struct {
 	database.Service[Trip]
 }
 
-func (db *_DatabaseServiceMockGetError) Get(keyObj interface{}) (*Trip, error) {
+func (db *_DatabaseServiceMockGetError) Get(ctx context.Context, keyObj interface{}) (*Trip, error) {
 	return nil, errors.New("ERROR")
 }
 
-func (db *_DatabaseServiceMockGetError) Write(obj ...*Trip) error {
+func (db *_DatabaseServiceMockGetError) Write(ctx context.Context, obj ...*Trip) error {
 	return nil
 }
 
-func (db *_DatabaseServiceMockGetError) Query(filterObj interface{}, condition string) (*[]Trip, error) {
+func (db *_DatabaseServiceMockGetError) Query(ctx context.Context, filterObj interface{}, condition string) (*[]Trip, error) {
 	return nil, errors.New("ERROR")
 }
 
+// Mock DatabaseService which fails like the AWS SDK when the context is done
+type _DatabaseServiceMockContext struct {
+	database.Service[Trip]
+}
+
+func (db *_DatabaseServiceMockContext) Get(ctx context.Context, keyObj interface{}) (*Trip, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return &Trip{ID: "0000-0000-0000-0000"}, nil
+}
+
 // Mock DatabaseService where .Write returns an error
 type _DatabaseServiceMockWriteError struct {
 	database.Service[Trip]
 }
 
-func (db *_DatabaseServiceMockWriteError) Get(keyObj interface{}) (*Trip, error) {
+func (db *_DatabaseServiceMockWriteError) Get(ctx context.Context, keyObj interface{}) (*Trip, error) {
 	return nil, nil
 }
 
-func (db *_DatabaseServiceMockWriteError) Write(obj ...*Trip) error {
+func (db *_DatabaseServiceMockWriteError) Write(ctx context.Context, obj ...*Trip) error {
 	return errors.New("ERROR")
 }
 
+// Mock DatabaseService which keeps items in memory
+type _DatabaseServiceMockInMemory struct {
+	database.Service[Trip]
+	items map[string]Trip
+}
+
+func newInMemoryDatabase(items ...Trip) *_DatabaseServiceMockInMemory {
+	db := &_DatabaseServiceMockInMemory{items: map[string]Trip{}}
+	for _, item := range items {
+		db.items[item.PK+"|"+item.SK] = item
+	}
+	return db
+}
+
+func (db *_DatabaseServiceMockInMemory) Get(ctx context.Context, keyObj interface{}) (*Trip, error) {
+	key := keyObj.(map[string]string)
+	item, ok := db.items[key["PK"]+"|"+key["SK"]]
+	if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+func (db *_DatabaseServiceMockInMemory) Write(ctx context.Context, obj ...*Trip) error {
+	for _, item := range obj {
+		db.items[item.PK+"|"+item.SK] = *item
+	}
+	return nil
+}
+
+func (db *_DatabaseServiceMockInMemory) Delete(ctx context.Context, keyObj interface{}) error {
+	key := keyObj.(map[string]string)
+	delete(db.items, key["PK"]+"|"+key["SK"])
+	return nil
+}
+
+func (db *_DatabaseServiceMockInMemory) QueryWithIndex(ctx context.Context, filterObj interface{}, condition string, filterExpr string, index string) (*[]Trip, error) {
+	filter := filterObj.(map[string]string)
+	out := []Trip{}
+	for _, item := range db.items {
+		if item.SK == filter[":SK"] && strings.HasPrefix(item.PK, filter[":PK"]) {
+			out = append(out, item)
+		}
+	}
+	return &out, nil
+}
+
+// Transaction updates the created_by and role of items which exist and are still created by the expected user
+func (db *_DatabaseServiceMockInMemory) Transaction(ctx context.Context, items ...database.TransactionItem) error {
+	for i, item := range items {
+		key := item.Update.(map[string]string)
+		existing, ok := db.items[key["PK"]+"|"+key["SK"]]
+		if !ok || existing.CreatedBy != item.Changes.Values[":created_by"] {
+			return &database.ConditionError{Index: i}
+		}
+	}
+
+	for _, item := range items {
+		key := item.Update.(map[string]string)
+		existing := db.items[key["PK"]+"|"+key["SK"]]
+		existing.CreatedBy = item.Changes.Set["created_by"].(string)
+		if role, ok := item.Changes.Set["role"].(Role); ok {
+			existing.Role = role
+		}
+		db.items[key["PK"]+"|"+key["SK"]] = existing
+	}
+	return nil
+}
+
+// newTripWithParticipants returns trip items where owner, editor and viewer are participants
+func newTripWithParticipants() []Trip {
+	trip := Trip{
+		PK:        "TRIP#trip",
+		SK:        "TRIP#trip",
+		ID:        "trip",
+		CreatedBy: "owner",
+		FromDate:  time.Now().Add(time.Hour * 24).UTC().Format(time.RFC3339),
+		ToDate:    time.Now().Add(time.Hour * 24 * 2).UTC().Format(time.RFC3339),
+		Name:      "trip.name",
+	}
+
+	items := []Trip{trip}
+	for _, participant := range []struct {
+		id   string
+		role Role
+	}{{"owner", RoleOwner}, {"editor", RoleEditor}, {"viewer", RoleViewer}} {
+		ref := trip
+		ref.PK = "USER#" + participant.id
+		ref.ParticipantID = participant.id
+		ref.Role = participant.role
+		items = append(items, ref)
+	}
+
+	return items
+}
+
+func TestRoleCan(t *testing.T) {
+	t.Run("SUCCESS: OWNER CAN MANAGE, EDITOR CAN EDIT, VIEWER CAN VIEW", func(t *testing.T) {
+		assert.True(t, RoleOwner.Can(PermissionManage))
+		assert.True(t, RoleEditor.Can(PermissionEdit))
+		assert.False(t, RoleEditor.Can(PermissionManage))
+		assert.True(t, RoleViewer.Can(PermissionView))
+		assert.False(t, RoleViewer.Can(PermissionEdit))
+		assert.False(t, Role("").Can(PermissionView))
+	})
+}
+
+func TestTripResponse(t *testing.T) {
+	t.Run("SUCCESS: REQUEST ONLY SETS FIELDS THE USER CAN SET", func(t *testing.T) {
+		request := &TripRequest{Name: "trip.name", FromDate: "2030-01-01T00:00:00Z", ToDate: "2030-01-02T00:00:00Z", Visibility: VisibilityPublic}
+
+		result := request.Trip()
+
+		assert.Equal(t, &Trip{Name: "trip.name", FromDate: "2030-01-01T00:00:00Z", ToDate: "2030-01-02T00:00:00Z", Visibility: VisibilityPublic}, result)
+	})
+
+	t.Run("SUCCESS: RESPONSE DOES NOT CONTAIN STORAGE FIELDS", func(t *testing.T) {
+		trip := &Trip{PK: "TRIP#id", SK: "TRIP#id", ID: "id", Name: "trip.name", CoverPhotoKey: "cover.key", NormalizedName: "trip name", GSI2PK: PUBLIC_TRIPS_PK}
+
+		body, _ := json.Marshal(trip.Response())
+
+		assert.Contains(t, string(body), `"id":"id"`)
+		for _, field := range []string{"PK", "SK", "cover.key", "trip name", PUBLIC_TRIPS_PK} {
+			assert.NotContains(t, string(body), field)
+		}
+	})
+}
+
 func TestNewTripService(t *testing.T) {
 	t.Run("SUCCESS: RETURN NEW AUTHENTICATION SERVICE", func(t *testing.T) {
-		svc := NewTripService()
+		svc := NewTripService(
+			newInMemoryDatabase(),
+			&_LinkDatabaseServiceMockInMemory{items: map[string]InviteLink{}},
+			&_StorageServiceMock{},
+			"invite",
+			testClock,
+			testIDs,
+			testLogger,
+		)
 
 		assert.NotEmpty(t, svc, "Service should not empty")
 	})
@@ -103,9 +273,9 @@ func TestNewTripService(t *testing.T) {
 
 func TestCreateTrip(t *testing.T) {
 	t.Run("SUCCESS: RETURN CREATED MESSAGE WHEN FIELDS ARE VALID", func(t *testing.T) {
-		svc := _Service{db: &_DatabaseServiceMockItemNotFound{}}
+		svc := _Service{db: &_DatabaseServiceMockItemNotFound{}, clock: testClock, ids: testIDs, logger: testLogger}
 
-		err := svc.CreateTrip(&Trip{
+		err := svc.CreateTrip(context.Background(), &Trip{
 			CreatedBy:   "0000-0000-0000-0000",
 			FromDate:    time.Now().Add(time.Hour * 24).UTC().Format(time.RFC3339),
 			ToDate:      time.Now().Add(time.Hour * 24 * 2).UTC().Format(time.RFC3339),
@@ -122,9 +292,9 @@ func TestCreateTrip(t *testing.T) {
 	})
 
 	t.Run("ERROR: RETURN 400 WHEN FROM_DATE IS INVALID", func(t *testing.T) {
-		svc := _Service{db: &_DatabaseServiceMockItemNotFound{}}
+		svc := _Service{db: &_DatabaseServiceMockItemNotFound{}, clock: testClock, ids: testIDs, logger: testLogger}
 
-		err := svc.CreateTrip(&Trip{
+		err := svc.CreateTrip(context.Background(), &Trip{
 			CreatedBy:   "0000-0000-0000-0000",
 			FromDate:    "invalid.time",
 			ToDate:      time.Now().Add(time.Hour * 24).UTC().Format(time.RFC3339),
@@ -141,9 +311,9 @@ func TestCreateTrip(t *testing.T) {
 	})
 
 	t.Run("ERROR: RETURN 400 WHEN TO_DATE IS INVALID", func(t *testing.T) {
-		svc := _Service{db: &_DatabaseServiceMockItemNotFound{}}
+		svc := _Service{db: &_DatabaseServiceMockItemNotFound{}, clock: testClock, ids: testIDs, logger: testLogger}
 
-		err := svc.CreateTrip(&Trip{
+		err := svc.CreateTrip(context.Background(), &Trip{
 			CreatedBy:   "0000-0000-0000-0000",
 			FromDate:    time.Now().Add(time.Hour * 24).UTC().Format(time.RFC3339),
 			ToDate:      "invalid.time",
@@ -160,9 +330,9 @@ func TestCreateTrip(t *testing.T) {
 	})
 
 	t.Run("ERROR: RETURN 400 WHEN DATES ARE IN THE PAST", func(t *testing.T) {
-		svc := _Service{db: &_DatabaseServiceMockItemNotFound{}}
+		svc := _Service{db: &_DatabaseServiceMockItemNotFound{}, clock: testClock, ids: testIDs, logger: testLogger}
 
-		err := svc.CreateTrip(&Trip{
+		err := svc.CreateTrip(context.Background(), &Trip{
 			CreatedBy:   "0000-0000-0000-0000",
 			FromDate:    time.Now().Add(-time.Hour * 24 * 2).UTC().Format(time.RFC3339),
 			ToDate:      time.Now().Add(-time.Hour * 24).UTC().Format(time.RFC3339),
@@ -179,9 +349,9 @@ func TestCreateTrip(t *testing.T) {
 	})
 
 	t.Run("ERROR: RETURN 400 WHEN FROM_DATE IS AFTER TO_DATE", func(t *testing.T) {
-		svc := _Service{db: &_DatabaseServiceMockItemNotFound{}}
+		svc := _Service{db: &_DatabaseServiceMockItemNotFound{}, clock: testClock, ids: testIDs, logger: testLogger}
 
-		err := svc.CreateTrip(&Trip{
+		err := svc.CreateTrip(context.Background(), &Trip{
 			CreatedBy:   "0000-0000-0000-0000",
 			FromDate:    time.Now().Add(time.Hour * 24 * 2).UTC().Format(time.RFC3339),
 			ToDate:      time.Now().Add(time.Hour * 24).UTC().Format(time.RFC3339),
@@ -198,9 +368,9 @@ func TestCreateTrip(t *testing.T) {
 	})
 
 	t.Run("ERROR: RETURN 400 WHEN USER ID IS EMPTY", func(t *testing.T) {
-		svc := _Service{db: &_DatabaseServiceMockItemNotFound{}}
+		svc := _Service{db: &_DatabaseServiceMockItemNotFound{}, clock: testClock, ids: testIDs, logger: testLogger}
 
-		err := svc.CreateTrip(&Trip{
+		err := svc.CreateTrip(context.Background(), &Trip{
 			CreatedBy:   "",
 			FromDate:    time.Now().Add(-time.Hour * 24 * 2).UTC().Format(time.RFC3339),
 			ToDate:      time.Now().Add(-time.Hour * 24).UTC().Format(time.RFC3339),
@@ -217,9 +387,9 @@ func TestCreateTrip(t *testing.T) {
 	})
 
 	t.Run("ERROR: RETURN 400 WHEN NAME IS EMPTY", func(t *testing.T) {
-		svc := _Service{db: &_DatabaseServiceMockItemNotFound{}}
+		svc := _Service{db: &_DatabaseServiceMockItemNotFound{}, clock: testClock, ids: testIDs, logger: testLogger}
 
-		err := svc.CreateTrip(&Trip{
+		err := svc.CreateTrip(context.Background(), &Trip{
 			CreatedBy:   "0000-0000-0000-0000",
 			FromDate:    time.Now().Add(-time.Hour * 24 * 2).UTC().Format(time.RFC3339),
 			ToDate:      time.Now().Add(-time.Hour * 24).UTC().Format(time.RFC3339),
@@ -236,9 +406,9 @@ func TestCreateTrip(t *testing.T) {
 	})
 
 	t.Run("ERROR: RETURN 503 WHEN DB WRITE RETURNS ERROR", func(t *testing.T) {
-		svc := &_Service{db: &_DatabaseServiceMockWriteError{}}
+		svc := &_Service{db: &_DatabaseServiceMockWriteError{}, clock: testClock, ids: testIDs, logger: testLogger}
 
-		err := svc.CreateTrip(&Trip{
+		err := svc.CreateTrip(context.Background(), &Trip{
 			CreatedBy:   "0000-0000-0000-0000",
 			FromDate:    time.Now().Add(time.Hour * 24).UTC().Format(time.RFC3339),
 			ToDate:      time.Now().Add(time.Hour * 24 * 2).UTC().Format(time.RFC3339),
@@ -257,49 +427,775 @@ func TestCreateTrip(t *testing.T) {
 
 func TestGetTrip(t *testing.T) {
 	t.Run("SUCCESS: RETURN 200 WHEN ITEM IS FOUND", func(t *testing.T) {
-		svc := _Service{db: &_DatabaseServiceMockItemExists{}}
+		svc := _Service{db: &_DatabaseServiceMockItemExists{}, clock: testClock, ids: testIDs, logger: testLogger}
 
-		result, err := svc.GetTrip("0000-0000-0000-0000")
+		result, err := svc.GetTrip(context.Background(), "0000-0000-0000-0000")
 
 		assert.NotEmpty(t, result, "Result should be not be empty")
 		assert.Empty(t, err, "Error should be empty")
 	})
 
 	t.Run("ERROR: RETURN 400 WHEN ITEM NOT FOUND", func(t *testing.T) {
-		svc := _Service{db: &_DatabaseServiceMockItemNotFound{}}
+		svc := _Service{db: &_DatabaseServiceMockItemNotFound{}, clock: testClock, ids: testIDs, logger: testLogger}
 
-		result, err := svc.GetTrip("0000-0000-0000-0000")
+		result, err := svc.GetTrip(context.Background(), "0000-0000-0000-0000")
 
 		assert.Empty(t, result, "Result should be empty")
 		assert.Equal(t, 400, err.Code, "Error should be 400")
 	})
 
 	t.Run("ERROR: RETURN 503 WHEN DB READ RETURNS ERROR", func(t *testing.T) {
-		svc := &_Service{db: &_DatabaseServiceMockGetError{}}
+		svc := &_Service{db: &_DatabaseServiceMockGetError{}, clock: testClock, ids: testIDs, logger: testLogger}
 
-		result, err := svc.GetTrip("0000-0000-0000-0000")
+		result, err := svc.GetTrip(context.Background(), "0000-0000-0000-0000")
 
 		assert.Empty(t, result, "Result should be empty")
 		assert.Equal(t, 503, err.Code, "Error should be 503")
 	})
+
+	t.Run("ERROR: RETURN 503 WHEN REQUEST CONTEXT IS CANCELLED", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockContext{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		result, err := svc.GetTrip(ctx, "0000-0000-0000-0000")
+
+		assert.Empty(t, result, "Result should be empty")
+		assert.Equal(t, 503, err.Code, "Error should be 503")
+		assert.ErrorIs(t, err, context.Canceled, "Error should wrap context.Canceled")
+	})
 }
 
 func TestGetTripByUser(t *testing.T) {
 	t.Run("SUCCESS: RETURN 200 WHEN QUERY IS SUCCESSFUL", func(t *testing.T) {
-		svc := _Service{db: &_DatabaseServiceMockItemExists{}}
+		svc := _Service{db: &_DatabaseServiceMockItemExists{}, clock: testClock, ids: testIDs, logger: testLogger}
 
-		result, err := svc.GetTripsByUser("0000-0000-0000-0000")
+		result, err := svc.GetTripsByUser(context.Background(), "0000-0000-0000-0000")
 
 		assert.NotEmpty(t, result, "Result should be not be empty")
 		assert.Empty(t, err, "Error should be empty")
 	})
 
 	t.Run("ERROR: RETURN 503 WHEN DB QUERY RETURNS ERROR", func(t *testing.T) {
-		svc := &_Service{db: &_DatabaseServiceMockGetError{}}
+		svc := &_Service{db: &_DatabaseServiceMockGetError{}, clock: testClock, ids: testIDs, logger: testLogger}
 
-		result, err := svc.GetTripsByUser("0000-0000-0000-0000")
+		result, err := svc.GetTripsByUser(context.Background(), "0000-0000-0000-0000")
 
 		assert.Empty(t, result, "Result should be empty")
 		assert.Equal(t, 503, err.Code, "Error should be 503")
 	})
 }
+
+func TestCreateTripOwner(t *testing.T) {
+	t.Run("SUCCESS: CREATOR REFERENCE ITEM IS OWNER", func(t *testing.T) {
+		db := newInMemoryDatabase()
+		svc := _Service{db: db, clock: testClock, ids: testIDs, logger: testLogger}
+
+		trip := &Trip{
+			CreatedBy: "owner",
+			FromDate:  time.Now().Add(time.Hour * 24).UTC().Format(time.RFC3339),
+			ToDate:    time.Now().Add(time.Hour * 24 * 2).UTC().Format(time.RFC3339),
+			Name:      "trip.name",
+		}
+
+		err := svc.CreateTrip(context.Background(), trip)
+
+		assert.Empty(t, err)
+		assert.Equal(t, RoleOwner, db.items["USER#owner|TRIP#"+trip.ID].Role, "Role should be owner")
+	})
+}
+
+func TestCreateTripWithFixedClockAndIDs(t *testing.T) {
+	now := time.Date(2030, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("SUCCESS: TRIPS GET GENERATED IDS AND KEYS", func(t *testing.T) {
+		db := newInMemoryDatabase()
+		svc := _Service{db: db, clock: clock.Fixed(now), ids: ids.Sequence(), logger: testLogger}
+
+		first := &Trip{CreatedBy: "owner", FromDate: "2030-06-02T00:00:00Z", ToDate: "2030-06-05T00:00:00Z", Name: "first"}
+		second := &Trip{CreatedBy: "owner", FromDate: "2030-07-01T00:00:00Z", ToDate: "2030-07-02T00:00:00Z", Name: "second"}
+
+		assert.Empty(t, svc.CreateTrip(context.Background(), first))
+		assert.Empty(t, svc.CreateTrip(context.Background(), second))
+
+		assert.Equal(t, "00000000-0000-0000-0000-000000000001", first.ID)
+		assert.Equal(t, "TRIP#00000000-0000-0000-0000-000000000001", first.PK)
+		assert.Equal(t, "TRIP#00000000-0000-0000-0000-000000000001", first.SK)
+		assert.Equal(t, "00000000-0000-0000-0000-000000000002", second.ID)
+		assert.Contains(t, db.items, "TRIP#00000000-0000-0000-0000-000000000001|TRIP#00000000-0000-0000-0000-000000000001")
+		assert.Contains(t, db.items, "USER#owner|TRIP#00000000-0000-0000-0000-000000000002")
+	})
+
+	t.Run("SUCCESS: TRIP CAN START AT THE CURRENT TIME", func(t *testing.T) {
+		svc := _Service{db: newInMemoryDatabase(), clock: clock.Fixed(now), ids: ids.Sequence(), logger: testLogger}
+
+		err := svc.CreateTrip(context.Background(), &Trip{
+			CreatedBy: "owner",
+			FromDate:  now.Format(time.RFC3339),
+			ToDate:    now.Add(time.Hour).Format(time.RFC3339),
+			Name:      "trip.name",
+		})
+
+		assert.Empty(t, err)
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN FROM_DATE IS BEFORE THE CURRENT TIME", func(t *testing.T) {
+		db := newInMemoryDatabase()
+		svc := _Service{db: db, clock: clock.Fixed(now), ids: ids.Sequence(), logger: testLogger}
+
+		err := svc.CreateTrip(context.Background(), &Trip{
+			CreatedBy: "owner",
+			FromDate:  now.Add(-time.Second).Format(time.RFC3339),
+			ToDate:    now.Add(time.Hour).Format(time.RFC3339),
+			Name:      "trip.name",
+		})
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+		assert.Empty(t, db.items, "Trip should not be written")
+	})
+}
+
+func TestUpdateTrip(t *testing.T) {
+	t.Run("SUCCESS: EDITOR UPDATES TRIP AND REFERENCE ITEMS", func(t *testing.T) {
+		db := newInMemoryDatabase(newTripWithParticipants()...)
+		svc := _Service{db: db, clock: testClock, ids: testIDs, logger: testLogger}
+
+		update := db.items["TRIP#trip|TRIP#trip"]
+		update.Name = "trip.updated"
+
+		err := svc.UpdateTrip(context.Background(), "editor", &update)
+
+		assert.Empty(t, err)
+		assert.Equal(t, "trip.updated", db.items["TRIP#trip|TRIP#trip"].Name)
+		assert.Equal(t, "trip.updated", db.items["USER#viewer|TRIP#trip"].Name)
+		assert.Equal(t, RoleViewer, db.items["USER#viewer|TRIP#trip"].Role, "Role should be kept")
+	})
+
+	t.Run("ERROR: RETURN 403 WHEN USER IS VIEWER", func(t *testing.T) {
+		db := newInMemoryDatabase(newTripWithParticipants()...)
+		svc := _Service{db: db, clock: testClock, ids: testIDs, logger: testLogger}
+
+		update := db.items["TRIP#trip|TRIP#trip"]
+
+		err := svc.UpdateTrip(context.Background(), "viewer", &update)
+
+		assert.Equal(t, 403, err.Code, "Error should be 403")
+	})
+
+	t.Run("ERROR: RETURN 403 WHEN USER IS NOT A PARTICIPANT", func(t *testing.T) {
+		db := newInMemoryDatabase(newTripWithParticipants()...)
+		svc := _Service{db: db, clock: testClock, ids: testIDs, logger: testLogger}
+
+		update := db.items["TRIP#trip|TRIP#trip"]
+
+		err := svc.UpdateTrip(context.Background(), "stranger", &update)
+
+		assert.Equal(t, 403, err.Code, "Error should be 403")
+	})
+}
+
+// Mock StorageService which signs urls without credentials
+type _StorageServiceMock struct {
+	filestorage.Service
+	files map[string][]byte
+}
+
+func newStorage() *_StorageServiceMock {
+	return &_StorageServiceMock{files: map[string][]byte{}}
+}
+
+func (storage *_StorageServiceMock) UploadFile(ctx context.Context, filename string, body io.ReadSeeker, contentType string) error {
+	data, _ := io.ReadAll(body)
+	storage.files[filename] = data
+	return nil
+}
+
+func (storage *_StorageServiceMock) DeleteFile(ctx context.Context, filename string) error {
+	delete(storage.files, filename)
+	return nil
+}
+
+func (storage *_StorageServiceMock) GetDownloadUrl(filename string) (string, error) {
+	return "https://storage/" + filename + "?signature=test", nil
+}
+
+func encodePNG(width int, height int) io.Reader {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)))
+	return &buf
+}
+
+func TestSetCoverPhoto(t *testing.T) {
+	t.Run("SUCCESS: EDITOR SETS COVER PHOTO ON TRIP AND REFERENCE ITEMS", func(t *testing.T) {
+		db := newInMemoryDatabase(newTripWithParticipants()...)
+		storage := newStorage()
+		svc := _Service{db: db, storage: storage, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.SetCoverPhoto(context.Background(), "editor", "trip", encodePNG(3000, 1500))
+
+		assert.Empty(t, err)
+		key := db.items["TRIP#trip|TRIP#trip"].CoverPhotoKey
+		assert.True(t, strings.HasPrefix(key, "trips/trip/covers/"), "Cover should be stored under trip")
+		assert.Equal(t, key, db.items["USER#viewer|TRIP#trip"].CoverPhotoKey)
+		assert.Equal(t, "https://storage/"+key+"?signature=test", result.CoverPhotoUrl)
+
+		cover, format, _ := image.DecodeConfig(bytes.NewReader(storage.files[key]))
+		assert.Equal(t, "jpeg", format, "Cover should be re-encoded")
+		assert.Equal(t, maxCoverPhotoDimension, cover.Width, "Cover should be scaled down")
+	})
+
+	t.Run("SUCCESS: GET TRIP RETURNS SIGNED COVER PHOTO URL", func(t *testing.T) {
+		db := newInMemoryDatabase(newTripWithParticipants()...)
+		svc := _Service{db: db, storage: newStorage(), clock: testClock, ids: testIDs, logger: testLogger}
+		cover, _ := svc.SetCoverPhoto(context.Background(), "owner", "trip", encodePNG(10, 10))
+
+		result, err := svc.GetTrip(context.Background(), "trip")
+
+		assert.Empty(t, err)
+		assert.Equal(t, "https://storage/"+cover.CoverPhotoKey+"?signature=test", result.CoverPhotoUrl)
+	})
+
+	t.Run("SUCCESS: DELETE PREVIOUS COVER PHOTO", func(t *testing.T) {
+		db := newInMemoryDatabase(newTripWithParticipants()...)
+		storage := newStorage()
+		svc := _Service{db: db, storage: storage, clock: testClock, ids: testIDs, logger: testLogger}
+
+		first, _ := svc.SetCoverPhoto(context.Background(), "owner", "trip", encodePNG(10, 10))
+		second, _ := svc.SetCoverPhoto(context.Background(), "owner", "trip", encodePNG(10, 10))
+
+		assert.NotEqual(t, first.CoverPhotoKey, second.CoverPhotoKey)
+		assert.Len(t, storage.files, 1)
+		assert.Contains(t, storage.files, second.CoverPhotoKey)
+	})
+
+	t.Run("SUCCESS: UPDATE TRIP KEEPS COVER PHOTO", func(t *testing.T) {
+		db := newInMemoryDatabase(newTripWithParticipants()...)
+		svc := _Service{db: db, storage: newStorage(), clock: testClock, ids: testIDs, logger: testLogger}
+		result, _ := svc.SetCoverPhoto(context.Background(), "owner", "trip", encodePNG(10, 10))
+
+		update := db.items["TRIP#trip|TRIP#trip"]
+		update.CoverPhotoKey = "trips/trip/covers/other.jpg"
+
+		assert.Empty(t, svc.UpdateTrip(context.Background(), "owner", &update))
+		assert.Equal(t, result.CoverPhotoKey, db.items["TRIP#trip|TRIP#trip"].CoverPhotoKey)
+	})
+
+	t.Run("ERROR: RETURN 415 WHEN FILE IS NOT AN IMAGE", func(t *testing.T) {
+		storage := newStorage()
+		svc := _Service{db: newInMemoryDatabase(newTripWithParticipants()...), storage: storage, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.SetCoverPhoto(context.Background(), "owner", "trip", strings.NewReader("not an image"))
+
+		assert.Equal(t, 415, err.Code, "Error should be 415")
+		assert.Empty(t, result, "Result should be empty")
+		assert.Empty(t, storage.files, "Nothing should be stored")
+	})
+
+	t.Run("ERROR: RETURN 403 WHEN USER IS VIEWER", func(t *testing.T) {
+		svc := _Service{db: newInMemoryDatabase(newTripWithParticipants()...), storage: newStorage(), clock: testClock, ids: testIDs, logger: testLogger}
+
+		_, err := svc.SetCoverPhoto(context.Background(), "viewer", "trip", encodePNG(10, 10))
+
+		assert.Equal(t, 403, err.Code, "Error should be 403")
+	})
+}
+
+func TestUpdateParticipantRole(t *testing.T) {
+	t.Run("SUCCESS: OWNER CHANGES ROLE", func(t *testing.T) {
+		db := newInMemoryDatabase(newTripWithParticipants()...)
+		svc := _Service{db: db, clock: testClock, ids: testIDs, logger: testLogger}
+
+		err := svc.UpdateParticipantRole(context.Background(), "owner", "trip", "viewer", RoleEditor)
+
+		assert.Empty(t, err)
+		assert.Equal(t, RoleEditor, db.items["USER#viewer|TRIP#trip"].Role)
+	})
+
+	t.Run("ERROR: RETURN 403 WHEN USER IS EDITOR", func(t *testing.T) {
+		svc := _Service{db: newInMemoryDatabase(newTripWithParticipants()...), clock: testClock, ids: testIDs, logger: testLogger}
+
+		err := svc.UpdateParticipantRole(context.Background(), "editor", "trip", "viewer", RoleEditor)
+
+		assert.Equal(t, 403, err.Code, "Error should be 403")
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN ROLE IS OWNER", func(t *testing.T) {
+		svc := _Service{db: newInMemoryDatabase(newTripWithParticipants()...), clock: testClock, ids: testIDs, logger: testLogger}
+
+		err := svc.UpdateParticipantRole(context.Background(), "owner", "trip", "viewer", RoleOwner)
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+	})
+
+	t.Run("ERROR: RETURN 404 WHEN PARTICIPANT NOT FOUND", func(t *testing.T) {
+		svc := _Service{db: newInMemoryDatabase(newTripWithParticipants()...), clock: testClock, ids: testIDs, logger: testLogger}
+
+		err := svc.UpdateParticipantRole(context.Background(), "owner", "trip", "stranger", RoleEditor)
+
+		assert.Equal(t, 404, err.Code, "Error should be 404")
+	})
+}
+
+// Mock DatabaseService where a participant leaves the trip after the participants were listed
+type _DatabaseServiceMockLeaving struct {
+	*_DatabaseServiceMockInMemory
+	leaving string
+}
+
+func (db *_DatabaseServiceMockLeaving) Transaction(ctx context.Context, items ...database.TransactionItem) error {
+	delete(db.items, db.leaving)
+	return db._DatabaseServiceMockInMemory.Transaction(ctx, items...)
+}
+
+func TestTransferOwnership(t *testing.T) {
+	t.Run("SUCCESS: OWNER BECOMES EDITOR", func(t *testing.T) {
+		db := newInMemoryDatabase(newTripWithParticipants()...)
+		svc := _Service{db: db, clock: testClock, ids: testIDs, logger: testLogger}
+
+		err := svc.TransferOwnership(context.Background(), "owner", "trip", "viewer")
+
+		assert.Empty(t, err)
+		assert.Equal(t, RoleEditor, db.items["USER#owner|TRIP#trip"].Role)
+		assert.Equal(t, RoleOwner, db.items["USER#viewer|TRIP#trip"].Role)
+		assert.Equal(t, RoleEditor, db.items["USER#editor|TRIP#trip"].Role, "Role should be kept")
+	})
+
+	t.Run("SUCCESS: NEW OWNER BECOMES CREATOR OF EVERY ITEM", func(t *testing.T) {
+		db := newInMemoryDatabase(newTripWithParticipants()...)
+		svc := _Service{db: db, clock: testClock, ids: testIDs, logger: testLogger}
+
+		err := svc.TransferOwnership(context.Background(), "owner", "trip", "viewer")
+
+		assert.Empty(t, err)
+		for key, item := range db.items {
+			assert.Equal(t, "viewer", item.CreatedBy, key)
+		}
+	})
+
+	t.Run("ERROR: RETURN 409 WHEN PARTICIPANT LEFT DURING TRANSFER", func(t *testing.T) {
+		db := newInMemoryDatabase(newTripWithParticipants()...)
+		svc := _Service{db: &_DatabaseServiceMockLeaving{db, "USER#editor|TRIP#trip"}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		err := svc.TransferOwnership(context.Background(), "owner", "trip", "viewer")
+
+		assert.Equal(t, 409, err.Code, "Error should be 409")
+		assert.Equal(t, RoleOwner, db.items["USER#owner|TRIP#trip"].Role, "Nothing should be changed")
+		assert.Equal(t, "owner", db.items["TRIP#trip|TRIP#trip"].CreatedBy, "Nothing should be changed")
+	})
+
+	t.Run("ERROR: RETURN 403 WHEN USER IS NOT OWNER", func(t *testing.T) {
+		svc := _Service{db: newInMemoryDatabase(newTripWithParticipants()...), clock: testClock, ids: testIDs, logger: testLogger}
+
+		err := svc.TransferOwnership(context.Background(), "editor", "trip", "viewer")
+
+		assert.Equal(t, 403, err.Code, "Error should be 403")
+	})
+}
+
+func TestRemoveParticipant(t *testing.T) {
+	t.Run("SUCCESS: OWNER REMOVES PARTICIPANT", func(t *testing.T) {
+		db := newInMemoryDatabase(newTripWithParticipants()...)
+		svc := _Service{db: db, clock: testClock, ids: testIDs, logger: testLogger}
+
+		err := svc.RemoveParticipant(context.Background(), "owner", "trip", "editor")
+
+		assert.Empty(t, err)
+		assert.NotContains(t, db.items, "USER#editor|TRIP#trip")
+	})
+
+	t.Run("SUCCESS: PARTICIPANT LEAVES TRIP", func(t *testing.T) {
+		db := newInMemoryDatabase(newTripWithParticipants()...)
+		svc := _Service{db: db, clock: testClock, ids: testIDs, logger: testLogger}
+
+		err := svc.RemoveParticipant(context.Background(), "viewer", "trip", "viewer")
+
+		assert.Empty(t, err)
+		assert.NotContains(t, db.items, "USER#viewer|TRIP#trip")
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN OWNER LEAVES TRIP", func(t *testing.T) {
+		svc := _Service{db: newInMemoryDatabase(newTripWithParticipants()...), clock: testClock, ids: testIDs, logger: testLogger}
+
+		err := svc.RemoveParticipant(context.Background(), "owner", "trip", "owner")
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+	})
+
+	t.Run("ERROR: RETURN 403 WHEN EDITOR REMOVES PARTICIPANT", func(t *testing.T) {
+		svc := _Service{db: newInMemoryDatabase(newTripWithParticipants()...), clock: testClock, ids: testIDs, logger: testLogger}
+
+		err := svc.RemoveParticipant(context.Background(), "editor", "trip", "viewer")
+
+		assert.Equal(t, 403, err.Code, "Error should be 403")
+	})
+}
+
+// Mock DatabaseService which keeps invite links in memory, transactions add participants to trips
+type _LinkDatabaseServiceMockInMemory struct {
+	database.Service[InviteLink]
+	items map[string]InviteLink
+	trips *_DatabaseServiceMockInMemory
+}
+
+// Transaction puts the participant if they are not a participant yet
+// and counts the use of the link if the link exists and is not used up
+func (db *_LinkDatabaseServiceMockInMemory) Transaction(ctx context.Context, items ...database.TransactionItem) error {
+	for i, item := range items {
+		if item.Put != nil {
+			participant := item.Put.(*Trip)
+			if _, ok := db.trips.items[participant.PK+"|"+participant.SK]; ok && item.Condition != "" {
+				return &database.ConditionError{Index: i}
+			}
+			continue
+		}
+		key := item.Update.(map[string]string)
+		link, ok := db.items[key["PK"]+"|"+key["SK"]]
+		if !ok || (link.MaxUses > 0 && link.Uses >= link.MaxUses) {
+			return &database.ConditionError{Index: i}
+		}
+	}
+
+	for _, item := range items {
+		if item.Put != nil {
+			db.trips.Write(ctx, item.Put.(*Trip))
+			continue
+		}
+		key := item.Update.(map[string]string)
+		link := db.items[key["PK"]+"|"+key["SK"]]
+		link.Uses++
+		db.items[key["PK"]+"|"+key["SK"]] = link
+	}
+	return nil
+}
+
+// Mock DatabaseService which returns the trips as they were read before the participants joined
+type _DatabaseServiceMockNotJoined struct {
+	*_DatabaseServiceMockInMemory
+}
+
+func (db *_DatabaseServiceMockNotJoined) Get(ctx context.Context, keyObj interface{}) (*Trip, error) {
+	if strings.HasPrefix(keyObj.(map[string]string)["PK"], "USER#") {
+		return nil, nil
+	}
+	return db._DatabaseServiceMockInMemory.Get(ctx, keyObj)
+}
+
+// Mock DatabaseService which returns the invite link as it was read before other users used it up
+type _LinkDatabaseServiceMockStale struct {
+	*_LinkDatabaseServiceMockInMemory
+}
+
+func (db *_LinkDatabaseServiceMockStale) Get(ctx context.Context, keyObj interface{}) (*InviteLink, error) {
+	link, err := db._LinkDatabaseServiceMockInMemory.Get(ctx, keyObj)
+	if link != nil {
+		link.Uses = 0
+	}
+	return link, err
+}
+
+func (db *_LinkDatabaseServiceMockInMemory) Get(ctx context.Context, keyObj interface{}) (*InviteLink, error) {
+	key := keyObj.(map[string]string)
+	item, ok := db.items[key["PK"]+"|"+key["SK"]]
+	if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+func (db *_LinkDatabaseServiceMockInMemory) Write(ctx context.Context, obj ...*InviteLink) error {
+	for _, item := range obj {
+		db.items[item.PK+"|"+item.SK] = *item
+	}
+	return nil
+}
+
+func (db *_LinkDatabaseServiceMockInMemory) Delete(ctx context.Context, keyObj interface{}) error {
+	key := keyObj.(map[string]string)
+	delete(db.items, key["PK"]+"|"+key["SK"])
+	return nil
+}
+
+func TestInviteLinks(t *testing.T) {
+	newService := func() (*_Service, *_DatabaseServiceMockInMemory) {
+		db := newInMemoryDatabase(newTripWithParticipants()...)
+		links := &_LinkDatabaseServiceMockInMemory{items: map[string]InviteLink{}, trips: db}
+		return &_Service{db: db, links: links, clock: testClock, ids: testIDs, logger: testLogger}, db
+	}
+
+	t.Run("SUCCESS: JOIN TRIP AS VIEWER WITH INVITE LINK", func(t *testing.T) {
+		svc, db := newService()
+
+		link, err := svc.CreateInviteLink(context.Background(), "owner", "trip", &CreateInviteLinkRequest{})
+		assert.Empty(t, err)
+
+		trip, err := svc.JoinTrip(context.Background(), "guest", link.Token, nil)
+
+		assert.Empty(t, err)
+		assert.Equal(t, "trip", trip.ID)
+		assert.Equal(t, RoleViewer, db.items["USER#guest|TRIP#trip"].Role, "Role should be viewer")
+	})
+
+	t.Run("ERROR: RETURN 403 WHEN USER IS NOT OWNER", func(t *testing.T) {
+		svc, _ := newService()
+
+		link, err := svc.CreateInviteLink(context.Background(), "editor", "trip", &CreateInviteLinkRequest{})
+
+		assert.Empty(t, link)
+		assert.Equal(t, 403, err.Code, "Error should be 403")
+	})
+
+	t.Run("ERROR: RETURN 409 WHEN USER IS ALREADY A PARTICIPANT", func(t *testing.T) {
+		svc, _ := newService()
+
+		link, _ := svc.CreateInviteLink(context.Background(), "owner", "trip", &CreateInviteLinkRequest{})
+		_, err := svc.JoinTrip(context.Background(), "viewer", link.Token, nil)
+
+		assert.Equal(t, 409, err.Code, "Error should be 409")
+	})
+
+	t.Run("ERROR: RETURN 409 WHEN USER JOINED AFTER PARTICIPANTS WERE READ", func(t *testing.T) {
+		svc, db := newService()
+
+		link, _ := svc.CreateInviteLink(context.Background(), "owner", "trip", &CreateInviteLinkRequest{})
+		svc.db = &_DatabaseServiceMockNotJoined{db}
+		_, err := svc.JoinTrip(context.Background(), "viewer", link.Token, nil)
+
+		assert.Equal(t, 409, err.Code, "Error should be 409")
+		assert.Equal(t, RoleViewer, db.items["USER#viewer|TRIP#trip"].Role)
+		assert.Equal(t, 0, svc.links.(*_LinkDatabaseServiceMockInMemory).items["TRIP#trip|LINK#"+link.ID].Uses, "Use should not be counted")
+	})
+
+	t.Run("ERROR: RETURN 410 WHEN MAX USES IS REACHED", func(t *testing.T) {
+		svc, _ := newService()
+
+		link, _ := svc.CreateInviteLink(context.Background(), "owner", "trip", &CreateInviteLinkRequest{MaxUses: 1})
+		_, err := svc.JoinTrip(context.Background(), "guest", link.Token, nil)
+		assert.Empty(t, err)
+
+		_, err = svc.JoinTrip(context.Background(), "another.guest", link.Token, nil)
+
+		assert.Equal(t, 410, err.Code, "Error should be 410")
+	})
+
+	t.Run("ERROR: RETURN 410 WHEN LINK IS USED UP AFTER IT WAS READ", func(t *testing.T) {
+		svc, db := newService()
+
+		link, _ := svc.CreateInviteLink(context.Background(), "owner", "trip", &CreateInviteLinkRequest{MaxUses: 1})
+		_, err := svc.JoinTrip(context.Background(), "guest", link.Token, nil)
+		assert.Empty(t, err)
+
+		links := svc.links.(*_LinkDatabaseServiceMockInMemory)
+		svc.links = &_LinkDatabaseServiceMockStale{links}
+		_, err = svc.JoinTrip(context.Background(), "another.guest", link.Token, nil)
+
+		assert.Equal(t, 410, err.Code, "Error should be 410")
+		assert.NotContains(t, db.items, "USER#another.guest|TRIP#trip")
+		assert.Equal(t, 1, links.items["TRIP#trip|LINK#"+link.ID].Uses, "Uses should not exceed max uses")
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN LINK IS REVOKED", func(t *testing.T) {
+		svc, _ := newService()
+
+		link, _ := svc.CreateInviteLink(context.Background(), "owner", "trip", &CreateInviteLinkRequest{})
+		assert.Empty(t, svc.RevokeInviteLink(context.Background(), "owner", "trip", link.ID))
+
+		_, err := svc.JoinTrip(context.Background(), "guest", link.Token, nil)
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+	})
+
+	t.Run("ERROR: RETURN ERROR WHEN INVITATION IS REJECTED", func(t *testing.T) {
+		svc, db := newService()
+
+		link, _ := svc.CreateInviteLink(context.Background(), "owner", "trip", &CreateInviteLinkRequest{})
+		_, err := svc.JoinTrip(context.Background(), "guest", link.Token, func(trip *Trip, link *InviteLink) *pkg.Error {
+			return &pkg.Error{Code: 403, Reason: "Forbidden"}
+		})
+
+		assert.Equal(t, 403, err.Code, "Error should be 403")
+		assert.NotContains(t, db.items, "USER#guest|TRIP#trip")
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN TOKEN IS INVALID", func(t *testing.T) {
+		svc, _ := newService()
+
+		_, err := svc.JoinTrip(context.Background(), "guest", "invalid.token", nil)
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+	})
+}
+
+// Mock DatabaseService which records the filter used to query trips
+type _DatabaseServiceMockQueryFilter struct {
+	database.Service[Trip]
+	values     map[string]string
+	filterExpr string
+	names      map[string]string
+	items      []Trip
+}
+
+func (db *_DatabaseServiceMockQueryFilter) QueryWithFilter(ctx context.Context, filterObj interface{}, condition string, filterExpr string, names map[string]string) (*[]Trip, error) {
+	db.values = filterObj.(map[string]string)
+	db.filterExpr = filterExpr
+	db.names = names
+	return &db.items, nil
+}
+
+func TestSearchTripsByUser(t *testing.T) {
+	t.Run("SUCCESS: SPLIT TRIPS INTO UPCOMING, ONGOING AND PAST", func(t *testing.T) {
+		now := time.Now().UTC()
+		db := &_DatabaseServiceMockQueryFilter{items: []Trip{
+			{ID: "past", FromDate: now.Add(-time.Hour * 48).Format(time.RFC3339), ToDate: now.Add(-time.Hour * 24).Format(time.RFC3339)},
+			{ID: "ongoing", FromDate: now.Add(-time.Hour * 24).Format(time.RFC3339), ToDate: now.Add(time.Hour * 24).Format(time.RFC3339)},
+			{ID: "upcoming.later", FromDate: now.Add(time.Hour * 48).Format(time.RFC3339), ToDate: now.Add(time.Hour * 72).Format(time.RFC3339)},
+			{ID: "upcoming", FromDate: now.Add(time.Hour * 24).Format(time.RFC3339), ToDate: now.Add(time.Hour * 72).Format(time.RFC3339)},
+		}}
+		svc := _Service{db: db, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.SearchTripsByUser(context.Background(), "0000-0000-0000-0000", &TripFilter{})
+
+		assert.Empty(t, err)
+		assert.Empty(t, db.filterExpr, "Filter should be empty")
+		assert.Equal(t, "upcoming", result.Upcoming[0].ID)
+		assert.Equal(t, "upcoming.later", result.Upcoming[1].ID)
+		assert.Equal(t, "ongoing", result.Ongoing[0].ID)
+		assert.Equal(t, "past", result.Past[0].ID)
+	})
+
+	t.Run("SUCCESS: BUILD FILTER EXPRESSION FROM FILTERS", func(t *testing.T) {
+		db := &_DatabaseServiceMockQueryFilter{}
+		svc := _Service{db: db, clock: testClock, ids: testIDs, logger: testLogger}
+
+		_, err := svc.SearchTripsByUser(context.Background(), "0000-0000-0000-0000", &TripFilter{
+			From:    "2030-01-01",
+			To:      "2030-01-31",
+			City:    "New York",
+			Country: "US",
+			Query:   "  Summer   TRIP ",
+		})
+
+		assert.Empty(t, err)
+		assert.Equal(t, "to_date >= :from And from_date <= :to And #location.#city = :city And #location.#country = :country And contains(normalized_name, :q)", db.filterExpr)
+		assert.Equal(t, "2030-01-01T00:00:00Z", db.values[":from"])
+		assert.Equal(t, "2030-01-31T23:59:59Z", db.values[":to"])
+		assert.Equal(t, "summer trip", db.values[":q"])
+		assert.Equal(t, "location", db.names["#location"])
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN FROM DATE IS INVALID", func(t *testing.T) {
+		svc := _Service{db: &_DatabaseServiceMockQueryFilter{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.SearchTripsByUser(context.Background(), "0000-0000-0000-0000", &TripFilter{From: "invalid.date"})
+
+		assert.Empty(t, result, "Result should be empty")
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+	})
+}
+
+// Mock DatabaseService which records the options used to query a page
+type _DatabaseServiceMockQueryPage struct {
+	database.Service[Trip]
+	values    map[string]string
+	condition string
+	options   *database.QueryOptions
+	err       error
+}
+
+func (db *_DatabaseServiceMockQueryPage) QueryPage(ctx context.Context, filterObj interface{}, condition string, options *database.QueryOptions) (*database.Page[Trip], error) {
+	db.values = filterObj.(map[string]string)
+	db.condition = condition
+	db.options = options
+	if db.err != nil {
+		return nil, db.err
+	}
+	return &database.Page[Trip]{Items: []Trip{{ID: "trip"}}, Cursor: "next"}, nil
+}
+
+func TestPublicTrips(t *testing.T) {
+	t.Run("SUCCESS: ONLY PUBLIC TRIP ITEM IS INDEXED", func(t *testing.T) {
+		db := newInMemoryDatabase()
+		svc := _Service{db: db, clock: testClock, ids: testIDs, logger: testLogger}
+
+		trip := &Trip{
+			CreatedBy:  "owner",
+			FromDate:   time.Now().Add(time.Hour * 24).UTC().Format(time.RFC3339),
+			ToDate:     time.Now().Add(time.Hour * 24 * 2).UTC().Format(time.RFC3339),
+			Name:       "trip.name",
+			Visibility: VisibilityPublic,
+		}
+
+		err := svc.CreateTrip(context.Background(), trip)
+
+		assert.Empty(t, err)
+		assert.Equal(t, PUBLIC_TRIPS_PK, db.items[trip.PK+"|"+trip.SK].GSI2PK)
+		assert.Equal(t, trip.FromDate, db.items[trip.PK+"|"+trip.SK].GSI2SK)
+		assert.Empty(t, db.items["USER#owner|"+trip.SK].GSI2PK, "Reference item should not be indexed")
+	})
+
+	t.Run("SUCCESS: TRIP IS PRIVATE BY DEFAULT", func(t *testing.T) {
+		db := newInMemoryDatabase()
+		svc := _Service{db: db, clock: testClock, ids: testIDs, logger: testLogger}
+
+		trip := &Trip{
+			CreatedBy: "owner",
+			FromDate:  time.Now().Add(time.Hour * 24).UTC().Format(time.RFC3339),
+			ToDate:    time.Now().Add(time.Hour * 24 * 2).UTC().Format(time.RFC3339),
+			Name:      "trip.name",
+		}
+
+		err := svc.CreateTrip(context.Background(), trip)
+
+		assert.Empty(t, err)
+		assert.Equal(t, VisibilityPrivate, db.items[trip.PK+"|"+trip.SK].Visibility)
+		assert.Empty(t, db.items[trip.PK+"|"+trip.SK].GSI2PK, "Private trip should not be indexed")
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN VISIBILITY IS INVALID", func(t *testing.T) {
+		svc := _Service{db: newInMemoryDatabase(), clock: testClock, ids: testIDs, logger: testLogger}
+
+		err := svc.CreateTrip(context.Background(), &Trip{
+			CreatedBy:  "owner",
+			FromDate:   time.Now().Add(time.Hour * 24).UTC().Format(time.RFC3339),
+			ToDate:     time.Now().Add(time.Hour * 24 * 2).UTC().Format(time.RFC3339),
+			Name:       "trip.name",
+			Visibility: "everyone",
+		})
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+	})
+
+	t.Run("SUCCESS: QUERY UPCOMING PUBLIC TRIPS WITH FILTERS", func(t *testing.T) {
+		db := &_DatabaseServiceMockQueryPage{}
+		svc := _Service{db: db, clock: testClock, ids: testIDs, logger: testLogger}
+
+		page, err := svc.GetPublicTrips(context.Background(), &PublicTripFilter{Country: "US", Cursor: "cursor"})
+
+		assert.Empty(t, err)
+		assert.Equal(t, "next", page.Cursor)
+		assert.Equal(t, "GSI2PK = :PK And GSI2SK >= :now", db.condition)
+		assert.Equal(t, PUBLIC_TRIPS_PK, db.values[":PK"])
+		assert.Equal(t, "APPLICATION_GSI_2", db.options.Index)
+		assert.Equal(t, "#location.#country = :country", db.options.FilterExpression)
+		assert.Equal(t, int64(defaultPublicTripsLimit), db.options.Limit)
+		assert.Equal(t, "cursor", db.options.Cursor)
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN CURSOR IS INVALID", func(t *testing.T) {
+		svc := _Service{db: &_DatabaseServiceMockQueryPage{err: database.ErrInvalidCursor}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		page, err := svc.GetPublicTrips(context.Background(), &PublicTripFilter{Cursor: "invalid"})
+
+		assert.Empty(t, page, "Result should be empty")
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN LIMIT IS TOO LARGE", func(t *testing.T) {
+		svc := _Service{db: &_DatabaseServiceMockQueryPage{}, clock: testClock, ids: testIDs, logger: testLogger}
+
+		page, err := svc.GetPublicTrips(context.Background(), &PublicTripFilter{Limit: 1000})
+
+		assert.Empty(t, page, "Result should be empty")
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+	})
+}
@@ -0,0 +1,98 @@
+package upload
+
+import "net/http"
+
+var UPLOAD_PK string = "USER#%s"
+var UPLOAD_SK string = "UPLOAD#%s"
+
+// PENDING_UPLOAD_KEY is the storage key of uploaded files: user id and upload id
+var PENDING_UPLOAD_KEY string = "uploads/%s/%s"
+
+// Target is what the uploaded file is attached to
+type Target string
+
+const (
+	TargetProfilePicture Target = "profile_picture"
+	TargetTripCover      Target = "trip_cover"
+	TargetTripPhoto      Target = "trip_photo"
+)
+
+// Status of an upload
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	// StatusProcessing is set while the file is attached, so only one request attaches it
+	StatusProcessing Status = "processing"
+	StatusCompleted  Status = "completed"
+)
+
+// Upload object to store in database.
+// PK should be in the format of UPLOAD_PK and SK should be in the format of UPLOAD_SK.
+// TargetID is the id of the trip for trip targets, Caption is only used for trip photos.
+// Url is a short-lived url of the attached file, it is only returned when the upload is completed.
+type Upload struct {
+	PK          string `json:"PK,omitempty"`
+	SK          string `json:"SK,omitempty"`
+	ID          string `json:"id"`
+	UserID      string `json:"user_id"`
+	Key         string `json:"-" dynamodbav:"key"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Target      Target `json:"target"`
+	TargetID    string `json:"target_id,omitempty"`
+	Caption     string `json:"caption,omitempty"`
+	Status      Status `json:"status"`
+	Url         string `json:"url,omitempty" dynamodbav:"-"`
+	CreatedAt   string `json:"created_at"`
+	ExpiresAt   string `json:"expires_at"`
+}
+
+// UploadResponse object which contains the upload fields sent to clients
+type UploadResponse struct {
+	ID          string `json:"id"`
+	UserID      string `json:"user_id"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Target      Target `json:"target"`
+	TargetID    string `json:"target_id,omitempty"`
+	Caption     string `json:"caption,omitempty"`
+	Status      Status `json:"status"`
+	Url         string `json:"url,omitempty"`
+	CreatedAt   string `json:"created_at"`
+	ExpiresAt   string `json:"expires_at"`
+}
+
+// Response returns upload as sent to clients
+func (upload *Upload) Response() *UploadResponse {
+	return &UploadResponse{
+		ID:          upload.ID,
+		UserID:      upload.UserID,
+		ContentType: upload.ContentType,
+		Size:        upload.Size,
+		Target:      upload.Target,
+		TargetID:    upload.TargetID,
+		Caption:     upload.Caption,
+		Status:      upload.Status,
+		Url:         upload.Url,
+		CreatedAt:   upload.CreatedAt,
+		ExpiresAt:   upload.ExpiresAt,
+	}
+}
+
+// CreateUploadRequest object which contains the file to upload, Size is in bytes
+type CreateUploadRequest struct {
+	ContentType string `json:"content_type" binding:"required"`
+	Size        int64  `json:"size" binding:"required,min=1"`
+	Target      Target `json:"target" binding:"required,oneof=profile_picture trip_cover trip_photo"`
+	TargetID    string `json:"target_id"`
+	Caption     string `json:"caption" binding:"max=500"`
+}
+
+// CreateUploadResponse object which contains the upload and where to upload the file,
+// the file must be uploaded with a PUT request to UploadUrl including Headers
+type CreateUploadResponse struct {
+	UploadResponse
+	UploadUrl string      `json:"upload_url"`
+	Headers   http.Header `json:"headers"`
+}
@@ -0,0 +1,214 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"speakeasy/pkg"
+	"speakeasy/pkg/clock"
+	"speakeasy/pkg/database"
+	"speakeasy/pkg/filestorage"
+	"speakeasy/pkg/ids"
+	"time"
+)
+
+// allowedContentTypes are the content types which can be uploaded
+var allowedContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// maxSizes contains the maximum file size in bytes of each target
+var maxSizes = map[Target]int64{
+	TargetProfilePicture: 10 << 20,
+	TargetTripCover:      10 << 20,
+	TargetTripPhoto:      20 << 20,
+}
+
+type _Service struct {
+	db      database.Service[Upload]
+	storage filestorage.Service
+	clock   clock.Clock
+	ids     ids.Generator
+	logger  *slog.Logger
+}
+
+// NewUploadService returns Service object which stores uploads in db and their files in storage
+func NewUploadService(db database.Service[Upload], storage filestorage.Service, clock clock.Clock, ids ids.Generator, logger *slog.Logger) Service {
+	return &_Service{db, storage, clock, ids, logger}
+}
+
+// Service interface which contains direct-to-storage upload operations
+type Service interface {
+	CreateUpload(ctx context.Context, userID string, request *CreateUploadRequest) (*CreateUploadResponse, *pkg.Error)
+	CompleteUpload(ctx context.Context, userID string, uploadID string, attach func(upload *Upload) *pkg.Error) (*Upload, *pkg.Error)
+	OpenFile(ctx context.Context, upload *Upload) (io.ReadCloser, *pkg.Error)
+}
+
+// CreateUpload function to create a pending upload and a presigned url to upload the file directly to storage
+func (service *_Service) CreateUpload(ctx context.Context, userID string, request *CreateUploadRequest) (*CreateUploadResponse, *pkg.Error) {
+	maxSize, ok := maxSizes[request.Target]
+	if !ok {
+		return nil, &pkg.Error{Code: 400, Reason: "Target is invalid"}
+	}
+
+	if (request.Target == TargetTripCover || request.Target == TargetTripPhoto) && request.TargetID == "" {
+		return nil, &pkg.Error{Code: 400, Reason: "Target id is required"}
+	}
+
+	if !allowedContentTypes[request.ContentType] {
+		return nil, &pkg.Error{Code: 415, Reason: "Content type must be image/jpeg, image/png or image/webp"}
+	}
+
+	if request.Size <= 0 {
+		return nil, &pkg.Error{Code: 400, Reason: "Size is invalid"}
+	}
+
+	if request.Size > maxSize {
+		return nil, &pkg.Error{Code: 413, Reason: fmt.Sprintf("File can't be larger than %d bytes", maxSize)}
+	}
+
+	now := service.clock.Now().UTC()
+	id := service.ids.NewID()
+
+	upload := &Upload{
+		PK:          fmt.Sprintf(UPLOAD_PK, userID),
+		SK:          fmt.Sprintf(UPLOAD_SK, id),
+		ID:          id,
+		UserID:      userID,
+		Key:         fmt.Sprintf(PENDING_UPLOAD_KEY, userID, id),
+		ContentType: request.ContentType,
+		Size:        request.Size,
+		Target:      request.Target,
+		TargetID:    request.TargetID,
+		Caption:     request.Caption,
+		Status:      StatusPending,
+		CreatedAt:   now.Format(time.RFC3339),
+		ExpiresAt:   now.Add(filestorage.UploadUrlExpiry).Format(time.RFC3339),
+	}
+
+	url, headers, err := service.storage.GetUploadUrl(upload.Key, upload.ContentType, upload.Size)
+	if err != nil {
+		return nil, pkg.Unavailable("CreateUpload", err)
+	}
+
+	if err := service.db.Write(ctx, upload); err != nil {
+		return nil, pkg.Unavailable("CreateUpload", err)
+	}
+
+	return &CreateUploadResponse{UploadResponse: *upload.Response(), UploadUrl: url, Headers: headers}, nil
+}
+
+// CompleteUpload function to verify the uploaded file matches the pending upload and attach it to its target.
+// The upload is only marked completed if attach succeeds, so a failed attach can be retried until the upload expires.
+// Attach processes the file into the target's own files and sets the url of the result, the uploaded file is deleted then.
+func (service *_Service) CompleteUpload(ctx context.Context, userID string, uploadID string, attach func(upload *Upload) *pkg.Error) (*Upload, *pkg.Error) {
+	input := map[string]string{
+		"PK": fmt.Sprintf(UPLOAD_PK, userID),
+		"SK": fmt.Sprintf(UPLOAD_SK, uploadID),
+	}
+
+	upload, err := service.db.Get(ctx, input)
+	if err != nil {
+		return nil, pkg.Unavailable("CompleteUpload", err)
+	}
+
+	if upload == nil {
+		return nil, &pkg.Error{Code: 404, Reason: "Upload not found"}
+	}
+
+	if upload.Status == StatusCompleted {
+		return nil, &pkg.Error{Code: 409, Reason: "Upload is already completed"}
+	}
+
+	if upload.Status == StatusProcessing {
+		return nil, &pkg.Error{Code: 409, Reason: "Upload is being completed"}
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, upload.ExpiresAt)
+	if err != nil {
+		return nil, pkg.Internal("CompleteUpload", err)
+	}
+
+	if !service.clock.Now().Before(expiresAt) {
+		if err := service.storage.DeleteFile(ctx, upload.Key); err != nil {
+			service.logger.Warn("delete expired upload file failed", "upload_id", upload.ID, "error", err.Error())
+		}
+
+		return nil, &pkg.Error{Code: 410, Reason: "Upload has expired"}
+	}
+
+	info, err := service.storage.HeadFile(ctx, upload.Key)
+	if errors.Is(err, filestorage.ErrNotFound) {
+		return nil, &pkg.Error{Code: 400, Reason: "File has not been uploaded"}
+	}
+
+	if err != nil {
+		return nil, pkg.Unavailable("CompleteUpload", err)
+	}
+
+	if info.Size != upload.Size || info.ContentType != upload.ContentType {
+		if err := service.storage.DeleteFile(ctx, upload.Key); err != nil {
+			service.logger.Warn("delete mismatched upload file failed", "upload_id", upload.ID, "error", err.Error())
+		}
+
+		return nil, &pkg.Error{Code: 400, Reason: "Uploaded file does not match the upload size or content type"}
+	}
+
+	// Claimed before attaching, concurrent requests which read the upload as pending fail here
+	err = service.setStatus(ctx, input, StatusPending, StatusProcessing)
+	if errors.Is(err, database.ErrConditionFailed) {
+		return nil, &pkg.Error{Code: 409, Reason: "Upload is being completed"}
+	}
+
+	if err != nil {
+		return nil, pkg.Unavailable("CompleteUpload", err)
+	}
+
+	if err := attach(upload); err != nil {
+		// Pending again so the upload can be retried
+		if err := service.setStatus(ctx, input, StatusProcessing, StatusPending); err != nil {
+			service.logger.Warn("release upload failed", "upload_id", upload.ID, "error", err.Error())
+		}
+
+		return nil, err
+	}
+
+	if err := service.setStatus(ctx, input, StatusProcessing, StatusCompleted); err != nil {
+		return nil, pkg.Unavailable("CompleteUpload", err)
+	}
+
+	upload.Status = StatusCompleted
+
+	// Attach stores processed copies of the file, the uploaded file is not needed anymore
+	if err := service.storage.DeleteFile(ctx, upload.Key); err != nil {
+		service.logger.Warn("delete processed upload file failed", "upload_id", upload.ID, "error", err.Error())
+	}
+
+	return upload, nil
+}
+
+// setStatus changes the status of the upload if it still has the expected status
+func (service *_Service) setStatus(ctx context.Context, key map[string]string, from Status, to Status) error {
+	_, err := service.db.Update(ctx, key, &database.Update{
+		Set:       map[string]interface{}{"status": to},
+		Condition: "#status = :status",
+		Names:     map[string]string{"#status": "status"},
+		Values:    map[string]interface{}{":status": from},
+	})
+
+	return err
+}
+
+// OpenFile function to download the uploaded file, the caller must close the returned reader
+func (service *_Service) OpenFile(ctx context.Context, upload *Upload) (io.ReadCloser, *pkg.Error) {
+	file, err := service.storage.GetFile(ctx, upload.Key)
+	if err != nil {
+		return nil, pkg.Unavailable("OpenFile", err)
+	}
+
+	return file, nil
+}
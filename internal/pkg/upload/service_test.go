@@ -0,0 +1,313 @@
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"speakeasy/pkg"
+	"speakeasy/pkg/clock"
+	"speakeasy/pkg/database"
+	"speakeasy/pkg/filestorage"
+	"speakeasy/pkg/ids"
+	"speakeasy/pkg/logging"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testLogger drops the log records of the services under test
+var testLogger = logging.Discard()
+
+// testClock and testIDs are the real clock and uuid generator, tests which check times or ids set fixed ones
+var (
+	testClock = clock.System()
+	testIDs   = ids.UUID()
+)
+
+// Mock DatabaseService which keeps uploads in memory
+type _DatabaseServiceMockInMemory struct {
+	database.Service[Upload]
+	items map[string]Upload
+}
+
+func newInMemoryDatabase() *_DatabaseServiceMockInMemory {
+	return &_DatabaseServiceMockInMemory{items: map[string]Upload{}}
+}
+
+func (db *_DatabaseServiceMockInMemory) Get(ctx context.Context, keyObj interface{}) (*Upload, error) {
+	key := keyObj.(map[string]string)
+	item, ok := db.items[key["PK"]+"|"+key["SK"]]
+	if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+func (db *_DatabaseServiceMockInMemory) Write(ctx context.Context, obj ...*Upload) error {
+	for _, item := range obj {
+		db.items[item.PK+"|"+item.SK] = *item
+	}
+	return nil
+}
+
+// Update changes the status of the upload if it has the status of the condition
+func (db *_DatabaseServiceMockInMemory) Update(ctx context.Context, keyObj interface{}, update *database.Update) (*Upload, error) {
+	key := keyObj.(map[string]string)
+	item, ok := db.items[key["PK"]+"|"+key["SK"]]
+	if !ok || item.Status != update.Values[":status"] {
+		return nil, database.ErrConditionFailed
+	}
+	item.Status = update.Set["status"].(Status)
+	db.items[key["PK"]+"|"+key["SK"]] = item
+	return &item, nil
+}
+
+// Mock DatabaseService which returns uploads as they were read before another request claimed them
+type _DatabaseServiceMockStale struct {
+	*_DatabaseServiceMockInMemory
+}
+
+func (db *_DatabaseServiceMockStale) Get(ctx context.Context, keyObj interface{}) (*Upload, error) {
+	upload, err := db._DatabaseServiceMockInMemory.Get(ctx, keyObj)
+	if upload != nil {
+		upload.Status = StatusPending
+	}
+	return upload, err
+}
+
+// Mock DatabaseService where every operation returns an error
+type _DatabaseServiceMockError struct {
+	database.Service[Upload]
+}
+
+func (db *_DatabaseServiceMockError) Get(ctx context.Context, keyObj interface{}) (*Upload, error) {
+	return nil, errors.New("ERROR")
+}
+
+func (db *_DatabaseServiceMockError) Write(ctx context.Context, obj ...*Upload) error {
+	return errors.New("ERROR")
+}
+
+// Mock StorageService which keeps uploaded file metadata in memory
+type _StorageServiceMock struct {
+	filestorage.Service
+	files   map[string]filestorage.FileInfo
+	deleted []string
+}
+
+func newStorage() *_StorageServiceMock {
+	return &_StorageServiceMock{files: map[string]filestorage.FileInfo{}}
+}
+
+func (storage *_StorageServiceMock) GetUploadUrl(filename string, contentType string, size int64) (string, http.Header, error) {
+	return "https://storage/" + filename + "?signature", http.Header{"Content-Type": {contentType}}, nil
+}
+
+func (storage *_StorageServiceMock) HeadFile(ctx context.Context, filename string) (*filestorage.FileInfo, error) {
+	info, ok := storage.files[filename]
+	if !ok {
+		return nil, filestorage.ErrNotFound
+	}
+	return &info, nil
+}
+
+func (storage *_StorageServiceMock) GetFile(ctx context.Context, filename string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("file")), nil
+}
+
+func (storage *_StorageServiceMock) DeleteFile(ctx context.Context, filename string) error {
+	storage.deleted = append(storage.deleted, filename)
+	return nil
+}
+
+func attachNothing(upload *Upload) *pkg.Error {
+	return nil
+}
+
+func TestNewUploadService(t *testing.T) {
+	t.Run("SUCCESS: RETURN NEW UPLOAD SERVICE", func(t *testing.T) {
+		svc := NewUploadService(newInMemoryDatabase(), newStorage(), testClock, testIDs, testLogger)
+
+		assert.NotEmpty(t, svc, "Service should not empty")
+	})
+}
+
+func TestCreateUpload(t *testing.T) {
+	t.Run("SUCCESS: CREATE PENDING UPLOAD WITH UPLOAD URL", func(t *testing.T) {
+		db := newInMemoryDatabase()
+		svc := &_Service{db: db, storage: newStorage(), clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.CreateUpload(context.Background(), "user", &CreateUploadRequest{ContentType: "image/png", Size: 100, Target: TargetProfilePicture})
+
+		assert.Empty(t, err, "Error should be empty")
+		assert.Equal(t, StatusPending, result.Status)
+		assert.Equal(t, "image/png", result.Headers.Get("Content-Type"))
+		assert.Contains(t, result.UploadUrl, "uploads/user/"+result.ID)
+		body, _ := json.Marshal(result)
+		assert.NotContains(t, string(body), `"PK"`, "Keys should not be returned")
+		assert.Len(t, db.items, 1)
+	})
+
+	t.Run("SUCCESS: STORE TRIP COVER UNDER PENDING UPLOADS TO PROCESS IT", func(t *testing.T) {
+		db := newInMemoryDatabase()
+		svc := &_Service{db: db, storage: newStorage(), clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.CreateUpload(context.Background(), "user", &CreateUploadRequest{ContentType: "image/jpeg", Size: 100, Target: TargetTripCover, TargetID: "trip"})
+
+		assert.Empty(t, err, "Error should be empty")
+		assert.Contains(t, result.UploadUrl, "uploads/user/"+result.ID)
+	})
+
+	t.Run("ERROR: RETURN ERROR WHEN REQUEST IS INVALID", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), storage: newStorage(), clock: testClock, ids: testIDs, logger: testLogger}
+
+		requests := map[int]*CreateUploadRequest{
+			400: {ContentType: "image/png", Size: 100, Target: "unknown"},
+			415: {ContentType: "application/pdf", Size: 100, Target: TargetProfilePicture},
+			413: {ContentType: "image/png", Size: 100 << 20, Target: TargetProfilePicture},
+		}
+
+		for code, request := range requests {
+			result, err := svc.CreateUpload(context.Background(), "user", request)
+
+			assert.Equal(t, code, err.Code)
+			assert.Empty(t, result, "Result should be empty")
+		}
+
+		_, err := svc.CreateUpload(context.Background(), "user", &CreateUploadRequest{ContentType: "image/png", Size: 100, Target: TargetTripCover})
+		assert.Equal(t, 400, err.Code, "Trip id should be required")
+	})
+
+	t.Run("ERROR: RETURN 503 WHEN DB WRITE RETURNS ERROR", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockError{}, storage: newStorage(), clock: testClock, ids: testIDs, logger: testLogger}
+
+		result, err := svc.CreateUpload(context.Background(), "user", &CreateUploadRequest{ContentType: "image/png", Size: 100, Target: TargetProfilePicture})
+
+		assert.Equal(t, 503, err.Code, "Error should be 503")
+		assert.Empty(t, result, "Result should be empty")
+	})
+}
+
+func TestCompleteUpload(t *testing.T) {
+	create := func(svc *_Service) *CreateUploadResponse {
+		result, _ := svc.CreateUpload(context.Background(), "user", &CreateUploadRequest{ContentType: "image/png", Size: 100, Target: TargetProfilePicture})
+		return result
+	}
+
+	t.Run("SUCCESS: COMPLETE UPLOAD AND ATTACH FILE", func(t *testing.T) {
+		storage := newStorage()
+		svc := &_Service{db: newInMemoryDatabase(), storage: storage, clock: testClock, ids: testIDs, logger: testLogger}
+		created := create(svc)
+		storage.files["uploads/user/"+created.ID] = filestorage.FileInfo{Size: 100, ContentType: "image/png"}
+
+		var attached *Upload
+		result, err := svc.CompleteUpload(context.Background(), "user", created.ID, func(upload *Upload) *pkg.Error {
+			attached = upload
+			upload.Url = "https://storage/processed.jpg?signature=test"
+			return nil
+		})
+
+		assert.Empty(t, err, "Error should be empty")
+		assert.Equal(t, StatusCompleted, result.Status)
+		assert.Equal(t, created.ID, attached.ID)
+		assert.Equal(t, "https://storage/processed.jpg?signature=test", result.Url)
+		assert.Equal(t, []string{"uploads/user/" + created.ID}, storage.deleted, "Uploaded file should be deleted after processing")
+
+		_, err = svc.CompleteUpload(context.Background(), "user", created.ID, attachNothing)
+		assert.Equal(t, 409, err.Code, "Upload should only be completed once")
+	})
+
+	t.Run("ERROR: KEEP UPLOAD PENDING WHEN ATTACH FAILS", func(t *testing.T) {
+		storage := newStorage()
+		db := newInMemoryDatabase()
+		svc := &_Service{db: db, storage: storage, clock: testClock, ids: testIDs, logger: testLogger}
+		created := create(svc)
+		storage.files["uploads/user/"+created.ID] = filestorage.FileInfo{Size: 100, ContentType: "image/png"}
+
+		_, err := svc.CompleteUpload(context.Background(), "user", created.ID, func(upload *Upload) *pkg.Error {
+			return &pkg.Error{Code: 415, Reason: "Unsupported"}
+		})
+
+		assert.Equal(t, 415, err.Code, "Error should be 415")
+		assert.Equal(t, StatusPending, db.items["USER#user|UPLOAD#"+created.ID].Status)
+		assert.Empty(t, storage.deleted, "Uploaded file should be kept to retry")
+	})
+
+	t.Run("ERROR: RETURN 409 WHEN UPLOAD IS CLAIMED BY ANOTHER REQUEST", func(t *testing.T) {
+		storage := newStorage()
+		db := newInMemoryDatabase()
+		svc := &_Service{db: db, storage: storage, clock: testClock, ids: testIDs, logger: testLogger}
+		created := create(svc)
+		storage.files["uploads/user/"+created.ID] = filestorage.FileInfo{Size: 100, ContentType: "image/png"}
+
+		item := db.items["USER#user|UPLOAD#"+created.ID]
+		item.Status = StatusProcessing
+		db.items["USER#user|UPLOAD#"+created.ID] = item
+		svc.db = &_DatabaseServiceMockStale{db}
+
+		attached := false
+		_, err := svc.CompleteUpload(context.Background(), "user", created.ID, func(upload *Upload) *pkg.Error {
+			attached = true
+			return nil
+		})
+
+		assert.Equal(t, 409, err.Code, "Error should be 409")
+		assert.False(t, attached, "File should only be attached by the request which claimed the upload")
+		assert.Equal(t, StatusProcessing, db.items["USER#user|UPLOAD#"+created.ID].Status)
+	})
+
+	t.Run("ERROR: RETURN 410 AND DELETE FILE WHEN UPLOAD HAS EXPIRED", func(t *testing.T) {
+		storage := newStorage()
+		svc := &_Service{db: newInMemoryDatabase(), storage: storage, clock: clock.Fixed(time.Now().Add(-filestorage.UploadUrlExpiry - time.Minute)), ids: testIDs, logger: testLogger}
+		created := create(svc)
+		storage.files["uploads/user/"+created.ID] = filestorage.FileInfo{Size: 100, ContentType: "image/png"}
+		svc.clock = testClock
+
+		_, err := svc.CompleteUpload(context.Background(), "user", created.ID, attachNothing)
+
+		assert.Equal(t, 410, err.Code, "Error should be 410")
+		assert.Equal(t, []string{"uploads/user/" + created.ID}, storage.deleted)
+	})
+
+	t.Run("ERROR: RETURN 400 WHEN FILE IS NOT UPLOADED", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), storage: newStorage(), clock: testClock, ids: testIDs, logger: testLogger}
+		created := create(svc)
+
+		_, err := svc.CompleteUpload(context.Background(), "user", created.ID, attachNothing)
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+	})
+
+	t.Run("ERROR: DELETE FILE WHEN IT DOES NOT MATCH UPLOAD", func(t *testing.T) {
+		storage := newStorage()
+		svc := &_Service{db: newInMemoryDatabase(), storage: storage, clock: testClock, ids: testIDs, logger: testLogger}
+		created := create(svc)
+		storage.files["uploads/user/"+created.ID] = filestorage.FileInfo{Size: 5000, ContentType: "image/png"}
+
+		_, err := svc.CompleteUpload(context.Background(), "user", created.ID, attachNothing)
+
+		assert.Equal(t, 400, err.Code, "Error should be 400")
+		assert.Equal(t, []string{"uploads/user/" + created.ID}, storage.deleted)
+	})
+
+	t.Run("ERROR: RETURN 404 WHEN UPLOAD BELONGS TO ANOTHER USER", func(t *testing.T) {
+		svc := &_Service{db: newInMemoryDatabase(), storage: newStorage(), clock: testClock, ids: testIDs, logger: testLogger}
+		created := create(svc)
+
+		_, err := svc.CompleteUpload(context.Background(), "other", created.ID, attachNothing)
+
+		assert.Equal(t, 404, err.Code, "Error should be 404")
+	})
+
+	t.Run("ERROR: RETURN 503 WHEN DB READ RETURNS ERROR", func(t *testing.T) {
+		svc := &_Service{db: &_DatabaseServiceMockError{}, storage: newStorage(), clock: testClock, ids: testIDs, logger: testLogger}
+
+		_, err := svc.CompleteUpload(context.Background(), "user", "upload", attachNothing)
+
+		assert.Equal(t, 503, err.Code, "Error should be 503")
+	})
+}
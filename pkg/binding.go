@@ -0,0 +1,183 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Password policy of the password binding tag, bcrypt only uses the first 72 bytes
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// validators contains the custom binding tags keyed by tag name
+var validators = map[string]validator.Func{
+	"notblank": func(fl validator.FieldLevel) bool {
+		return strings.TrimSpace(fl.Field().String()) != ""
+	},
+	"password": func(fl validator.FieldLevel) bool {
+		return ValidPassword(fl.Field().String())
+	},
+	"http_url": func(fl validator.FieldLevel) bool {
+		return isHTTPURL(fl.Field().String())
+	},
+	"phone": func(fl validator.FieldLevel) bool {
+		return phonePattern.MatchString(fl.Field().String())
+	},
+	"date": func(fl validator.FieldLevel) bool {
+		_, err := time.Parse(DateLayout, fl.Field().String())
+		return err == nil
+	},
+	"rfc3339": func(fl validator.FieldLevel) bool {
+		_, err := time.Parse(time.RFC3339, fl.Field().String())
+		return err == nil
+	},
+}
+
+// messages contains the reason of a failed binding tag keyed by tag name,
+// %s is replaced with the tag parameter
+var messages = map[string]string{
+	"required": "Is required",
+	"notblank": "Cannot be empty",
+	"email":    "Must be a valid email address",
+	"http_url": "Must be a valid http or https url",
+	"oneof":    "Must be one of %s",
+	"password": fmt.Sprintf("Must be %d to %d characters with an upper case letter, a lower case letter and a digit", MinPasswordLength, MaxPasswordLength),
+	"phone":    "Must be a phone number in E.164 format",
+	"date":     "Must be a date in YYYY-MM-DD format",
+	"rfc3339":  "Must be a RFC 3339 timestamp",
+}
+
+// RegisterValidators registers the custom binding tags with the Gin validator and names invalid
+// fields by their json or form name, it should be called once at server start before any request is bound.
+func RegisterValidators() error {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("RegisterValidators: unsupported binding validator")
+	}
+
+	validate.RegisterTagNameFunc(fieldName)
+
+	for tag, fn := range validators {
+		if err := validate.RegisterValidation(tag, fn); err != nil {
+			return fmt.Errorf("RegisterValidators: %s: %w", tag, err)
+		}
+	}
+
+	return nil
+}
+
+// ValidPassword returns true if the password meets the password policy
+func ValidPassword(password string) bool {
+	length := utf8.RuneCountInString(password)
+	if length < MinPasswordLength || len(password) > MaxPasswordLength {
+		return false
+	}
+
+	var upper, lower, digit bool
+	for _, r := range password {
+		upper = upper || unicode.IsUpper(r)
+		lower = lower || unicode.IsLower(r)
+		digit = digit || unicode.IsDigit(r)
+	}
+
+	return upper && lower && digit
+}
+
+// BindingError returns 400 error for a request body or query which can't be bound,
+// failed binding tags and invalid json types are reported as details of each field.
+func BindingError(err error) *Error {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		details := map[string]string{}
+		for _, fieldErr := range validationErrs {
+			details[fieldPath(fieldErr.Namespace())] = message(fieldErr)
+		}
+
+		return &Error{Code: http.StatusBadRequest, Type: TypeValidationFailed, Reason: "Validation failed", Details: details, Err: err}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		details := map[string]string{typeErr.Field: "Has invalid type"}
+		return &Error{Code: http.StatusBadRequest, Type: TypeValidationFailed, Reason: "Validation failed", Details: details, Err: err}
+	}
+
+	return &Error{Code: http.StatusBadRequest, Reason: "Request is invalid", Err: err}
+}
+
+// Validate checks the binding tags of a request which wasn't bound by Gin, e.g. a merged patch,
+// invalid fields are reported like BindingError. RegisterValidators must be called first.
+func Validate(request interface{}) *Error {
+	if err := binding.Validator.ValidateStruct(request); err != nil {
+		return BindingError(err)
+	}
+
+	return nil
+}
+
+// message returns the reason of the failed binding tag
+func message(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "max", "lte":
+		return limitMessage("at most", fieldErr)
+	case "min", "gte":
+		return limitMessage("at least", fieldErr)
+	case "oneof":
+		return fmt.Sprintf(messages["oneof"], strings.Join(strings.Fields(fieldErr.Param()), ", "))
+	}
+
+	if reason, ok := messages[fieldErr.Tag()]; ok {
+		return reason
+	}
+
+	return "Is invalid"
+}
+
+// limitMessage returns the reason of a failed length or number limit depending on the field kind
+func limitMessage(limit string, fieldErr validator.FieldError) string {
+	switch fieldErr.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("Must be %s %s characters", limit, fieldErr.Param())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return fmt.Sprintf("Must have %s %s items", limit, fieldErr.Param())
+	default:
+		return fmt.Sprintf("Must be %s %s", limit, fieldErr.Param())
+	}
+}
+
+// fieldName returns the json name of the field, or the form name for query parameters
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if name == "-" {
+			return ""
+		}
+
+		if name != "" {
+			return name
+		}
+	}
+
+	return field.Name
+}
+
+// fieldPath removes the request type from the namespace of the field, e.g. location.city
+func fieldPath(namespace string) string {
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+
+	return namespace
+}
@@ -0,0 +1,136 @@
+package pkg
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMain registers the custom binding tags once, like the server does at start
+func TestMain(m *testing.M) {
+	if err := RegisterValidators(); err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+// Request object which has a field for each custom binding tag
+type _Request struct {
+	Name      string            `json:"name" binding:"omitempty,notblank,max=5"`
+	Password  string            `json:"password" binding:"omitempty,password"`
+	Phone     string            `json:"phone" binding:"omitempty,phone"`
+	BirthDate string            `json:"birth_date" binding:"omitempty,date"`
+	StartsAt  string            `json:"starts_at" binding:"omitempty,rfc3339"`
+	Links     map[string]string `json:"links" binding:"max=2,dive,http_url"`
+	Limit     int64             `form:"limit" binding:"min=0"`
+	Role      string            `json:"role" binding:"omitempty,oneof=owner editor"`
+}
+
+func TestValidators(t *testing.T) {
+	for _, test := range []struct {
+		field   string
+		request _Request
+		valid   bool
+	}{
+		{"password", _Request{Password: "Passw0rd"}, true},
+		{"password", _Request{Password: "Pässw0rd"}, true},
+		{"password", _Request{Password: "Pa55w0"}, false},
+		{"password", _Request{Password: "password1"}, false},
+		{"password", _Request{Password: "PASSWORD1"}, false},
+		{"password", _Request{Password: "Password"}, false},
+		{"password", _Request{Password: "Passw0rd" + strings.Repeat("a", MaxPasswordLength-7)}, false},
+		{"phone", _Request{Phone: "+14155550100"}, true},
+		{"phone", _Request{Phone: "+351912345678"}, true},
+		{"phone", _Request{Phone: "4155550100"}, false},
+		{"phone", _Request{Phone: "+0155550100"}, false},
+		{"phone", _Request{Phone: "+1 415 555 0100"}, false},
+		{"phone", _Request{Phone: "+1234567890123456"}, false},
+		{"birth_date", _Request{BirthDate: "2000-02-29"}, true},
+		{"birth_date", _Request{BirthDate: "2001-02-29"}, false},
+		{"birth_date", _Request{BirthDate: "15/06/2000"}, false},
+		{"birth_date", _Request{BirthDate: "2000-06-15T00:00:00Z"}, false},
+		{"starts_at", _Request{StartsAt: "2024-06-15T10:00:00+01:00"}, true},
+		{"starts_at", _Request{StartsAt: "2024-06-15"}, false},
+		{"name", _Request{Name: "jane"}, true},
+		{"name", _Request{Name: "   "}, false},
+		{"links[site]", _Request{Links: map[string]string{"site": "https://example.com"}}, true},
+		{"links[site]", _Request{Links: map[string]string{"site": "javascript:alert(1)"}}, false},
+		{"links[site]", _Request{Links: map[string]string{"site": "example.com"}}, false},
+	} {
+		name := "SUCCESS: ACCEPT VALID "
+		if !test.valid {
+			name = "ERROR: REJECT INVALID "
+		}
+
+		t.Run(name+strings.ToUpper(test.field), func(t *testing.T) {
+			err := Validate(&test.request)
+
+			if test.valid {
+				assert.Empty(t, err, "Error should be empty")
+				return
+			}
+
+			assert.Equal(t, http.StatusBadRequest, err.Code, "Error should be 400")
+			assert.Equal(t, []string{test.field}, keys(err.Details))
+		})
+	}
+}
+
+func TestBindingError(t *testing.T) {
+	t.Run("ERROR: RETURN REASON OF EACH INVALID FIELD", func(t *testing.T) {
+		err := Validate(&_Request{
+			Name:      "jane doe",
+			Password:  "password",
+			Phone:     "0912345678",
+			BirthDate: "15/06/2000",
+			StartsAt:  "tomorrow",
+			Links:     map[string]string{"a": "https://a.com", "b": "https://b.com", "c": "https://c.com"},
+			Limit:     -1,
+			Role:      "admin",
+		})
+
+		assert.Equal(t, http.StatusBadRequest, err.Code, "Error should be 400")
+		assert.Equal(t, TypeValidationFailed, err.Type)
+		assert.Equal(t, map[string]string{
+			"name":       "Must be at most 5 characters",
+			"password":   messages["password"],
+			"phone":      "Must be a phone number in E.164 format",
+			"birth_date": "Must be a date in YYYY-MM-DD format",
+			"starts_at":  "Must be a RFC 3339 timestamp",
+			"links":      "Must have at most 2 items",
+			"limit":      "Must be at least 0",
+			"role":       "Must be one of owner, editor",
+		}, err.Details)
+	})
+
+	t.Run("ERROR: RETURN FIELD WITH INVALID TYPE", func(t *testing.T) {
+		var request _Request
+		err := BindingError(json.Unmarshal([]byte(`{"phone": 4155550100}`), &request))
+
+		assert.Equal(t, http.StatusBadRequest, err.Code, "Error should be 400")
+		assert.Equal(t, TypeValidationFailed, err.Type)
+		assert.Equal(t, map[string]string{"phone": "Has invalid type"}, err.Details)
+	})
+
+	t.Run("ERROR: RETURN 400 WITHOUT DETAILS WHEN BODY IS NOT JSON", func(t *testing.T) {
+		var request _Request
+		err := BindingError(json.Unmarshal([]byte(`{"phone"`), &request))
+
+		assert.Equal(t, http.StatusBadRequest, err.Code, "Error should be 400")
+		assert.Equal(t, "Request is invalid", err.Reason)
+		assert.Empty(t, err.Details)
+	})
+}
+
+func keys(details map[string]string) []string {
+	result := []string{}
+	for key := range details {
+		result = append(result, key)
+	}
+	return result
+}
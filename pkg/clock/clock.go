@@ -0,0 +1,32 @@
+package clock
+
+import "time"
+
+// Clock interface which returns the current time, services use it instead of time.Now so tests can fix the time
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+// System returns Clock which returns the system time
+func System() Clock {
+	return systemClock{}
+}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+type fixedClock struct {
+	now time.Time
+}
+
+// Fixed returns Clock which always returns now, for deterministic tests
+func Fixed(now time.Time) Clock {
+	return fixedClock{now}
+}
+
+func (clock fixedClock) Now() time.Time {
+	return clock.now
+}
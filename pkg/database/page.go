@@ -0,0 +1,71 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// QueryOptions object which contains optional parameters for QueryPage function.
+// Limit is applied before FilterExpression, so a page can contain less items than Limit.
+type QueryOptions struct {
+	Index            string
+	FilterExpression string
+	Names            map[string]string
+	Limit            int64
+	Cursor           string
+	Descending       bool
+}
+
+// Page object which contains query results and the cursor to get the next page,
+// Cursor is empty when there are no more results.
+type Page[T any] struct {
+	Items  []T    `json:"items"`
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// MapPage returns page with each item converted by fn, e.g. storage items to the responses sent to clients
+func MapPage[T any, R any](page *Page[T], fn func(item *T) R) *Page[R] {
+	items := make([]R, 0, len(page.Items))
+	for i := range page.Items {
+		items = append(items, fn(&page.Items[i]))
+	}
+
+	return &Page[R]{Items: items, Cursor: page.Cursor}
+}
+
+// encodeCursor function to encode last evaluated key as an opaque cursor
+func encodeCursor(key map[string]*dynamodb.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+
+	var values map[string]string
+	if err := dynamodbattribute.UnmarshalMap(key, &values); err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor function to decode cursor into exclusive start key
+func decodeCursor(cursor string) (map[string]*dynamodb.AttributeValue, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+
+	return dynamodbattribute.MarshalMap(values)
+}
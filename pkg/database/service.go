@@ -1,22 +1,48 @@
 package database
 
 import (
-	"log"
-	"os"
+	"context"
+	"errors"
+	"fmt"
+	"speakeasy/pkg/timeout"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 )
 
+// ErrInvalidCursor is returned when a page cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrUnprocessedItems is returned when batch items are still unprocessed after the last attempt, e.g. the table is throttled
+var ErrUnprocessedItems = errors.New("unprocessed batch items")
+
+const maxBatchWriteItems = 25
+const maxBatchGetItems = 100
+
+// maxBatchAttempts is the number of batch requests sent for the same items before ErrUnprocessedItems is returned
+const maxBatchAttempts = 5
+
+// batchRetryDelay is the delay before the first retry of unprocessed items, it doubles on every retry
+const batchRetryDelay = 50 * time.Millisecond
+
+// Config object which contains the settings of the DynamoDB client,
+// Endpoint is only set for DynamoDB local
+type Config struct {
+	Region   string
+	Endpoint string
+}
+
 type _Service[T any] struct {
-	db        *dynamodb.DynamoDB
+	db        dynamodbiface.DynamoDBAPI
 	tableName string
 }
 
-// NewDatabaseService function to initialize Service object
-func NewDatabaseService[T any](tableName string) Service[T] {
+// NewClient function to initialize DynamoDB client, the client should be shared by the services of all tables
+func NewClient(config Config) dynamodbiface.DynamoDBAPI {
 	// https://docs.aws.amazon.com/sdk-for-go/v1/developer-guide/configuring-sdk.html#specifying-credentials
 	// Initialize session and config for initializing client
 	sess := session.Must(session.NewSessionWithOptions(session.Options{
@@ -24,30 +50,63 @@ func NewDatabaseService[T any](tableName string) Service[T] {
 	}))
 
 	cfg := &aws.Config{
-		Region:   aws.String(os.Getenv("AWS_REGION")),
-		Endpoint: aws.String(os.Getenv("DYNAMODB_ENDPOINT")),
+		Region:   aws.String(config.Region),
+		Endpoint: aws.String(config.Endpoint),
 	}
 
-	// Create new DynamoDB client
-	db := dynamodb.New(sess, cfg)
-	return &_Service[T]{db, tableName}
+	return dynamodb.New(sess, cfg)
 }
 
-// Service interface which contains database operations
+// NewDatabaseService function to initialize Service object which reads and writes the items of the table with client
+func NewDatabaseService[T any](client dynamodbiface.DynamoDBAPI, tableName string) Service[T] {
+	return &_Service[T]{client, tableName}
+}
+
+// Service interface which contains database operations, each operation is cancelled when ctx is done
+// or after the timeout of the operation kind configured in the timeout package
 type Service[T any] interface {
-	Get(keyObj interface{}) (*T, error)
-	Write(obj ...*T) error
-	Delete(obj interface{}) error
-	Query(filterObj interface{}, condition string) (*[]T, error)
-	QueryWithIndex(filterObj interface{}, condition string, filterExpr string, index string) (*[]T, error)
+	Get(ctx context.Context, keyObj interface{}) (*T, error)
+	Write(ctx context.Context, obj ...*T) error
+	Delete(ctx context.Context, obj interface{}) error
+	Query(ctx context.Context, filterObj interface{}, condition string) (*[]T, error)
+	QueryWithIndex(ctx context.Context, filterObj interface{}, condition string, filterExpr string, index string) (*[]T, error)
+	QueryWithFilter(ctx context.Context, filterObj interface{}, condition string, filterExpr string, names map[string]string) (*[]T, error)
+	QueryPage(ctx context.Context, filterObj interface{}, condition string, options *QueryOptions) (*Page[T], error)
+	BatchGet(ctx context.Context, keyObjs ...interface{}) (*[]T, error)
+	Transaction(ctx context.Context, items ...TransactionItem) error
+	Update(ctx context.Context, keyObj interface{}, update *Update) (*T, error)
+	Ping(ctx context.Context) error
+}
+
+// Ping function to check the table can be reached and is active, it's used by readiness checks
+func (service *_Service[T]) Ping(ctx context.Context) error {
+	ctx, cancel := timeout.WithTimeout(ctx, timeout.DatabaseRead)
+	defer cancel()
+
+	output, err := service.db.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: &service.tableName,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Tables stay usable while they are updated, e.g. when an index is added
+	status := aws.StringValue(output.Table.TableStatus)
+	if status != dynamodb.TableStatusActive && status != dynamodb.TableStatusUpdating {
+		return fmt.Errorf("table %s is %s", service.tableName, status)
+	}
+
+	return nil
 }
 
 // Get function to read data from database
-func (service *_Service[T]) Get(keyObj interface{}) (*T, error) {
+func (service *_Service[T]) Get(ctx context.Context, keyObj interface{}) (*T, error) {
+	ctx, cancel := timeout.WithTimeout(ctx, timeout.DatabaseRead)
+	defer cancel()
+
 	// Create key object for DynamoDB Key
 	key, marshallError := dynamodbattribute.MarshalMap(keyObj)
 	if marshallError != nil {
-		log.Println("GetError: MarshalError: ", marshallError)
 		return nil, marshallError
 	}
 
@@ -56,9 +115,8 @@ func (service *_Service[T]) Get(keyObj interface{}) (*T, error) {
 		Key:       key,
 	}
 
-	result, err := service.db.GetItem(input)
+	result, err := service.db.GetItemWithContext(ctx, input)
 	if err != nil {
-		log.Println("GetError: ", err)
 		return nil, err
 	}
 
@@ -73,14 +131,16 @@ func (service *_Service[T]) Get(keyObj interface{}) (*T, error) {
 }
 
 // Write function to write data from database
-func (service *_Service[T]) Write(objs ...*T) error {
+func (service *_Service[T]) Write(ctx context.Context, objs ...*T) error {
+	ctx, cancel := timeout.WithTimeout(ctx, timeout.DatabaseWrite)
+	defer cancel()
+
 	items := []*dynamodb.WriteRequest{}
 
 	for _, obj := range objs {
 		// Create item object for DynamoDB
 		item, err := dynamodbattribute.MarshalMap(obj)
 		if err != nil {
-			log.Println("Error: ", err)
 			return err
 		}
 
@@ -93,22 +153,65 @@ func (service *_Service[T]) Write(objs ...*T) error {
 		items = append(items, &req)
 	}
 
-	input := &dynamodb.BatchWriteItemInput{
-		RequestItems: map[string][]*dynamodb.WriteRequest{
-			service.tableName: items,
-		},
+	// BatchWriteItem accepts at most 25 items per request
+	for start := 0; start < len(items); start += maxBatchWriteItems {
+		end := start + maxBatchWriteItems
+		if end > len(items) {
+			end = len(items)
+		}
+
+		request := map[string][]*dynamodb.WriteRequest{
+			service.tableName: items[start:end],
+		}
+
+		// Throttled items are returned as unprocessed and must be sent again
+		for attempt := 0; len(request) > 0; attempt++ {
+			if err := waitBeforeRetry(ctx, attempt); err != nil {
+				return err
+			}
+
+			result, err := service.db.BatchWriteItemWithContext(ctx, &dynamodb.BatchWriteItemInput{RequestItems: request})
+			if err != nil {
+				return err
+			}
+
+			request = result.UnprocessedItems
+		}
 	}
 
-	_, err := service.db.BatchWriteItem(input)
-	return err
+	return nil
+}
+
+// waitBeforeRetry waits with exponential backoff before the attempt of a batch request, the first attempt is not delayed.
+// Returns ErrUnprocessedItems if there are no attempts left, or the error of ctx if it's done while waiting.
+func waitBeforeRetry(ctx context.Context, attempt int) error {
+	if attempt == 0 {
+		return nil
+	}
+
+	if attempt >= maxBatchAttempts {
+		return ErrUnprocessedItems
+	}
+
+	timer := time.NewTimer(batchRetryDelay << (attempt - 1))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // Delete function to delete data from database
-func (service *_Service[T]) Delete(keyObj interface{}) error {
+func (service *_Service[T]) Delete(ctx context.Context, keyObj interface{}) error {
+	ctx, cancel := timeout.WithTimeout(ctx, timeout.DatabaseWrite)
+	defer cancel()
+
 	// Create item object for DynamoDB
 	key, marshalError := dynamodbattribute.MarshalMap(keyObj)
 	if marshalError != nil {
-		log.Println("Error: ", marshalError)
 		return marshalError
 	}
 
@@ -117,16 +220,18 @@ func (service *_Service[T]) Delete(keyObj interface{}) error {
 		Key:       key,
 	}
 
-	_, err := service.db.DeleteItem(input)
+	_, err := service.db.DeleteItemWithContext(ctx, input)
 	return err
 }
 
 // Query function to query data from database
-func (service *_Service[T]) Query(filterObj interface{}, condition string) (*[]T, error) {
+func (service *_Service[T]) Query(ctx context.Context, filterObj interface{}, condition string) (*[]T, error) {
+	ctx, cancel := timeout.WithTimeout(ctx, timeout.DatabaseQuery)
+	defer cancel()
+
 	// Create item object for DynamoDB
 	filter, err := dynamodbattribute.MarshalMap(filterObj)
 	if err != nil {
-		log.Println("QueryError: ", err)
 		return nil, err
 	}
 
@@ -136,9 +241,8 @@ func (service *_Service[T]) Query(filterObj interface{}, condition string) (*[]T
 		ExpressionAttributeValues: filter,
 	}
 
-	result, err := service.db.Query(input)
+	result, err := service.db.QueryWithContext(ctx, input)
 	if err != nil {
-		log.Println("QueryError: ", err)
 		return nil, err
 	}
 
@@ -150,11 +254,13 @@ func (service *_Service[T]) Query(filterObj interface{}, condition string) (*[]T
 }
 
 // Query function to query data from database
-func (service *_Service[T]) QueryWithIndex(filterObj interface{}, condition string, filterExpr string, index string) (*[]T, error) {
+func (service *_Service[T]) QueryWithIndex(ctx context.Context, filterObj interface{}, condition string, filterExpr string, index string) (*[]T, error) {
+	ctx, cancel := timeout.WithTimeout(ctx, timeout.DatabaseQuery)
+	defer cancel()
+
 	// Create item object for DynamoDB
 	filter, err := dynamodbattribute.MarshalMap(filterObj)
 	if err != nil {
-		log.Println("QueryWithIndexError: ", err)
 		return nil, err
 	}
 
@@ -166,9 +272,8 @@ func (service *_Service[T]) QueryWithIndex(filterObj interface{}, condition stri
 		ExpressionAttributeValues: filter,
 	}
 
-	result, err := service.db.Query(input)
+	result, err := service.db.QueryWithContext(ctx, input)
 	if err != nil {
-		log.Println("QueryWithIndexError: ", err)
 		return nil, err
 	}
 
@@ -178,3 +283,154 @@ func (service *_Service[T]) QueryWithIndex(filterObj interface{}, condition stri
 
 	return &out, err
 }
+
+// QueryWithFilter function to query data from database and filter results,
+// names are expression attribute names used for reserved words or nested attributes.
+// DynamoDB filters each page of at most 1MB after reading it, so every page is read until the key condition is exhausted.
+func (service *_Service[T]) QueryWithFilter(ctx context.Context, filterObj interface{}, condition string, filterExpr string, names map[string]string) (*[]T, error) {
+	ctx, cancel := timeout.WithTimeout(ctx, timeout.DatabaseQuery)
+	defer cancel()
+
+	// Create item object for DynamoDB
+	filter, err := dynamodbattribute.MarshalMap(filterObj)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:                 &service.tableName,
+		KeyConditionExpression:    &condition,
+		ExpressionAttributeValues: filter,
+	}
+
+	if filterExpr != "" {
+		input.FilterExpression = &filterExpr
+	}
+
+	if len(names) > 0 {
+		input.ExpressionAttributeNames = aws.StringMap(names)
+	}
+
+	items := []map[string]*dynamodb.AttributeValue{}
+	err = service.db.QueryPagesWithContext(ctx, input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		items = append(items, page.Items...)
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var out []T
+
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &out)
+
+	return &out, err
+}
+
+// QueryPage function to query a page of data from database, use cursor of the returned page to get the next page
+func (service *_Service[T]) QueryPage(ctx context.Context, filterObj interface{}, condition string, options *QueryOptions) (*Page[T], error) {
+	ctx, cancel := timeout.WithTimeout(ctx, timeout.DatabaseQuery)
+	defer cancel()
+
+	// Create item object for DynamoDB
+	filter, err := dynamodbattribute.MarshalMap(filterObj)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:                 &service.tableName,
+		KeyConditionExpression:    &condition,
+		ExpressionAttributeValues: filter,
+		ScanIndexForward:          aws.Bool(!options.Descending),
+	}
+
+	if options.Index != "" {
+		input.IndexName = &options.Index
+	}
+
+	if options.FilterExpression != "" {
+		input.FilterExpression = &options.FilterExpression
+	}
+
+	if len(options.Names) > 0 {
+		input.ExpressionAttributeNames = aws.StringMap(options.Names)
+	}
+
+	if options.Limit > 0 {
+		input.Limit = &options.Limit
+	}
+
+	if options.Cursor != "" {
+		startKey, err := decodeCursor(options.Cursor)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		input.ExclusiveStartKey = startKey
+	}
+
+	result, err := service.db.QueryWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	page := Page[T]{Items: []T{}}
+	if err := dynamodbattribute.UnmarshalListOfMaps(result.Items, &page.Items); err != nil {
+		return nil, err
+	}
+
+	page.Cursor, err = encodeCursor(result.LastEvaluatedKey)
+	return &page, err
+}
+
+// BatchGet function to read multiple items from database, items which do not exist are skipped
+func (service *_Service[T]) BatchGet(ctx context.Context, keyObjs ...interface{}) (*[]T, error) {
+	ctx, cancel := timeout.WithTimeout(ctx, timeout.DatabaseRead)
+	defer cancel()
+
+	out := []T{}
+
+	keys := []map[string]*dynamodb.AttributeValue{}
+	for _, keyObj := range keyObjs {
+		key, err := dynamodbattribute.MarshalMap(keyObj)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	// BatchGetItem accepts at most 100 keys per request
+	for start := 0; start < len(keys); start += maxBatchGetItems {
+		end := start + maxBatchGetItems
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		request := map[string]*dynamodb.KeysAndAttributes{
+			service.tableName: {Keys: keys[start:end]},
+		}
+
+		// Throttled keys are returned as unprocessed and must be requested again
+		for attempt := 0; len(request) > 0; attempt++ {
+			if err := waitBeforeRetry(ctx, attempt); err != nil {
+				return nil, err
+			}
+
+			result, err := service.db.BatchGetItemWithContext(ctx, &dynamodb.BatchGetItemInput{RequestItems: request})
+			if err != nil {
+				return nil, err
+			}
+
+			var items []T
+			if err := dynamodbattribute.UnmarshalListOfMaps(result.Responses[service.tableName], &items); err != nil {
+				return nil, err
+			}
+			out = append(out, items...)
+
+			request = result.UnprocessedKeys
+		}
+	}
+
+	return &out, nil
+}
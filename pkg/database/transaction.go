@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"speakeasy/pkg/timeout"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// ErrConditionFailed is returned when a condition of a transaction is not met
+var ErrConditionFailed = errors.New("condition failed")
+
+// ConditionError object which is returned by Transaction when the condition of the item at Index is not met,
+// it matches ErrConditionFailed so callers which don't need the item can keep using errors.Is
+type ConditionError struct {
+	Index int
+}
+
+func (err *ConditionError) Error() string {
+	return fmt.Sprintf("condition failed: item %d", err.Index)
+}
+
+func (err *ConditionError) Is(target error) bool {
+	return target == ErrConditionFailed
+}
+
+// TransactionItem object which contains one operation of a transaction.
+// Exactly one of Put (item object), Delete (key object) or Update (key object) should be set,
+// Condition is an optional condition expression using Values.
+// Changes contains the attribute changes of Update, its own condition, names and values are used instead.
+type TransactionItem struct {
+	Put       interface{}
+	Delete    interface{}
+	Update    interface{}
+	Changes   *Update
+	Condition string
+	Values    interface{}
+}
+
+// Transaction function to write and delete items atomically, the items can be of any type.
+// Returns *ConditionError if any condition is not met, nothing is written in that case.
+func (service *_Service[T]) Transaction(ctx context.Context, items ...TransactionItem) error {
+	ctx, cancel := timeout.WithTimeout(ctx, timeout.DatabaseWrite)
+	defer cancel()
+
+	transactItems := []*dynamodb.TransactWriteItem{}
+
+	for _, item := range items {
+		var condition *string
+		if item.Condition != "" {
+			condition = &item.Condition
+		}
+
+		var values map[string]*dynamodb.AttributeValue
+		if item.Values != nil {
+			var err error
+			if values, err = dynamodbattribute.MarshalMap(item.Values); err != nil {
+				return err
+			}
+		}
+
+		if item.Put != nil {
+			obj, err := dynamodbattribute.MarshalMap(item.Put)
+			if err != nil {
+				return err
+			}
+
+			transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+				Put: &dynamodb.Put{
+					TableName:                 &service.tableName,
+					Item:                      obj,
+					ConditionExpression:       condition,
+					ExpressionAttributeValues: values,
+				},
+			})
+			continue
+		}
+
+		if item.Update != nil {
+			key, err := dynamodbattribute.MarshalMap(item.Update)
+			if err != nil {
+				return err
+			}
+
+			expression, names, updateValues, err := item.Changes.expression()
+			if err != nil {
+				return err
+			}
+
+			update := &dynamodb.Update{
+				TableName:                &service.tableName,
+				Key:                      key,
+				UpdateExpression:         &expression,
+				ExpressionAttributeNames: names,
+			}
+
+			if len(updateValues) > 0 {
+				update.ExpressionAttributeValues = updateValues
+			}
+
+			if item.Changes.Condition != "" {
+				update.ConditionExpression = &item.Changes.Condition
+			}
+
+			transactItems = append(transactItems, &dynamodb.TransactWriteItem{Update: update})
+			continue
+		}
+
+		key, err := dynamodbattribute.MarshalMap(item.Delete)
+		if err != nil {
+			return err
+		}
+
+		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+			Delete: &dynamodb.Delete{
+				TableName:                 &service.tableName,
+				Key:                       key,
+				ConditionExpression:       condition,
+				ExpressionAttributeValues: values,
+			},
+		})
+	}
+
+	input := &dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	}
+
+	_, err := service.db.TransactWriteItemsWithContext(ctx, input)
+
+	var canceled *dynamodb.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		// Reasons are in the order of the items
+		for i, reason := range canceled.CancellationReasons {
+			if reason.Code != nil && *reason.Code == "ConditionalCheckFailed" {
+				return &ConditionError{Index: i}
+			}
+		}
+	}
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return ErrConditionFailed
+	}
+
+	return err
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"speakeasy/pkg/timeout"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Update object which contains the attribute changes of an item.
+// Set contains the new value of each attribute and Remove the attributes to delete, keyed by attribute name.
+// Add contains the amount added to each number attribute, missing attributes start at zero.
+// Condition is an optional condition expression using Names and Values, placeholders
+// starting with #u or :u are reserved for the generated update expression.
+type Update struct {
+	Set       map[string]interface{}
+	Remove    []string
+	Add       map[string]interface{}
+	Condition string
+	Names     map[string]string
+	Values    map[string]interface{}
+}
+
+// Update function to change only the given attributes of an item with a single UpdateItem request,
+// returns the updated item. Returns ErrConditionFailed if the condition is not met, nothing is changed in that case.
+func (service *_Service[T]) Update(ctx context.Context, keyObj interface{}, update *Update) (*T, error) {
+	ctx, cancel := timeout.WithTimeout(ctx, timeout.DatabaseWrite)
+	defer cancel()
+
+	key, err := dynamodbattribute.MarshalMap(keyObj)
+	if err != nil {
+		return nil, err
+	}
+
+	expression, names, values, err := update.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName:                &service.tableName,
+		Key:                      key,
+		UpdateExpression:         &expression,
+		ExpressionAttributeNames: names,
+		ReturnValues:             aws.String(dynamodb.ReturnValueAllNew),
+	}
+
+	if len(values) > 0 {
+		input.ExpressionAttributeValues = values
+	}
+
+	if update.Condition != "" {
+		input.ConditionExpression = &update.Condition
+	}
+
+	result, err := service.db.UpdateItemWithContext(ctx, input)
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return nil, ErrConditionFailed
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var out T
+	err = dynamodbattribute.UnmarshalMap(result.Attributes, &out)
+
+	return &out, err
+}
+
+// expression generates the update expression with placeholders for every attribute name and value,
+// attributes are sorted so the same update always generates the same expression
+func (update *Update) expression() (string, map[string]*string, map[string]*dynamodb.AttributeValue, error) {
+	names := map[string]*string{}
+	values := map[string]*dynamodb.AttributeValue{}
+
+	for placeholder, name := range update.Names {
+		names[placeholder] = aws.String(name)
+	}
+
+	for placeholder, value := range update.Values {
+		av, err := dynamodbattribute.Marshal(value)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		values[placeholder] = av
+	}
+
+	setNames := make([]string, 0, len(update.Set))
+	for name := range update.Set {
+		setNames = append(setNames, name)
+	}
+	sort.Strings(setNames)
+
+	removeNames := append([]string{}, update.Remove...)
+	sort.Strings(removeNames)
+
+	addNames := make([]string, 0, len(update.Add))
+	for name := range update.Add {
+		addNames = append(addNames, name)
+	}
+	sort.Strings(addNames)
+
+	if len(setNames) == 0 && len(removeNames) == 0 && len(addNames) == 0 {
+		return "", nil, nil, errors.New("update has no changes")
+	}
+
+	sets := []string{}
+	for i, name := range setNames {
+		av, err := dynamodbattribute.Marshal(update.Set[name])
+		if err != nil {
+			return "", nil, nil, err
+		}
+
+		names[fmt.Sprintf("#u%d", i)] = aws.String(name)
+		values[fmt.Sprintf(":u%d", i)] = av
+		sets = append(sets, fmt.Sprintf("#u%d = :u%d", i, i))
+	}
+
+	removes := []string{}
+	for i, name := range removeNames {
+		placeholder := fmt.Sprintf("#u%d", len(setNames)+i)
+		names[placeholder] = aws.String(name)
+		removes = append(removes, placeholder)
+	}
+
+	adds := []string{}
+	for i, name := range addNames {
+		av, err := dynamodbattribute.Marshal(update.Add[name])
+		if err != nil {
+			return "", nil, nil, err
+		}
+
+		n := len(setNames) + len(removeNames) + i
+		names[fmt.Sprintf("#u%d", n)] = aws.String(name)
+		values[fmt.Sprintf(":u%d", n)] = av
+		adds = append(adds, fmt.Sprintf("#u%d :u%d", n, n))
+	}
+
+	clauses := []string{}
+	if len(sets) > 0 {
+		clauses = append(clauses, "SET "+strings.Join(sets, ", "))
+	}
+
+	if len(removes) > 0 {
+		clauses = append(clauses, "REMOVE "+strings.Join(removes, ", "))
+	}
+
+	if len(adds) > 0 {
+		clauses = append(clauses, "ADD "+strings.Join(adds, ", "))
+	}
+
+	return strings.Join(clauses, " "), names, values, nil
+}
@@ -1,6 +1,106 @@
 package pkg
 
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Stable machine readable error types, clients should check them instead of the reason
+const (
+	TypeBadRequest           = "bad_request"
+	TypeValidationFailed     = "validation_failed"
+	TypeUnauthorized         = "unauthorized"
+	TypeTokenExpired         = "token_expired"
+	TypeForbidden            = "forbidden"
+	TypeNotFound             = "not_found"
+	TypeConflict             = "conflict"
+	TypeGone                 = "gone"
+	TypePayloadTooLarge      = "payload_too_large"
+	TypeUnsupportedMediaType = "unsupported_media_type"
+	TypeTooManyRequests      = "too_many_requests"
+	TypeInternal             = "internal_error"
+	TypeUnavailable          = "service_unavailable"
+)
+
+// Error object which is returned by services and rendered by the error middleware.
+// Code is the HTTP status and Type the stable error type, the type of the status is used when it is empty.
+// Details contains the reason of each invalid request field keyed by field name.
+// Err is the underlying cause, it is only logged and never sent to the client.
 type Error struct {
 	Code   int
+	Type   string
 	Reason string
+
+	Details map[string]string
+	Err     error
+}
+
+// Error returns the reason and the cause of the error
+func (err *Error) Error() string {
+	if err.Err != nil {
+		return fmt.Sprintf("%s: %s", err.Reason, err.Err)
+	}
+
+	return err.Reason
+}
+
+// Unwrap returns the underlying cause
+func (err *Error) Unwrap() error {
+	return err.Err
+}
+
+// ErrorType returns the stable error type
+func (err *Error) ErrorType() string {
+	if err.Type != "" {
+		return err.Type
+	}
+
+	switch err.Code {
+	case http.StatusBadRequest:
+		return TypeBadRequest
+	case http.StatusUnauthorized:
+		return TypeUnauthorized
+	case http.StatusForbidden:
+		return TypeForbidden
+	case http.StatusNotFound:
+		return TypeNotFound
+	case http.StatusConflict:
+		return TypeConflict
+	case http.StatusGone:
+		return TypeGone
+	case http.StatusRequestEntityTooLarge:
+		return TypePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return TypeUnsupportedMediaType
+	case http.StatusTooManyRequests:
+		return TypeTooManyRequests
+	case http.StatusServiceUnavailable:
+		return TypeUnavailable
+	case http.StatusInternalServerError:
+		return TypeInternal
+	}
+
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(err.Code)), " ", "_")
+}
+
+// Unavailable returns 503 error when a dependency like the database or the file storage fails,
+// op names the failed operation in the logs
+func Unavailable(op string, cause error) *Error {
+	return &Error{
+		Code:   http.StatusServiceUnavailable,
+		Type:   TypeUnavailable,
+		Reason: "Service is temporarily unavailable, please try again",
+		Err:    fmt.Errorf("%s: %w", op, cause),
+	}
+}
+
+// Internal returns 500 error for unexpected failures, op names the failed operation in the logs
+func Internal(op string, cause error) *Error {
+	return &Error{
+		Code:   http.StatusInternalServerError,
+		Type:   TypeInternal,
+		Reason: "Internal Server Error",
+		Err:    fmt.Errorf("%s: %w", op, cause),
+	}
 }
@@ -0,0 +1,300 @@
+package filestorage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"speakeasy/pkg/logging"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LOCAL_FILES_PATH is the route prefix of the local file storage handler
+const LOCAL_FILES_PATH = "/files/"
+
+// metadataSuffix is added to the file path to get the path of the file which stores the content type
+const metadataSuffix = ".content-type"
+
+// ErrInvalidFilename is returned when filename would be stored outside of the storage directory
+var ErrInvalidFilename = errors.New("invalid filename")
+
+var (
+	localSecret     []byte
+	localSecretOnce sync.Once
+)
+
+type _LocalService struct {
+	dir        string
+	bucketName string
+	baseUrl    string
+	secret     []byte
+}
+
+// NewLocalFileStorageService function to initialize filestorage.Service which stores files in a directory,
+// files are served by LocalFileHandler to emulate presigned urls.
+func NewLocalFileStorageService(config Config, bucketName string) Service {
+	return &_LocalService{
+		dir:        config.LocalPath,
+		bucketName: bucketName,
+		baseUrl:    strings.TrimSuffix(config.LocalURL, "/"),
+		secret:     localSigningSecret(config),
+	}
+}
+
+// GetUploadUrl to get signed url to upload file with PUT request, the request must include the returned headers
+// and have the size as Content-Length
+func (svc *_LocalService) GetUploadUrl(filename string, contentType string, size int64) (string, http.Header, error) {
+	uploadUrl, err := svc.signedUrl(http.MethodPut, filename, contentType, size, UploadUrlExpiry)
+	if err != nil {
+		return "", nil, err
+	}
+
+	headers := http.Header{}
+	headers.Set("Content-Type", contentType)
+	headers.Set("Content-Length", strconv.FormatInt(size, 10))
+
+	return uploadUrl, headers, nil
+}
+
+// UploadFile stores file with the content type
+func (svc *_LocalService) UploadFile(ctx context.Context, filename string, body io.ReadSeeker, contentType string) error {
+	return writeLocalFile(svc.dir, svc.bucketName, filename, body, contentType)
+}
+
+// HeadFile gets file metadata, returns ErrNotFound if the file does not exist
+func (svc *_LocalService) HeadFile(ctx context.Context, filename string) (*FileInfo, error) {
+	return statLocalFile(svc.dir, svc.bucketName, filename)
+}
+
+// GetFile opens file, the caller must close the returned reader
+func (svc *_LocalService) GetFile(ctx context.Context, filename string) (io.ReadCloser, error) {
+	filePath, err := svc.path(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	return file, err
+}
+
+// DeleteFile deletes file and its metadata, deleting missing file is not an error
+func (svc *_LocalService) DeleteFile(ctx context.Context, filename string) error {
+	filePath, err := svc.path(filename)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range []string{filePath, metadataPath(filePath)} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetDownloadUrl to get signed url to download file with GET request
+func (svc *_LocalService) GetDownloadUrl(filename string) (string, error) {
+	return svc.signedUrl(http.MethodGet, filename, "", 0, DownloadUrlExpiry)
+}
+
+// Ping checks the bucket directory exists or can be created
+func (svc *_LocalService) Ping(ctx context.Context) error {
+	return os.MkdirAll(filepath.Join(svc.dir, svc.bucketName), 0o755)
+}
+
+func (svc *_LocalService) path(filename string) (string, error) {
+	return localFilePath(svc.dir, svc.bucketName, filename)
+}
+
+// signedUrl returns url of the file served by LocalFileHandler which allows the method until it expires,
+// size is the Content-Length of the request
+func (svc *_LocalService) signedUrl(method string, filename string, contentType string, size int64, expiry time.Duration) (string, error) {
+	if _, err := svc.path(filename); err != nil {
+		return "", err
+	}
+
+	expires := time.Now().Add(expiry).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", sign(svc.secret, method, svc.bucketName, filename, contentType, size, expires))
+
+	return fmt.Sprintf("%s%s%s/%s?%s", svc.baseUrl, LOCAL_FILES_PATH, svc.bucketName, filename, query.Encode()), nil
+}
+
+// LocalFileHandler returns http.Handler which serves files of the local file storage backend under LOCAL_FILES_PATH.
+// PUT requests need a signed url from GetUploadUrl and GET requests a signed url from GetDownloadUrl.
+func LocalFileHandler(config Config) http.Handler {
+	dir := config.LocalPath
+	secret := localSigningSecret(config)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bucketName, filename, found := strings.Cut(strings.TrimPrefix(r.URL.Path, LOCAL_FILES_PATH), "/")
+		if !found || bucketName == "" || filename == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPut:
+			if !verify(secret, r, bucketName, filename, r.Header.Get("Content-Type"), r.ContentLength) {
+				http.Error(w, "Signature is invalid or expired", http.StatusForbidden)
+				return
+			}
+
+			// The signed size is the Content-Length, the body can't be longer than that
+			body := http.MaxBytesReader(w, r.Body, r.ContentLength)
+			if err := writeLocalFile(dir, bucketName, filename, body, r.Header.Get("Content-Type")); err != nil {
+				logging.FromContext(r.Context()).Error("store local file failed", "bucket", bucketName, "file", filename, "error", err.Error())
+				http.Error(w, "File can't be stored", http.StatusBadRequest)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			if !verify(secret, r, bucketName, filename, "", 0) {
+				http.Error(w, "Signature is invalid or expired", http.StatusForbidden)
+				return
+			}
+
+			info, err := statLocalFile(dir, bucketName, filename)
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+
+			filePath, _ := localFilePath(dir, bucketName, filename)
+			w.Header().Set("Content-Type", info.ContentType)
+			http.ServeFile(w, r, filePath)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// localFilePath returns the path of the file, ErrInvalidFilename if it would be outside of the bucket directory
+func localFilePath(dir string, bucketName string, filename string) (string, error) {
+	clean := path.Clean("/" + filename)
+	if clean == "/" || clean != "/"+filename || strings.HasSuffix(clean, metadataSuffix) ||
+		strings.Contains(bucketName, "/") || strings.HasPrefix(bucketName, ".") {
+		return "", ErrInvalidFilename
+	}
+
+	return filepath.Join(dir, bucketName, filepath.FromSlash(clean)), nil
+}
+
+// metadataPath returns the path of the file which stores the content type of the file
+func metadataPath(filePath string) string {
+	return filePath + metadataSuffix
+}
+
+func writeLocalFile(dir string, bucketName string, filename string, body io.Reader, contentType string) error {
+	filePath, err := localFilePath(dir, bucketName, filename)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename it when it's complete, so a failed write doesn't leave a partial file
+	file, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := file.Chmod(0o644); err != nil {
+		file.Close()
+		return err
+	}
+
+	if _, err := io.Copy(file, body); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(metadataPath(filePath), []byte(contentType), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), filePath)
+}
+
+func statLocalFile(dir string, bucketName string, filename string) (*FileInfo, error) {
+	filePath, err := localFilePath(dir, bucketName, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	contentType, err := os.ReadFile(metadataPath(filePath))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return &FileInfo{Size: stat.Size(), ContentType: string(contentType)}, nil
+}
+
+// sign returns hex HMAC-SHA256 signature of the request which is allowed by the url
+func sign(secret []byte, method string, bucketName string, filename string, contentType string, size int64, expires int64) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s\n%s/%s\n%s\n%d\n%d", method, bucketName, filename, contentType, size, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify returns true if the request url has a valid signature which has not expired
+func verify(secret []byte, r *http.Request, bucketName string, filename string, contentType string, size int64) bool {
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+
+	expected := sign(secret, r.Method, bucketName, filename, contentType, size, expires)
+	return hmac.Equal([]byte(expected), []byte(r.URL.Query().Get("signature")))
+}
+
+// localSigningSecret returns the configured secret, or a random secret if it's not set so urls are valid until restart
+func localSigningSecret(config Config) []byte {
+	if config.LocalSecret != "" {
+		return []byte(config.LocalSecret)
+	}
+
+	localSecretOnce.Do(func() {
+		localSecret = make([]byte, 32)
+		if _, err := rand.Read(localSecret); err != nil {
+			panic(err)
+		}
+	})
+
+	return localSecret
+}
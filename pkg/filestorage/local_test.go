@@ -0,0 +1,208 @@
+package filestorage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newLocalService returns local storage in a temporary directory and the handler which serves its urls
+func newLocalService(t *testing.T) (*_LocalService, http.Handler) {
+	config := Config{Backend: BackendLocal, LocalPath: t.TempDir(), LocalURL: "http://localhost", LocalSecret: "secret"}
+	return NewLocalFileStorageService(config, "bucket").(*_LocalService), LocalFileHandler(config)
+}
+
+// serve sends the request with the body to the url path and query, body length is the Content-Length
+func serve(handler http.Handler, method string, rawUrl string, body string, contentType string) *httptest.ResponseRecorder {
+	parsed, _ := url.Parse(rawUrl)
+
+	r := httptest.NewRequest(method, parsed.RequestURI(), strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+// withQuery returns the url with the query parameter replaced
+func withQuery(rawUrl string, name string, value string) string {
+	parsed, _ := url.Parse(rawUrl)
+	query := parsed.Query()
+	query.Set(name, value)
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// Reader which returns an error after the data, like a client which disconnects during the upload
+type _FailingReader struct {
+	data io.Reader
+}
+
+func (reader *_FailingReader) Read(p []byte) (int, error) {
+	n, err := reader.data.Read(p)
+	if err == io.EOF {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+func TestLocalFileHandler(t *testing.T) {
+	t.Run("SUCCESS: UPLOAD AND DOWNLOAD FILE WITH SIGNED URLS", func(t *testing.T) {
+		svc, handler := newLocalService(t)
+
+		uploadUrl, headers, err := svc.GetUploadUrl("uploads/user/file", "image/png", 5)
+		assert.Empty(t, err)
+		assert.Equal(t, "5", headers.Get("Content-Length"))
+
+		w := serve(handler, http.MethodPut, uploadUrl, "image", "image/png")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		info, err := svc.HeadFile(context.Background(), "uploads/user/file")
+		assert.Empty(t, err)
+		assert.Equal(t, &FileInfo{Size: 5, ContentType: "image/png"}, info)
+
+		downloadUrl, _ := svc.GetDownloadUrl("uploads/user/file")
+		w = serve(handler, http.MethodGet, downloadUrl, "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image", w.Body.String())
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	})
+
+	svc, handler := newLocalService(t)
+	uploadUrl, _, _ := svc.GetUploadUrl("uploads/user/file", "image/png", 5)
+	downloadUrl, _ := svc.GetDownloadUrl("uploads/user/file")
+
+	expires := time.Now().Add(-time.Minute).Unix()
+	expiredUrl := withQuery(withQuery(uploadUrl, "expires", strconv.FormatInt(expires, 10)),
+		"signature", sign(svc.secret, http.MethodPut, "bucket", "uploads/user/file", "image/png", 5, expires))
+
+	for _, test := range []struct {
+		name        string
+		method      string
+		url         string
+		body        string
+		contentType string
+		code        int
+	}{
+		{"TAMPERED SIGNATURE", http.MethodPut, withQuery(uploadUrl, "signature", strings.Repeat("0", 64)), "image", "image/png", http.StatusForbidden},
+		{"MISSING SIGNATURE", http.MethodPut, withQuery(uploadUrl, "signature", ""), "image", "image/png", http.StatusForbidden},
+		{"EXTENDED EXPIRY", http.MethodPut, withQuery(uploadUrl, "expires", strconv.FormatInt(time.Now().Add(time.Hour*24).Unix(), 10)), "image", "image/png", http.StatusForbidden},
+		{"EXPIRED URL", http.MethodPut, expiredUrl, "image", "image/png", http.StatusForbidden},
+		{"OTHER FILE", http.MethodPut, strings.Replace(uploadUrl, "uploads/user/file", "uploads/user/other", 1), "image", "image/png", http.StatusForbidden},
+		{"LARGER SIZE", http.MethodPut, uploadUrl, "image.image", "image/png", http.StatusForbidden},
+		{"SMALLER SIZE", http.MethodPut, uploadUrl, "img", "image/png", http.StatusForbidden},
+		{"OTHER CONTENT TYPE", http.MethodPut, uploadUrl, "image", "text/html", http.StatusForbidden},
+		{"DOWNLOAD URL", http.MethodPut, downloadUrl, "image", "image/png", http.StatusForbidden},
+		{"UPLOAD URL", http.MethodGet, uploadUrl, "", "", http.StatusForbidden},
+		{"METHOD", http.MethodDelete, uploadUrl, "", "", http.StatusMethodNotAllowed},
+	} {
+		t.Run("ERROR: REJECT REQUEST WITH "+test.name, func(t *testing.T) {
+			w := serve(handler, test.method, test.url, test.body, test.contentType)
+
+			assert.Equal(t, test.code, w.Code)
+			_, err := svc.HeadFile(context.Background(), "uploads/user/file")
+			assert.ErrorIs(t, err, ErrNotFound, "File should not be stored")
+		})
+	}
+
+	t.Run("ERROR: RETURN 404 WHEN FILE DOES NOT EXIST", func(t *testing.T) {
+		w := serve(handler, http.MethodGet, downloadUrl, "", "")
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestLocalFilePath(t *testing.T) {
+	t.Run("SUCCESS: RETURN PATH IN BUCKET DIRECTORY", func(t *testing.T) {
+		filePath, err := localFilePath("dir", "bucket", "trips/trip/photo.jpg")
+
+		assert.Empty(t, err)
+		assert.Equal(t, filepath.Join("dir", "bucket", "trips", "trip", "photo.jpg"), filePath)
+	})
+
+	for _, test := range []struct {
+		bucketName string
+		filename   string
+	}{
+		{"bucket", "../file"},
+		{"bucket", "../../etc/passwd"},
+		{"bucket", "trips/../../file"},
+		{"bucket", "trips/./file"},
+		{"bucket", "trips//file"},
+		{"bucket", "/file"},
+		{"bucket", "trips/"},
+		{"bucket", ""},
+		{"bucket", "file" + metadataSuffix},
+		{"..", "file"},
+		{".hidden", "file"},
+		{"bucket/other", "file"},
+	} {
+		t.Run("ERROR: RETURN ErrInvalidFilename FOR "+test.bucketName+" "+test.filename, func(t *testing.T) {
+			_, err := localFilePath("dir", test.bucketName, test.filename)
+
+			assert.ErrorIs(t, err, ErrInvalidFilename)
+		})
+	}
+
+	t.Run("ERROR: DON'T SIGN URL OUTSIDE OF BUCKET", func(t *testing.T) {
+		svc, _ := newLocalService(t)
+
+		_, _, err := svc.GetUploadUrl("../file", "image/png", 5)
+
+		assert.ErrorIs(t, err, ErrInvalidFilename)
+	})
+}
+
+func TestWriteLocalFile(t *testing.T) {
+	t.Run("SUCCESS: REPLACE FILE AND CONTENT TYPE", func(t *testing.T) {
+		svc, _ := newLocalService(t)
+
+		assert.Empty(t, writeLocalFile(svc.dir, "bucket", "file", strings.NewReader("old"), "text/plain"))
+		assert.Empty(t, writeLocalFile(svc.dir, "bucket", "file", strings.NewReader("new file"), "image/png"))
+
+		info, _ := svc.HeadFile(context.Background(), "file")
+		assert.Equal(t, &FileInfo{Size: 8, ContentType: "image/png"}, info)
+	})
+
+	t.Run("ERROR: KEEP PREVIOUS FILE WHEN WRITE FAILS", func(t *testing.T) {
+		svc, _ := newLocalService(t)
+		assert.Empty(t, writeLocalFile(svc.dir, "bucket", "file", strings.NewReader("old"), "text/plain"))
+
+		err := writeLocalFile(svc.dir, "bucket", "file", &_FailingReader{strings.NewReader("partial")}, "image/png")
+
+		assert.Error(t, err)
+		data, _ := os.ReadFile(filepath.Join(svc.dir, "bucket", "file"))
+		assert.Equal(t, "old", string(data))
+		info, _ := svc.HeadFile(context.Background(), "file")
+		assert.Equal(t, "text/plain", info.ContentType)
+
+		entries, _ := os.ReadDir(filepath.Join(svc.dir, "bucket"))
+		assert.Len(t, entries, 2, "Only the file and its metadata should remain")
+	})
+
+	t.Run("ERROR: DON'T STORE PARTIAL FILE", func(t *testing.T) {
+		svc, _ := newLocalService(t)
+
+		err := writeLocalFile(svc.dir, "bucket", "file", &_FailingReader{strings.NewReader("partial")}, "image/png")
+
+		assert.Error(t, err)
+		_, err = svc.HeadFile(context.Background(), "file")
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		entries, _ := os.ReadDir(filepath.Join(svc.dir, "bucket"))
+		assert.Empty(t, entries, "Temporary file should be removed")
+	})
+}
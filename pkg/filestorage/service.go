@@ -1,28 +1,80 @@
 package filestorage
 
 import (
-	"mime/multipart"
-	"os"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"speakeasy/pkg/timeout"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 )
 
+// ErrNotFound is returned when the file does not exist
+var ErrNotFound = errors.New("file not found")
+
+// UploadUrlExpiry is how long upload urls are valid
+const UploadUrlExpiry = 15 * time.Minute
+
+// DownloadUrlExpiry is how long download urls are valid, files are private so urls must not be stored
+const DownloadUrlExpiry = time.Hour
+
+// FileInfo object which contains file metadata
+type FileInfo struct {
+	Size        int64
+	ContentType string
+}
+
+// File storage backends
+const (
+	BackendS3    = "s3"
+	BackendLocal = "local"
+)
+
+// Config object which contains the settings of the file storage clients.
+// Endpoint can point to an S3 compatible store, the local settings are only used by the local backend.
+type Config struct {
+	Backend     string
+	Region      string
+	Endpoint    string
+	LocalPath   string
+	LocalURL    string
+	LocalSecret string
+}
+
+// UseLocalStorage returns true if the local file storage backend is configured
+func (config Config) UseLocalStorage() bool {
+	return config.Backend == BackendLocal
+}
+
 type _Service struct {
 	client     *s3.S3
 	bucketName string
 }
 
-// Service interface which contains file storage operations
+// Service interface which contains file storage operations, calls to storage are cancelled when ctx is done
+// or after the timeout of the operation kind configured in the timeout package, urls are signed locally
 type Service interface {
-	GetUploadUrl(filename string) (string, error)
-	UploadFile(filename string, file *multipart.File) error
+	GetUploadUrl(filename string, contentType string, size int64) (string, http.Header, error)
+	UploadFile(ctx context.Context, filename string, body io.ReadSeeker, contentType string) error
+	HeadFile(ctx context.Context, filename string) (*FileInfo, error)
+	GetFile(ctx context.Context, filename string) (io.ReadCloser, error)
+	DeleteFile(ctx context.Context, filename string) error
+	GetDownloadUrl(filename string) (string, error)
+	Ping(ctx context.Context) error
 }
 
-// NewDatabaseService function to initialize filestorage.Service object
-func NewFileStorageService(bucketName string) Service {
+// NewFileStorageService function to initialize filestorage.Service object,
+// the local backend stores files in a directory instead of S3
+func NewFileStorageService(config Config, bucketName string) Service {
+	if config.UseLocalStorage() {
+		return NewLocalFileStorageService(config, bucketName)
+	}
+
 	// https://docs.aws.amazon.com/sdk-for-go/v1/developer-guide/configuring-sdk.html#specifying-credentials
 	// Initialize session and config for initializing client
 	sess := session.Must(session.NewSessionWithOptions(session.Options{
@@ -30,35 +82,150 @@ func NewFileStorageService(bucketName string) Service {
 	}))
 
 	cfg := &aws.Config{
-		Region: aws.String(os.Getenv("AWS_REGION")),
+		Region: aws.String(config.Region),
+	}
+
+	// S3 compatible stores, e.g. localstack or minio, need path style urls
+	if config.Endpoint != "" {
+		cfg.Endpoint = aws.String(config.Endpoint)
+		cfg.S3ForcePathStyle = aws.Bool(true)
 	}
 
 	client := s3.New(sess, cfg)
 	return &_Service{client: client, bucketName: bucketName}
 }
 
-// GetUploadUrl to get url to upload directly to storage instead of going through the server
-func (svc *_Service) GetUploadUrl(filename string) (string, error) {
-	response, _ := svc.client.PutObjectRequest(&s3.PutObjectInput{
+// GetUploadUrl to get url to upload directly to storage instead of going through the server,
+// the upload request must include the returned headers. The size is signed so storage rejects a file of another size.
+func (svc *_Service) GetUploadUrl(filename string, contentType string, size int64) (string, http.Header, error) {
+	request, _ := svc.client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket:        &svc.bucketName,
+		Key:           &filename,
+		ContentType:   &contentType,
+		ContentLength: &size,
+	})
+
+	if request.Error != nil {
+		return "", nil, request.Error
+	}
+
+	// Get presign url which allows upload with necessary permission
+	return request.PresignRequest(UploadUrlExpiry)
+}
+
+// UploadFile uploads file to storage with the content type
+func (svc *_Service) UploadFile(ctx context.Context, filename string, body io.ReadSeeker, contentType string) error {
+	ctx, cancel := timeout.WithTimeout(ctx, timeout.StorageWrite)
+	defer cancel()
+
+	_, err := svc.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      &svc.bucketName,
+		Key:         &filename,
+		Body:        body,
+		ContentType: &contentType,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// HeadFile gets file metadata without downloading the file, returns ErrNotFound if the file does not exist
+func (svc *_Service) HeadFile(ctx context.Context, filename string) (*FileInfo, error) {
+	ctx, cancel := timeout.WithTimeout(ctx, timeout.StorageRead)
+	defer cancel()
+
+	output, err := svc.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
 		Bucket: &svc.bucketName,
 		Key:    &filename,
 	})
 
-	// Get presign url which allows upload with necessary permission
-	return response.Presign(5 * time.Minute)
+	if err != nil {
+		return nil, notFoundError(err)
+	}
+
+	return &FileInfo{
+		Size:        aws.Int64Value(output.ContentLength),
+		ContentType: aws.StringValue(output.ContentType),
+	}, nil
 }
 
-// UploadFile uploads file to storage
-func (svc *_Service) UploadFile(filename string, file *multipart.File) error {
-	_, err := svc.client.PutObject(&s3.PutObjectInput{
+// GetFile downloads file from storage, the caller must close the returned reader.
+// The timeout includes reading the file, it is stopped when the reader is closed.
+func (svc *_Service) GetFile(ctx context.Context, filename string) (io.ReadCloser, error) {
+	ctx, cancel := timeout.WithTimeout(ctx, timeout.StorageRead)
+
+	output, err := svc.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
 		Bucket: &svc.bucketName,
 		Key:    &filename,
-		Body:   *file,
 	})
 
 	if err != nil {
-		return err
+		cancel()
+		return nil, notFoundError(err)
 	}
 
-	return nil
+	return &cancelOnClose{ReadCloser: output.Body, cancel: cancel}, nil
+}
+
+// DeleteFile deletes file from storage
+func (svc *_Service) DeleteFile(ctx context.Context, filename string) error {
+	ctx, cancel := timeout.WithTimeout(ctx, timeout.StorageWrite)
+	defer cancel()
+
+	_, err := svc.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: &svc.bucketName,
+		Key:    &filename,
+	})
+
+	return err
+}
+
+// GetDownloadUrl to get short-lived url to download private file directly from storage
+func (svc *_Service) GetDownloadUrl(filename string) (string, error) {
+	request, _ := svc.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: &svc.bucketName,
+		Key:    &filename,
+	})
+
+	if request.Error != nil {
+		return "", request.Error
+	}
+
+	return request.Presign(DownloadUrlExpiry)
+}
+
+// Ping checks the bucket exists and can be accessed, it's used by readiness checks
+func (svc *_Service) Ping(ctx context.Context) error {
+	ctx, cancel := timeout.WithTimeout(ctx, timeout.StorageRead)
+	defer cancel()
+
+	_, err := svc.client.HeadBucketWithContext(ctx, &s3.HeadBucketInput{
+		Bucket: &svc.bucketName,
+	})
+
+	return err
+}
+
+// cancelOnClose object which cancels the context of the download when the file is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (body *cancelOnClose) Close() error {
+	defer body.cancel()
+	return body.ReadCloser.Close()
+}
+
+// notFoundError returns ErrNotFound if S3 returned not found error
+func notFoundError(err error) error {
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && (awsErr.Code() == s3.ErrCodeNoSuchKey || awsErr.Code() == "NotFound") {
+		return ErrNotFound
+	}
+
+	return err
 }
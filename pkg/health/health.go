@@ -0,0 +1,144 @@
+package health
+
+import (
+	"context"
+	"log/slog"
+	"speakeasy/pkg/clock"
+	"sync"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Checker interface which checks one dependency of the api, e.g. a DynamoDB table or the S3 bucket
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts function to Checker, e.g. the Ping method of a service
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Component object which contains the result of one checker
+type Component struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// Report object which contains the result of every checker, status is down if any component is down
+type Report struct {
+	Status     string               `json:"status"`
+	CheckedAt  time.Time            `json:"checked_at"`
+	Components map[string]Component `json:"components"`
+}
+
+// Up returns true if every component is up
+func (report *Report) Up() bool {
+	return report.Status == StatusUp
+}
+
+type namedChecker struct {
+	name    string
+	checker Checker
+}
+
+// Registry object which runs the registered checkers concurrently, each with a timeout.
+// The report is cached for the cache TTL so frequent probes don't call the dependencies on every request.
+type Registry struct {
+	timeout  time.Duration
+	cacheTTL time.Duration
+	clock    clock.Clock
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	checkers []namedChecker
+	cached   *Report
+}
+
+// NewRegistry returns Registry object without checkers
+func NewRegistry(timeout time.Duration, cacheTTL time.Duration, clock clock.Clock, logger *slog.Logger) *Registry {
+	return &Registry{timeout: timeout, cacheTTL: cacheTTL, clock: clock, logger: logger}
+}
+
+// Register adds checker with the name which is used as component name in the report
+func (registry *Registry) Register(name string, checker Checker) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	registry.checkers = append(registry.checkers, namedChecker{name, checker})
+	registry.cached = nil
+}
+
+// Check returns the cached report if it's not older than the cache TTL, otherwise runs the checkers.
+// Concurrent calls wait for the running checks instead of starting their own, and the checks are not
+// cancelled with ctx so a client which disconnects doesn't cache failed checks for the other callers.
+func (registry *Registry) Check(ctx context.Context) Report {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	ctx = context.WithoutCancel(ctx)
+
+	now := registry.clock.Now()
+	if registry.cached != nil && now.Sub(registry.cached.CheckedAt) < registry.cacheTTL {
+		return *registry.cached
+	}
+
+	report := Report{Status: StatusUp, CheckedAt: now, Components: map[string]Component{}}
+
+	var (
+		wg      sync.WaitGroup
+		results = make([]Component, len(registry.checkers))
+	)
+	for i, checker := range registry.checkers {
+		wg.Add(1)
+		go func(i int, checker namedChecker) {
+			defer wg.Done()
+			results[i] = registry.run(ctx, checker)
+		}(i, checker)
+	}
+	wg.Wait()
+
+	for i, checker := range registry.checkers {
+		report.Components[checker.name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+
+	registry.cached = &report
+	return report
+}
+
+// run runs the checker with the timeout, the error is only logged so it's not exposed by the report.
+// The component is down when the timeout passes even if the checker ignores ctx.
+func (registry *Registry) run(ctx context.Context, checker namedChecker) Component {
+	ctx, cancel := context.WithTimeout(ctx, registry.timeout)
+	defer cancel()
+
+	start := time.Now()
+	result := make(chan error, 1)
+	go func() {
+		result <- checker.checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	component := Component{Status: StatusUp, LatencyMs: time.Since(start).Milliseconds()}
+
+	if err != nil {
+		component.Status = StatusDown
+		registry.logger.Warn("health check failed", "component", checker.name, "error", err.Error())
+	}
+
+	return component
+}
@@ -0,0 +1,45 @@
+package ids
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Generator interface which returns new unique ids, services use it instead of uuid.New so tests can predict ids
+type Generator interface {
+	NewID() string
+}
+
+type uuidGenerator struct{}
+
+// UUID returns Generator which returns random UUIDs
+func UUID() Generator {
+	return uuidGenerator{}
+}
+
+func (uuidGenerator) NewID() string {
+	return uuid.New().String()
+}
+
+type sequenceGenerator struct {
+	mu   sync.Mutex
+	next int
+}
+
+// Sequence returns Generator which returns UUID formatted ids counting up from 1,
+// e.g. 00000000-0000-0000-0000-000000000001, for deterministic tests
+func Sequence() Generator {
+	return &sequenceGenerator{next: 1}
+}
+
+func (generator *sequenceGenerator) NewID() string {
+	generator.mu.Lock()
+	defer generator.mu.Unlock()
+
+	id := fmt.Sprintf("00000000-0000-0000-0000-%012d", generator.next)
+	generator.next++
+
+	return id
+}
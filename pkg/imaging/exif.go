@@ -0,0 +1,122 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+)
+
+const (
+	tagOrientation      = 0x0112
+	tagExifIFD          = 0x8769
+	tagDateTimeOriginal = 0x9003
+
+	exifDateLayout = "2006:01:02 15:04:05"
+)
+
+// Exif object which contains the EXIF fields used by the application,
+// fields are zero values when they are missing or can't be read.
+type Exif struct {
+	Orientation int
+	TakenAt     *time.Time
+}
+
+// ReadExif function to read EXIF fields from JPEG data, invalid EXIF data is ignored
+func ReadExif(data []byte) Exif {
+	tiff := findExifSegment(data)
+	if len(tiff) < 8 {
+		return Exif{}
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return Exif{}
+	}
+
+	result := Exif{}
+	ifd0 := readIFD(tiff, order, order.Uint32(tiff[4:8]))
+
+	if value, ok := ifd0[tagOrientation]; ok {
+		result.Orientation = int(order.Uint16(value[:2]))
+	}
+
+	if value, ok := ifd0[tagExifIFD]; ok {
+		exifIFD := readIFD(tiff, order, order.Uint32(value))
+		if value, ok := exifIFD[tagDateTimeOriginal]; ok {
+			result.TakenAt = readDate(tiff, order.Uint32(value))
+		}
+	}
+
+	return result
+}
+
+// findExifSegment returns the TIFF data of the JPEG APP1 Exif segment
+func findExifSegment(data []byte) []byte {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil
+	}
+
+	for offset := 2; offset+4 <= len(data); {
+		if data[offset] != 0xFF {
+			return nil
+		}
+
+		marker := data[offset+1]
+		length := int(binary.BigEndian.Uint16(data[offset+2 : offset+4]))
+
+		// Metadata segments come before start of scan
+		if marker == 0xDA || length < 2 || offset+2+length > len(data) {
+			return nil
+		}
+
+		segment := data[offset+4 : offset+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:]
+		}
+
+		offset += 2 + length
+	}
+
+	return nil
+}
+
+// readIFD returns the 4 byte value fields of the IFD entries keyed by tag
+func readIFD(tiff []byte, order binary.ByteOrder, offset uint32) map[uint16][]byte {
+	entries := map[uint16][]byte{}
+	if uint64(offset)+2 > uint64(len(tiff)) {
+		return entries
+	}
+
+	count := int(order.Uint16(tiff[offset:]))
+	start := int(offset) + 2
+	for i := 0; i < count; i++ {
+		entry := start + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+
+		entries[order.Uint16(tiff[entry:])] = tiff[entry+8 : entry+12]
+	}
+
+	return entries
+}
+
+// readDate reads EXIF date string at offset
+func readDate(tiff []byte, offset uint32) *time.Time {
+	end := uint64(offset) + uint64(len(exifDateLayout))
+	if end > uint64(len(tiff)) {
+		return nil
+	}
+
+	date, err := time.Parse(exifDateLayout, string(tiff[offset:end]))
+	if err != nil {
+		return nil
+	}
+
+	return &date
+}
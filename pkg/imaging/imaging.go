@@ -0,0 +1,216 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+// ErrUnsupportedFormat is returned when data is not a JPEG, PNG or WebP image
+var ErrUnsupportedFormat = errors.New("unsupported image format")
+
+// ErrTooManyPixels is returned when the image has more than MaxPixels pixels
+var ErrTooManyPixels = errors.New("image has too many pixels")
+
+// MaxPixels is the maximum width x height of a decoded image, a small compressed file
+// can declare huge dimensions and decoding it would allocate memory for every pixel
+const MaxPixels = 40_000_000
+
+// Supported content types
+const (
+	ContentTypeJPEG = "image/jpeg"
+	ContentTypePNG  = "image/png"
+	ContentTypeWebP = "image/webp"
+)
+
+const jpegQuality = 85
+
+// Metadata object which contains information read from the image,
+// Width and Height are the dimensions after applying EXIF orientation.
+type Metadata struct {
+	ContentType string
+	Width       int
+	Height      int
+	Exif        Exif
+}
+
+// DetectContentType returns the sniffed content type of data, ErrUnsupportedFormat if it's not a supported image
+func DetectContentType(data []byte) (string, error) {
+	switch contentType := http.DetectContentType(data); contentType {
+	case ContentTypeJPEG, ContentTypePNG, ContentTypeWebP:
+		return contentType, nil
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+// Decode function to decode JPEG, PNG or WebP image based on its content,
+// JPEG images are rotated according to their EXIF orientation.
+// Returns ErrTooManyPixels without decoding the pixels if the dimensions in the header are above MaxPixels.
+func Decode(data []byte) (image.Image, *Metadata, error) {
+	contentType, err := DetectContentType(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		decode       func(r io.Reader) (image.Image, error)
+		decodeConfig func(r io.Reader) (image.Config, error)
+	)
+
+	switch contentType {
+	case ContentTypeJPEG:
+		decode, decodeConfig = jpeg.Decode, jpeg.DecodeConfig
+	case ContentTypePNG:
+		decode, decodeConfig = png.Decode, png.DecodeConfig
+	case ContentTypeWebP:
+		decode, decodeConfig = webp.Decode, webp.DecodeConfig
+	}
+
+	config, err := decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if int64(config.Width)*int64(config.Height) > MaxPixels {
+		return nil, nil, ErrTooManyPixels
+	}
+
+	img, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metadata := &Metadata{ContentType: contentType}
+	if contentType == ContentTypeJPEG {
+		metadata.Exif = ReadExif(data)
+	}
+
+	img = orient(img, metadata.Exif.Orientation)
+	metadata.Width = img.Bounds().Dx()
+	metadata.Height = img.Bounds().Dy()
+
+	return img, metadata, nil
+}
+
+// Square function to crop the center square of the image and scale it to size x size
+func Square(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+
+	return scale(img, image.Rect(x, y, x+side, y+side), size, size)
+}
+
+// Fit function to scale the image down so neither side is larger than maxSize, smaller images are not scaled up
+func Fit(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if width > maxSize || height > maxSize {
+		if width >= height {
+			width, height = maxSize, height*maxSize/width
+		} else {
+			width, height = width*maxSize/height, maxSize
+		}
+	}
+
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+
+	return scale(img, bounds, width, height)
+}
+
+// EncodeJPEG function to encode image as JPEG, the output has no metadata so EXIF is stripped
+func EncodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// scale draws the source rectangle of the image on a white width x height canvas,
+// transparent areas become white because JPEG has no alpha channel
+func scale(img image.Image, src image.Rectangle, width int, height int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Over, nil)
+
+	return dst
+}
+
+// orient returns the image transformed according to the EXIF orientation (1-8),
+// pixels are copied between RGBA buffers so decoded images are converted at most once
+func orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	src := toRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+
+	// Orientations 5-8 swap width and height
+	dstW, dstH := w, h
+	if orientation >= 5 {
+		dstW, dstH = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < h; y++ {
+		row := src.Pix[y*src.Stride : y*src.Stride+w*4]
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dy*dst.Stride+dx*4:dy*dst.Stride+dx*4+4], row[x*4:x*4+4])
+		}
+	}
+
+	return dst
+}
+
+// toRGBA returns the image as RGBA with its origin at 0, 0, other color models are converted
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+
+	return rgba
+}
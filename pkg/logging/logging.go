@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+type contextKey struct{}
+
+// New returns logger which writes JSON records at or above the level to w,
+// one record per line so CloudWatch Logs can parse the fields.
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// ParseLevel returns the level with the name, e.g. debug, info, warn or error, info if the name is unknown
+func ParseLevel(name string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return slog.LevelInfo
+	}
+
+	return level
+}
+
+// ValidLevel returns true if ParseLevel knows the level name
+func ValidLevel(name string) bool {
+	var level slog.Level
+	return level.UnmarshalText([]byte(strings.TrimSpace(name))) == nil
+}
+
+// Discard returns logger which drops all records
+func Discard() *slog.Logger {
+	return slog.New(slog.NewJSONHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+}
+
+// WithLogger returns copy of ctx which carries the logger, e.g. the request logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger of ctx, the default logger if ctx has no logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+
+	return slog.Default()
+}
@@ -0,0 +1,8 @@
+package pkg
+
+import "strings"
+
+// NormalizeText lowercases text and collapses whitespace, used for case insensitive search fields
+func NormalizeText(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
@@ -0,0 +1,63 @@
+package timeout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Operation is a kind of call to DynamoDB or S3 which has its own timeout
+type Operation string
+
+const (
+	DatabaseRead  Operation = "database_read"
+	DatabaseQuery Operation = "database_query"
+	DatabaseWrite Operation = "database_write"
+	StorageRead   Operation = "storage_read"
+	StorageWrite  Operation = "storage_write"
+)
+
+// Defaults contains the timeout of each operation unless it is changed with Set
+var Defaults = map[Operation]time.Duration{
+	DatabaseRead:  2 * time.Second,
+	DatabaseQuery: 3 * time.Second,
+	DatabaseWrite: 3 * time.Second,
+	StorageRead:   5 * time.Second,
+	StorageWrite:  10 * time.Second,
+}
+
+var (
+	mu       sync.RWMutex
+	timeouts = map[Operation]time.Duration{}
+)
+
+// Set changes the timeout of the operation, zero restores the default
+func Set(op Operation, timeout time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if timeout <= 0 {
+		delete(timeouts, op)
+		return
+	}
+
+	timeouts[op] = timeout
+}
+
+// Get returns the timeout of the operation
+func Get(op Operation) time.Duration {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if timeout, ok := timeouts[op]; ok {
+		return timeout
+	}
+
+	return Defaults[op]
+}
+
+// WithTimeout returns copy of ctx which is cancelled after the timeout of the operation,
+// or earlier when ctx is cancelled, e.g. the client disconnected or the Lambda deadline passed
+func WithTimeout(ctx context.Context, op Operation) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, Get(op))
+}
@@ -0,0 +1,86 @@
+package pkg
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// MinimumAge is the minimum age in years of users with a birth date
+const MinimumAge = 13
+
+// DateLayout is the ISO 8601 layout of dates without time
+const DateLayout = "2006-01-02"
+
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// Validator collects field-level validation errors, the zero value is ready to use
+type Validator struct {
+	details map[string]string
+}
+
+// Check adds the reason to the field when ok is false, only the first reason of each field is kept
+func (validator *Validator) Check(ok bool, field string, reason string) {
+	if ok {
+		return
+	}
+
+	if validator.details == nil {
+		validator.details = map[string]string{}
+	}
+
+	if _, exists := validator.details[field]; !exists {
+		validator.details[field] = reason
+	}
+}
+
+// isHTTPURL returns true if the value is an absolute http or https url
+func isHTTPURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// Phone checks the value is a phone number in E.164 format, empty value is valid
+func (validator *Validator) Phone(field string, value string) {
+	if value == "" {
+		return
+	}
+
+	validator.Check(phonePattern.MatchString(value), field, "Must be a phone number in E.164 format")
+}
+
+// BirthDate checks the value is an ISO 8601 date of someone at least MinimumAge years old at now,
+// empty value is valid
+func (validator *Validator) BirthDate(field string, value string, now time.Time) {
+	if value == "" {
+		return
+	}
+
+	birthDate, err := time.Parse(DateLayout, value)
+	if err != nil {
+		validator.Check(false, field, "Must be a date in YYYY-MM-DD format")
+		return
+	}
+
+	validator.Check(Age(birthDate, now) >= MinimumAge, field, "Must be at least "+strconv.Itoa(MinimumAge)+" years old")
+}
+
+// Error returns 400 error with the details of invalid fields, nil if all fields are valid
+func (validator *Validator) Error() *Error {
+	if len(validator.details) == 0 {
+		return nil
+	}
+
+	return &Error{Code: 400, Type: TypeValidationFailed, Reason: "Validation failed", Details: validator.details}
+}
+
+// Age returns the age in full years at now of someone born at birthDate
+func Age(birthDate time.Time, now time.Time) int {
+	age := now.Year() - birthDate.Year()
+	if now.Before(birthDate.AddDate(age, 0, 0)) {
+		age--
+	}
+
+	return age
+}
@@ -87,6 +87,14 @@ func createApplicationTable(svc *dynamodb.DynamoDB) error {
 				AttributeName: aws.String("SK"),
 				AttributeType: aws.String("S"),
 			},
+			{
+				AttributeName: aws.String("GSI2PK"),
+				AttributeType: aws.String("S"),
+			},
+			{
+				AttributeName: aws.String("GSI2SK"),
+				AttributeType: aws.String("S"),
+			},
 		},
 		KeySchema: []*dynamodb.KeySchemaElement{
 			{
@@ -115,6 +123,26 @@ func createApplicationTable(svc *dynamodb.DynamoDB) error {
 					WriteCapacityUnits: aws.Int64(10),
 				},
 			},
+			{
+				IndexName: aws.String("APPLICATION_GSI_2"),
+				KeySchema: []*dynamodb.KeySchemaElement{
+					{
+						AttributeName: aws.String("GSI2PK"),
+						KeyType:       aws.String("HASH"),
+					},
+					{
+						AttributeName: aws.String("GSI2SK"),
+						KeyType:       aws.String("RANGE"),
+					},
+				},
+				Projection: &dynamodb.Projection{
+					ProjectionType: aws.String("ALL"),
+				},
+				ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+					ReadCapacityUnits:  aws.Int64(10),
+					WriteCapacityUnits: aws.Int64(10),
+				},
+			},
 		},
 		ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
 			ReadCapacityUnits:  aws.Int64(10),